@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
 	Server struct {
-		Port int
+		Port    int
+		BaseURL string // Адрес клиентского приложения для ссылок в письмах
 	}
 	DB struct {
 		Host     string
@@ -18,8 +20,12 @@ type Config struct {
 		DBName   string
 	}
 	JWT struct {
-		SecretKey string
-		ExpiresIn int // в часах
+		SigningKeys      []string // Приватные ключи подписи в PEM (RSA или Ed25519), по версиям начиная с 1
+		ActiveKeyVersion int      // Версия ключа для подписи новых токенов
+		Issuer           string   // Значение claim iss
+		Audience         string   // Значение claim aud
+		ExpiresIn        int      // Время жизни access-токена в минутах
+		RefreshExpiresIn int      // Время жизни refresh-токена в часах
 	}
 	SMTP struct {
 		Host     string
@@ -31,6 +37,82 @@ type Config struct {
 	CardPrivateKey string // Приватный ключ для подписи карт
 	CardPublicKey  string // Публичный ключ для проверки подписи карт
 	CardHMACKey    string // Ключ для HMAC-подписи карт
+
+	CardKeys             []CardKeyConfig // Все версии ключей карт (v1 — ключи выше)
+	CardActiveKeyVersion int             // Версия ключа для шифрования новых данных
+	CardReencryptBatch   int             // Размер пачки для перешифрования карт
+
+	CardPINKey         string // Ключ для хэширования PIN-кодов
+	CardPINMaxAttempts int    // Количество неверных вводов PIN до блокировки карты
+	CardCVVMaxAttempts int    // Количество неверных CVV или сроков действия до блокировки карты
+
+	CardDynamicCVVTTL int // Время жизни динамического CVV в минутах
+
+	CardRevealMaxAttempts int // Количество неверных паролей при показе реквизитов до блокировки показа
+	CardRevealLockMinutes int // На сколько минут блокируется показ реквизитов
+
+	CardSingleLimit        float64 // Лимит карты на одну операцию по умолчанию
+	CardDailyLimit         float64 // Суточный лимит карты по умолчанию
+	CardTransferFeePercent float64 // Комиссия за перевод на чужую карту, %
+	CardTransferMinFee     float64 // Минимальная комиссия за перевод
+	CardTransferMaxFee     float64 // Максимальная комиссия за перевод
+
+	CardReissueDaysBefore int // За сколько дней до истечения срока перевыпускать карту
+
+	CardChallengeThreshold   float64 // Сумма интернет-платежа, выше которой требуется одноразовый код
+	CardChallengeTTL         int     // Время жизни одноразового кода в минутах
+	CardChallengeMaxAttempts int     // Количество попыток ввода одноразового кода
+
+	CardPaymentSystem string                // Платежная система выпускаемых карт
+	CardBINRanges     map[string][]BINRange // Диапазоны BIN/IIN по платежным системам
+
+	TwoFactorIssuer            string  // Название сервиса в приложении-аутентификаторе
+	TwoFactorTransferThreshold float64 // Сумма перевода, выше которой требуется код 2FA
+	TwoFactorMaxAttempts       int     // Количество неверных кодов 2FA до временной блокировки
+	TwoFactorLockout           int     // Длительность блокировки проверки 2FA в минутах
+
+	SignInMaxFailures int // Количество неудачных попыток входа до блокировки учетной записи
+	SignInLockout     int // Длительность первой блокировки в минутах, каждая следующая вдвое дольше
+	SignInMaxLockout  int // Максимальная длительность блокировки в минутах
+
+	RateLimitAuth       int  // Запросов в минуту к публичным маршрутам аутентификации с одного IP
+	RateLimitAPI        int  // Запросов в минуту к защищенным маршрутам от одного пользователя
+	RateLimitMoney      int  // Запросов в минуту к операциям со средствами от одного пользователя
+	RateLimitRecipient  int  // Запросов в минуту на поиск получателя перевода от одного пользователя
+	RateLimitTrustProxy bool // Брать IP клиента из X-Forwarded-For (только за доверенным прокси)
+
+	KYCPublicKey  string // Публичный PGP-ключ для шифрования анкет и документов клиентов
+	KYCPrivateKey string // Приватный PGP-ключ для просмотра анкет сотрудниками
+
+	ExportDir     string // Каталог для архивов с выгрузкой персональных данных
+	ExportLinkTTL int    // Время жизни ссылки на скачивание выгрузки в часах
+
+	BankBIK             string // БИК банка для расчета контрольного ключа номеров счетов
+	AccountCurrencyCode string // Код валюты в номерах открываемых счетов (810 — рубль)
+
+	SBPURL     string // Адрес API Системы быстрых платежей
+	SBPSecret  string // Общий секрет для подписи запросов и уведомлений СБП
+	SBPTimeout int    // Таймаут запроса к СБП в секундах
+
+	SMSGatewayURL   string // Адрес API SMS-шлюза для кодов подтверждения телефона
+	SMSGatewayToken string // Токен доступа к SMS-шлюзу
+}
+
+// BINRange описывает диапазон префиксов номера карты одинаковой длины
+type BINRange struct {
+	From string
+	To   string
+}
+
+// defaultCardBINRanges диапазоны BIN по умолчанию
+const defaultCardBINRanges = "MIR:2200-2204;VISA:4000-4999;MASTERCARD:51-55,2221-2720"
+
+// CardKeyConfig описывает одну версию ключей карт
+type CardKeyConfig struct {
+	Version    int
+	PublicKey  string
+	PrivateKey string
+	HMACKey    string
 }
 
 func NewConfig() (*Config, error) {
@@ -41,6 +123,7 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("неверный формат порта сервера: %v", err)
 	}
 	cfg.Server.Port = port
+	cfg.Server.BaseURL = strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:8080"), "/")
 
 	// Настройки базы данных
 	cfg.DB.Host = getEnv("DB_HOST", "localhost")
@@ -54,12 +137,43 @@ func NewConfig() (*Config, error) {
 	cfg.DB.DBName = getEnv("DB_NAME", "go_test")
 
 	// Настройки JWT
-	cfg.JWT.SecretKey = getEnv("JWT_SECRET_KEY", "your-secret-key-here")
-	jwtExpiresIn, err := strconv.Atoi(getEnv("JWT_EXPIRES_IN", "24"))
+	// Ключи подписи: v1 — JWT_SIGNING_KEY, последующие версии — JWT_SIGNING_KEY_V2 и т.д.
+	// Без ключей сервер не запускается: NewTokenIssuer требует ключ подписи.
+	if signingKey := os.Getenv("JWT_SIGNING_KEY"); signingKey != "" {
+		cfg.JWT.SigningKeys = append(cfg.JWT.SigningKeys, signingKey)
+		for version := 2; ; version++ {
+			signingKey := os.Getenv("JWT_SIGNING_KEY_V" + strconv.Itoa(version))
+			if signingKey == "" {
+				break
+			}
+			cfg.JWT.SigningKeys = append(cfg.JWT.SigningKeys, signingKey)
+		}
+	}
+	jwtActiveKeyVersion, err := strconv.Atoi(getEnv("JWT_ACTIVE_KEY_VERSION", strconv.Itoa(len(cfg.JWT.SigningKeys))))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат версии ключа подписи JWT: %v", err)
+	}
+	cfg.JWT.ActiveKeyVersion = jwtActiveKeyVersion
+	cfg.JWT.Issuer = getEnv("JWT_ISSUER", "awesome-bank")
+	cfg.JWT.Audience = getEnv("JWT_AUDIENCE", "awesome-bank-api")
+	// JWT_EXPIRES_IN задавался в часах и учитывается, пока не задан JWT_ACCESS_EXPIRES_MINUTES
+	jwtExpiresIn, err := strconv.Atoi(getEnv("JWT_ACCESS_EXPIRES_MINUTES", "15"))
 	if err != nil {
 		return nil, fmt.Errorf("неверный формат времени жизни JWT: %v", err)
 	}
+	if os.Getenv("JWT_ACCESS_EXPIRES_MINUTES") == "" && os.Getenv("JWT_EXPIRES_IN") != "" {
+		hours, err := strconv.Atoi(os.Getenv("JWT_EXPIRES_IN"))
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат времени жизни JWT: %v", err)
+		}
+		jwtExpiresIn = hours * 60
+	}
 	cfg.JWT.ExpiresIn = jwtExpiresIn
+	refreshExpiresIn, err := strconv.Atoi(getEnv("JWT_REFRESH_EXPIRES_IN", "720"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат времени жизни refresh-токена: %v", err)
+	}
+	cfg.JWT.RefreshExpiresIn = refreshExpiresIn
 
 	// Настройки SMTP
 	cfg.SMTP.Host = getEnv("SMTP_HOST", "smtp.gmail.com")
@@ -77,9 +191,238 @@ func NewConfig() (*Config, error) {
 	cfg.CardPublicKey = getEnv("CARD_PUBLIC_KEY", "your-card-public-key-here")
 	cfg.CardHMACKey = getEnv("CARD_HMAC_KEY", "your-card-hmac-key-here")
 
+	// Версионированные ключи карт: v1 берется из переменных выше,
+	// последующие версии — из CARD_*_KEY_V2, CARD_*_KEY_V3 и т.д.
+	cfg.CardKeys = []CardKeyConfig{{
+		Version:    1,
+		PublicKey:  cfg.CardPublicKey,
+		PrivateKey: cfg.CardPrivateKey,
+		HMACKey:    cfg.CardHMACKey,
+	}}
+	for version := 2; ; version++ {
+		suffix := "_V" + strconv.Itoa(version)
+		publicKey := os.Getenv("CARD_PUBLIC_KEY" + suffix)
+		if publicKey == "" {
+			break
+		}
+		cfg.CardKeys = append(cfg.CardKeys, CardKeyConfig{
+			Version:    version,
+			PublicKey:  publicKey,
+			PrivateKey: os.Getenv("CARD_PRIVATE_KEY" + suffix),
+			HMACKey:    os.Getenv("CARD_HMAC_KEY" + suffix),
+		})
+	}
+	activeVersion, err := strconv.Atoi(getEnv("CARD_ACTIVE_KEY_VERSION", strconv.Itoa(len(cfg.CardKeys))))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат версии ключа карт: %v", err)
+	}
+	cfg.CardActiveKeyVersion = activeVersion
+	reencryptBatch, err := strconv.Atoi(getEnv("CARD_REENCRYPT_BATCH", "100"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат размера пачки перешифрования: %v", err)
+	}
+	cfg.CardReencryptBatch = reencryptBatch
+
+	// Известный ключ позволил бы подобрать PIN-коды по их хэшам
+	cfg.CardPINKey = getEnv("CARD_PIN_KEY", "")
+	if cfg.CardPINKey == "" || cfg.CardPINKey == "your-card-pin-key-here" {
+		return nil, fmt.Errorf("не задан ключ PIN-кодов карт (CARD_PIN_KEY)")
+	}
+	pinMaxAttempts, err := strconv.Atoi(getEnv("CARD_PIN_MAX_ATTEMPTS", "3"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат количества попыток ввода PIN: %v", err)
+	}
+	cfg.CardPINMaxAttempts = pinMaxAttempts
+	cvvMaxAttempts, err := strconv.Atoi(getEnv("CARD_CVV_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат количества попыток ввода CVV: %v", err)
+	}
+	cfg.CardCVVMaxAttempts = cvvMaxAttempts
+
+	dynamicCVVTTL, err := strconv.Atoi(getEnv("CARD_DYNAMIC_CVV_TTL", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат времени жизни динамического CVV: %v", err)
+	}
+	cfg.CardDynamicCVVTTL = dynamicCVVTTL
+
+	revealMaxAttempts, err := strconv.Atoi(getEnv("CARD_REVEAL_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат количества попыток показа реквизитов: %v", err)
+	}
+	cfg.CardRevealMaxAttempts = revealMaxAttempts
+	revealLockMinutes, err := strconv.Atoi(getEnv("CARD_REVEAL_LOCK_MINUTES", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат времени блокировки показа реквизитов: %v", err)
+	}
+	cfg.CardRevealLockMinutes = revealLockMinutes
+
+	reissueDaysBefore, err := strconv.Atoi(getEnv("CARD_REISSUE_DAYS_BEFORE", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат срока перевыпуска карт: %v", err)
+	}
+	cfg.CardReissueDaysBefore = reissueDaysBefore
+
+	challengeTTL, err := strconv.Atoi(getEnv("CARD_CHALLENGE_TTL", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат времени жизни кода подтверждения: %v", err)
+	}
+	cfg.CardChallengeTTL = challengeTTL
+	challengeMaxAttempts, err := strconv.Atoi(getEnv("CARD_CHALLENGE_MAX_ATTEMPTS", "3"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат количества попыток ввода кода подтверждения: %v", err)
+	}
+	cfg.CardChallengeMaxAttempts = challengeMaxAttempts
+
+	// Лимиты и комиссии по картам
+	floatSettings := []struct {
+		target       *float64
+		key          string
+		defaultValue string
+	}{
+		{&cfg.CardSingleLimit, "CARD_SINGLE_LIMIT", "100000"},
+		{&cfg.CardDailyLimit, "CARD_DAILY_LIMIT", "300000"},
+		{&cfg.CardTransferFeePercent, "CARD_TRANSFER_FEE_PERCENT", "1"},
+		{&cfg.CardTransferMinFee, "CARD_TRANSFER_MIN_FEE", "30"},
+		{&cfg.CardTransferMaxFee, "CARD_TRANSFER_MAX_FEE", "3000"},
+		{&cfg.CardChallengeThreshold, "CARD_CHALLENGE_THRESHOLD", "3000"},
+		{&cfg.TwoFactorTransferThreshold, "TWO_FACTOR_TRANSFER_THRESHOLD", "50000"},
+	}
+	for _, setting := range floatSettings {
+		value, err := strconv.ParseFloat(getEnv(setting.key, setting.defaultValue), 64)
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат %s: %v", setting.key, err)
+		}
+		*setting.target = value
+	}
+
+	cfg.CardPaymentSystem = getEnv("CARD_PAYMENT_SYSTEM", "MIR")
+	binRanges, err := parseBINRanges(getEnv("CARD_BIN_RANGES", defaultCardBINRanges))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат диапазонов BIN: %v", err)
+	}
+	if _, ok := binRanges[cfg.CardPaymentSystem]; !ok {
+		return nil, fmt.Errorf("для платежной системы %s не заданы диапазоны BIN", cfg.CardPaymentSystem)
+	}
+	cfg.CardBINRanges = binRanges
+
+	// Настройки двухфакторной аутентификации
+	cfg.TwoFactorIssuer = getEnv("TWO_FACTOR_ISSUER", "Awesome Bank")
+	twoFactorMaxAttempts, err := strconv.Atoi(getEnv("TWO_FACTOR_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат количества попыток ввода кода 2FA: %v", err)
+	}
+	cfg.TwoFactorMaxAttempts = twoFactorMaxAttempts
+	twoFactorLockout, err := strconv.Atoi(getEnv("TWO_FACTOR_LOCKOUT", "15"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат длительности блокировки 2FA: %v", err)
+	}
+	cfg.TwoFactorLockout = twoFactorLockout
+
+	// Защита от перебора паролей и ограничение частоты запросов
+	intSettings := []struct {
+		target       *int
+		key          string
+		defaultValue string
+	}{
+		{&cfg.SignInMaxFailures, "SIGNIN_MAX_FAILURES", "5"},
+		{&cfg.SignInLockout, "SIGNIN_LOCKOUT", "15"},
+		{&cfg.SignInMaxLockout, "SIGNIN_MAX_LOCKOUT", "1440"},
+		{&cfg.RateLimitAuth, "RATE_LIMIT_AUTH", "10"},
+		{&cfg.RateLimitAPI, "RATE_LIMIT_API", "120"},
+		{&cfg.RateLimitMoney, "RATE_LIMIT_MONEY", "30"},
+		{&cfg.RateLimitRecipient, "RATE_LIMIT_RECIPIENT", "10"},
+	}
+	for _, setting := range intSettings {
+		value, err := strconv.Atoi(getEnv(setting.key, setting.defaultValue))
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат %s: %v", setting.key, err)
+		}
+		*setting.target = value
+	}
+	trustProxy, err := strconv.ParseBool(getEnv("RATE_LIMIT_TRUST_PROXY", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат RATE_LIMIT_TRUST_PROXY: %v", err)
+	}
+	cfg.RateLimitTrustProxy = trustProxy
+
+	// Ключи шифрования анкет KYC
+	cfg.KYCPublicKey = getEnv("KYC_PUBLIC_KEY", "your-kyc-public-key-here")
+	cfg.KYCPrivateKey = getEnv("KYC_PRIVATE_KEY", "your-kyc-private-key-here")
+
+	// Выгрузка персональных данных
+	cfg.ExportDir = getEnv("EXPORT_DIR", "exports")
+	exportLinkTTL, err := strconv.Atoi(getEnv("EXPORT_LINK_TTL", "72"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат времени жизни ссылки на выгрузку: %v", err)
+	}
+	cfg.ExportLinkTTL = exportLinkTTL
+
+	// Реквизиты банка для номеров счетов
+	cfg.BankBIK = getEnv("BANK_BIK", "044525999")
+	cfg.AccountCurrencyCode = getEnv("ACCOUNT_CURRENCY_CODE", "810")
+
+	// Межбанковские платежи через СБП
+	cfg.SBPURL = strings.TrimRight(getEnv("SBP_URL", "http://localhost:9090"), "/")
+	// Без секрета любой может подделать уведомление СБП о входящем платеже
+	cfg.SBPSecret = getEnv("SBP_SECRET", "")
+	if cfg.SBPSecret == "" || cfg.SBPSecret == "your-sbp-secret-here" {
+		return nil, fmt.Errorf("не задан секрет СБП (SBP_SECRET)")
+	}
+	sbpTimeout, err := strconv.Atoi(getEnv("SBP_TIMEOUT", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат таймаута СБП: %v", err)
+	}
+	cfg.SBPTimeout = sbpTimeout
+
+	// SMS-шлюз для подтверждения телефона; без адреса подтверждение недоступно
+	cfg.SMSGatewayURL = strings.TrimRight(getEnv("SMS_GATEWAY_URL", ""), "/")
+	cfg.SMSGatewayToken = getEnv("SMS_GATEWAY_TOKEN", "")
+
 	return cfg, nil
 }
 
+// parseBINRanges разбирает строку вида "MIR:2200-2204;VISA:4000-4999,4276"
+func parseBINRanges(value string) (map[string][]BINRange, error) {
+	ranges := make(map[string][]BINRange)
+	for _, systemPart := range strings.Split(value, ";") {
+		systemPart = strings.TrimSpace(systemPart)
+		if systemPart == "" {
+			continue
+		}
+
+		name, list, found := strings.Cut(systemPart, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("ожидается ПЛАТЕЖНАЯ_СИСТЕМА:диапазоны, получено %q", systemPart)
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+
+		for _, rangePart := range strings.Split(list, ",") {
+			from, to, isRange := strings.Cut(strings.TrimSpace(rangePart), "-")
+			if !isRange {
+				to = from
+			}
+			if !isDigits(from) || !isDigits(to) || len(from) != len(to) || len(from) > 8 || from > to {
+				return nil, fmt.Errorf("некорректный диапазон BIN %q", rangePart)
+			}
+			ranges[name] = append(ranges[name], BINRange{From: from, To: to})
+		}
+	}
+	return ranges, nil
+}
+
+// isDigits проверяет, что строка непустая и состоит только из цифр
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // getEnv получает значение переменной окружения или возвращает значение по умолчанию
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -1,24 +1,31 @@
 package controllers
 
 import (
-	"awesomeProject/config"
 	"awesomeProject/database"
+	"awesomeProject/models"
 	"awesomeProject/services"
+	"awesomeProject/utils"
 	"encoding/json"
+	"errors"
 	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 	"log"
 	"net/http"
 	"regexp"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
+	"strconv"
 )
 
 type AuthController struct {
-	userHandler *services.UserService
-	validate    *validator.Validate
-	config      *config.Config
+	userHandler  *services.UserService
+	sessions     *services.SessionService
+	tokens       *services.TokenIssuer
+	twoFactor    *services.TwoFactorService
+	verification *services.VerificationService
+	signInGuard  *services.SignInGuard
+	signIns      *services.SignInHistory
+	apiKeys      *services.APIKeyService
+	validate     *validator.Validate
 }
 
 type SignInRequest struct {
@@ -27,13 +34,35 @@ type SignInRequest struct {
 }
 
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	ExpiresIn    int    `json:"expiresIn,omitempty"`
+
+	// Заполняются, если для входа требуется второй фактор
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	TwoFactorToken    string `json:"twoFactorToken,omitempty"`
 }
 
-type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	jwt.RegisteredClaims
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,password"`
+}
+
+type TwoFactorSignInRequest struct {
+	TwoFactorToken string `json:"twoFactorToken" validate:"required"`
+	Code           string `json:"code" validate:"required,min=6,max=11"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
 }
 
 type SignUpRequest struct {
@@ -44,9 +73,11 @@ type SignUpRequest struct {
 }
 
 type Token struct {
-	Token  string `json:"token"`
-	Email  string `json:"email"`
-	UserID uint   `json:"userId"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+	Email        string `json:"email"`
+	UserID       uint   `json:"userId"`
 }
 
 type AuthResponse struct {
@@ -59,34 +90,22 @@ type AuthResponse struct {
 	} `json:"user"`
 }
 
-func NewAuthController(db *database.Database) *AuthController {
+func NewAuthController(db *database.Database, sessions *services.SessionService, tokens *services.TokenIssuer, twoFactor *services.TwoFactorService, verification *services.VerificationService, signInGuard *services.SignInGuard, signIns *services.SignInHistory, apiKeys *services.APIKeyService) *AuthController {
 	validate := validator.New()
 
 	// Регистрация кастомной валидации для пароля
-	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
-		password := fl.Field().String()
-		// Проверка на наличие хотя бы одной цифры
-		hasNumber := regexp.MustCompile(`[0-9]`).MatchString(password)
-		// Проверка на наличие хотя бы одной заглавной буквы
-		hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-		// Проверка на наличие хотя бы одной строчной буквы
-		hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-		// Проверка на наличие хотя бы одного специального символа
-		hasSpecial := regexp.MustCompile(`[!@#$%^&*]`).MatchString(password)
-
-		return hasNumber && hasUpper && hasLower && hasSpecial
-	})
-
-	// Получаем конфигурацию
-	cfg, err := config.NewConfig()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
+	registerPasswordValidation(validate)
 
 	return &AuthController{
-		userHandler: services.NewUserService(db),
-		validate:    validate,
-		config:      cfg,
+		userHandler:  services.NewUserService(db),
+		sessions:     sessions,
+		tokens:       tokens,
+		twoFactor:    twoFactor,
+		verification: verification,
+		signInGuard:  signInGuard,
+		signIns:      signIns,
+		apiKeys:      apiKeys,
+		validate:     validate,
 	}
 }
 
@@ -117,34 +136,114 @@ func (c *AuthController) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Во время блокировки пароль не проверяем
+	if err := c.signInGuard.CheckLocked(user); err != nil {
+		c.signIns.Record(user.ID, requestMeta(r), models.SignInOutcomeLocked)
+		http.Error(w, err.Error(), http.StatusLocked)
+		return
+	}
+
 	// Проверяем пароль
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		c.signInGuard.RecordFailure(user.ID, utils.ClientIP(r))
+		c.signIns.Record(user.ID, requestMeta(r), models.SignInOutcomeInvalidPassword)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Создаем JWT токен
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	// Замороженная учетная запись не может войти до разморозки сотрудником банка
+	if user.FrozenAt != nil {
+		c.signIns.Record(user.ID, requestMeta(r), models.SignInOutcomeFrozen)
+		http.Error(w, "Учетная запись заморожена, обратитесь в поддержку", http.StatusForbidden)
+		return
+	}
+
+	// При включенной 2FA вход завершается вторым шагом
+	if c.twoFactor.IsEnabled(user.ID) {
+		twoFactorToken, err := c.twoFactor.StartChallenge(user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.signIns.Record(user.ID, requestMeta(r), models.SignInOutcomeTwoFactor)
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(SignInResponse{
+			TwoFactorRequired: true,
+			TwoFactorToken:    twoFactorToken,
+		})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(c.config.JWT.SecretKey))
+	// Счетчики неудачных входов сбрасываются только после полного входа:
+	// при 2FA — после второго шага
+	c.signInGuard.RecordSuccess(user)
+
+	// Открываем сеанс и выдаем пару токенов
+	response, err := c.openSession(r, user)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
 
-	response := SignInResponse{
-		Token: tokenString,
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SignInTwoFactor завершает вход кодом TOTP или кодом восстановления
+func (c *AuthController) SignInTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorSignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
 	}
 
+	userID, err := c.twoFactor.CompleteChallenge(req.TwoFactorToken, req.Code)
+	if err != nil {
+		// Неверный код второго шага считается неудачной попыткой входа
+		if userID != 0 {
+			c.signInGuard.RecordFailure(userID, utils.ClientIP(r))
+			c.signIns.Record(userID, requestMeta(r), models.SignInOutcomeTwoFactorFailed)
+		}
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := c.userHandler.FindByID(userID)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	// Блокировка могла наступить после первого шага
+	if err := c.signInGuard.CheckLocked(user); err != nil {
+		c.signIns.Record(user.ID, requestMeta(r), models.SignInOutcomeLocked)
+		http.Error(w, err.Error(), http.StatusLocked)
+		return
+	}
+
+	if user.FrozenAt != nil {
+		http.Error(w, "Учетная запись заморожена, обратитесь в поддержку", http.StatusForbidden)
+		return
+	}
+	c.signInGuard.RecordSuccess(user)
+
+	response, err := c.openSession(r, user)
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -183,12 +282,24 @@ func (c *AuthController) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Генерация JWT токена
-	token, err := c.generateToken(user.ID, user.Email)
+	// Отправляем ссылку для подтверждения email
+	if err := c.verification.SendVerification(user.ID); err != nil {
+		log.Printf("Ошибка отправки подтверждения email пользователю %d: %v", user.ID, err)
+	}
+
+	// Открываем сеанс и генерируем пару токенов
+	session, refreshToken, err := c.sessions.Create(user.ID, requestMeta(r))
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := c.generateToken(user, session.ID)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
+	token.RefreshToken = refreshToken
 
 	response := AuthResponse{
 		Token: *token,
@@ -206,38 +317,324 @@ func (c *AuthController) SignUp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetJWTKey возвращает ключ для JWT
-func (c *AuthController) GetJWTKey() string {
-	return c.config.JWT.SecretKey
+// Refresh обменивает refresh-токен на новую пару токенов.
+// Предъявленный refresh-токен после этого становится недействительным.
+func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, refreshToken, err := c.sessions.Rotate(req.RefreshToken, requestMeta(r))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := c.userHandler.FindByID(session.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	// Сеансы замороженной или закрытой учетной записи могли не отозваться,
+	// поэтому статус проверяется при каждом обновлении токена
+	if user.FrozenAt != nil || user.ClosedAt != nil {
+		if err := c.sessions.Revoke(user.ID, session.ID); err != nil {
+			log.Printf("Ошибка завершения сеанса %d недоступной учетной записи: %v", session.ID, err)
+		}
+		http.Error(w, "Учетная запись заморожена или закрыта", http.StatusForbidden)
+		return
+	}
+
+	token, err := c.generateToken(user, session.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	response := SignInResponse{
+		Token:        token.Token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    token.ExpiresIn,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Logout завершает текущий сеанс пользователя
+func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+	sessionID := r.Context().Value("session_id").(uint)
+
+	if err := c.sessions.Revoke(userID, sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll завершает все сеансы пользователя на всех устройствах и отзывает API-ключи
+func (c *AuthController) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+
+	if err := c.sessions.RevokeAll(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := c.apiKeys.RevokeAll(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail подтверждает email по токену из письма
+func (c *AuthController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.verification.VerifyEmail(req.Token); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email подтвержден",
+	})
+}
+
+// ConfirmEmailChange подтверждает новый email по токену из письма
+func (c *AuthController) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.verification.ConfirmEmailChange(req.Token); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email изменен",
+	})
+}
+
+// ResendVerification повторно отправляет ссылку для подтверждения email
+func (c *AuthController) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+
+	if err := c.verification.SendVerification(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
 }
 
-// GetJWTExpiresIn возвращает время жизни JWT токена
-func (c *AuthController) GetJWTExpiresIn() int {
-	return c.config.JWT.ExpiresIn
+// ForgotPassword отправляет ссылку для сброса пароля. Ответ не зависит от того,
+// зарегистрирован ли email.
+func (c *AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.verification.ForgotPassword(req.Email); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
 }
 
-// generateToken создает JWT токен
-func (c *AuthController) generateToken(userID uint, email string) (*Token, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"exp":     expirationTime.Unix(),
+// ResetPassword устанавливает новый пароль по токену из письма
+func (c *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		http.Error(w, validationErrors.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.verification.ResetPassword(req.Token, req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(c.config.JWT.SecretKey))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Пароль изменен",
+	})
+}
+
+// ListSessions возвращает действующие сеансы пользователя
+func (c *AuthController) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+	sessionID := r.Context().Value("session_id").(uint)
+
+	sessions, err := c.sessions.ListActive(userID, sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession завершает выбранный сеанс пользователя, например на потерянном устройстве
+func (c *AuthController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+
+	sessionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.sessions.Revoke(userID, uint(sessionID)); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SignInHistory возвращает последние попытки входа в учетную запись
+func (c *AuthController) SignInHistory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(uint)
+
+	events, err := c.signIns.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// JWKS отдает открытые ключи подписи токенов для других сервисов
+func (c *AuthController) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(c.tokens.JWKS())
+}
+
+// generateToken создает access-токен сеанса и привязывает его jti к сеансу
+func (c *AuthController) generateToken(user *models.User, sessionID uint) (*Token, error) {
+	tokenString, jti, expiresAt, err := c.tokens.Issue(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := c.sessions.BindAccessToken(sessionID, jti, expiresAt); err != nil {
+		return nil, err
+	}
+
 	return &Token{
-		Token:  tokenString,
-		Email:  email,
-		UserID: userID,
+		Token:     tokenString,
+		ExpiresIn: int(c.tokens.TTL().Seconds()),
+		Email:     user.Email,
+		UserID:    user.ID,
+	}, nil
+}
+
+// openSession открывает сеанс и выдает пару токенов
+func (c *AuthController) openSession(r *http.Request, user *models.User) (*SignInResponse, error) {
+	session, refreshToken, err := c.sessions.Create(user.ID, requestMeta(r))
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.generateToken(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.signIns.RecordSuccess(user, requestMeta(r))
+
+	return &SignInResponse{
+		Token:        token.Token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    token.ExpiresIn,
 	}, nil
 }
+
+// requestMeta извлекает сведения о клиенте для журнала сеансов
+func requestMeta(r *http.Request) services.RequestMeta {
+	return services.RequestMeta{
+		IP:        utils.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		DeviceID:  r.Header.Get("X-Device-ID"),
+	}
+}
+
+// registerPasswordValidation регистрирует правило "password" с требованиями к сложности пароля
+func registerPasswordValidation(validate *validator.Validate) {
+	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		password := fl.Field().String()
+		// Проверка на наличие хотя бы одной цифры
+		hasNumber := regexp.MustCompile(`[0-9]`).MatchString(password)
+		// Проверка на наличие хотя бы одной заглавной буквы
+		hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
+		// Проверка на наличие хотя бы одной строчной буквы
+		hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
+		// Проверка на наличие хотя бы одного специального символа
+		hasSpecial := regexp.MustCompile(`[!@#$%^&*]`).MatchString(password)
+
+		return hasNumber && hasUpper && hasLower && hasSpecial
+	})
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AccountMemberController обрабатывает участников совместных счетов и приглашения
+type AccountMemberController struct {
+	members   *services.AccountMemberService
+	validator *validator.Validate
+}
+
+// NewAccountMemberController создает новый экземпляр AccountMemberController
+func NewAccountMemberController(members *services.AccountMemberService) *AccountMemberController {
+	return &AccountMemberController{
+		members:   members,
+		validator: validator.New(),
+	}
+}
+
+// ListMembers возвращает участников счета
+func (c *AccountMemberController) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	members, err := c.members.List(userID, accountID)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(members)
+}
+
+// Invite приглашает пользователя к счету по email
+func (c *AccountMemberController) Invite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto services.InviteAccountMemberDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := c.members.Invite(userID, accountID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// RevokeInvitation отзывает приглашение
+func (c *AccountMemberController) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	invitationID, err := c.pathID(r, "invitationId")
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.members.RevokeInvitation(userID, accountID, invitationID); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember отключает участника от счета или выводит из счета самого пользователя
+func (c *AccountMemberController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	memberUserID, err := c.pathID(r, "userId")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.members.RemoveMember(userID, accountID, memberUserID); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation принимает приглашение по токену из письма
+func (c *AccountMemberController) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.AcceptInvitationDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	member, err := c.members.Accept(userID, dto.Token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(member)
+}
+
+// pathID разбирает числовой идентификатор из пути запроса
+func (c *AccountMemberController) pathID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// writeError отвечает 404 для ненайденного счета, 403 при отсутствии прав и 400 для остальных ошибок
+func (c *AccountMemberController) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAccountAccessDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *AccountMemberController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "email":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть корректным email")
+			case "oneof":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			case "gte":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше или равно "+e.Param())
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
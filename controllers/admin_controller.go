@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AdminReasonRequest представляет обоснование действия сотрудника
+type AdminReasonRequest struct {
+	Reason string `json:"reason" validate:"required,min=10,max=500"`
+}
+
+// AdminSetRoleRequest представляет запрос на назначение роли
+type AdminSetRoleRequest struct {
+	Role   models.Role `json:"role" validate:"required,oneof=CUSTOMER SUPPORT ADMIN"`
+	Reason string      `json:"reason" validate:"required,min=10,max=500"`
+}
+
+// AdminAdjustmentRequest представляет ручную корректировку баланса.
+// Положительная сумма зачисляется, отрицательная списывается.
+type AdminAdjustmentRequest struct {
+	Amount float64 `json:"amount" validate:"required,ne=0"`
+	Reason string  `json:"reason" validate:"required,min=10,max=500"`
+}
+
+// AdminAccountFreezeRequest представляет ограничение операций по счету
+type AdminAccountFreezeRequest struct {
+	Status     models.AccountStatus      `json:"status" validate:"required,oneof=FROZEN DEBIT_BLOCKED"`
+	ReasonCode models.AccountBlockReason `json:"reason_code" validate:"required,oneof=FRAUD_SUSPECTED COURT_ORDER AML_REVIEW CUSTOMER_REQUEST DECEASED"`
+	Reason     string                    `json:"reason" validate:"required,min=10,max=500"`
+}
+
+// AdminController обрабатывает запросы сотрудников банка
+type AdminController struct {
+	admin     *services.AdminService
+	validator *validator.Validate
+}
+
+// NewAdminController создает новый экземпляр AdminController
+func NewAdminController(admin *services.AdminService) *AdminController {
+	return &AdminController{
+		admin:     admin,
+		validator: validator.New(),
+	}
+}
+
+// SearchUsers ищет пользователей по email или номеру счета (?q=)
+func (c *AdminController) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := c.admin.SearchUsers(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// GetUser возвращает карточку пользователя со счетами и кредитами
+func (c *AdminController) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := c.admin.GetUser(userID)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// FreezeUser замораживает учетную запись пользователя
+func (c *AdminController) FreezeUser(w http.ResponseWriter, r *http.Request) {
+	c.reasonAction(w, r, c.admin.FreezeUser)
+}
+
+// UnfreezeUser снимает заморозку учетной записи
+func (c *AdminController) UnfreezeUser(w http.ResponseWriter, r *http.Request) {
+	c.reasonAction(w, r, c.admin.UnfreezeUser)
+}
+
+// UnlockUser снимает блокировку входа
+func (c *AdminController) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	c.reasonAction(w, r, c.admin.UnlockUser)
+}
+
+// CancelCredit отменяет кредит без платежей
+func (c *AdminController) CancelCredit(w http.ResponseWriter, r *http.Request) {
+	c.reasonAction(w, r, c.admin.CancelCredit)
+}
+
+// WriteOffCredit списывает задолженность по кредиту
+func (c *AdminController) WriteOffCredit(w http.ResponseWriter, r *http.Request) {
+	c.reasonAction(w, r, c.admin.WriteOffCredit)
+}
+
+// SetRole назначает пользователю роль
+func (c *AdminController) SetRole(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AdminSetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.admin.SetRole(adminID, userID, req.Role, req.Reason); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdjustBalance вручную корректирует баланс счета
+func (c *AdminController) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AdminAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := c.admin.AdjustBalance(adminID, accountID, req.Amount, req.Reason)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+// FreezeAccount замораживает счет или блокирует списания с него
+func (c *AdminController) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AdminAccountFreezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := c.admin.FreezeAccount(adminID, accountID, req.Status, req.ReasonCode, req.Reason)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+// UnfreezeAccount снимает ограничение со счета
+func (c *AdminController) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	c.reasonAction(w, r, c.admin.UnfreezeAccount)
+}
+
+// reasonAction выполняет действие над объектом {id}, для которого нужно только обоснование
+func (c *AdminController) reasonAction(w http.ResponseWriter, r *http.Request, action func(adminID, targetID uint, reason string) error) {
+	adminID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AdminReasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := action(adminID, targetID, req.Reason); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pathID разбирает идентификатор {id} из пути запроса
+func (c *AdminController) pathID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// writeError отвечает 404 для ненайденных объектов и 400 для остальных ошибок
+func (c *AdminController) writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrAdminTargetNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *AdminController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "min", "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" имеет неверную длину")
+			case "oneof":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			case "ne":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" не может быть равно "+e.Param())
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"awesomeProject/utils"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIKeyController обрабатывает выпуск и отзыв API-ключей
+type APIKeyController struct {
+	keys      *services.APIKeyService
+	validator *validator.Validate
+}
+
+// NewAPIKeyController создает новый экземпляр APIKeyController
+func NewAPIKeyController(keys *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		keys:      keys,
+		validator: validator.New(),
+	}
+}
+
+// Create выпускает API-ключ. Ключ возвращается только в этом ответе.
+func (c *APIKeyController) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.CreateAPIKeyDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := c.keys.Create(userID, dto, utils.ClientIP(r))
+	if err != nil {
+		if errors.Is(err, services.ErrIdentityNotConfirmed) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Ключ не должен оседать в кэшах и логах
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// List возвращает API-ключи пользователя без самих ключей
+func (c *APIKeyController) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := c.keys.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// Revoke отзывает API-ключ
+func (c *APIKeyController) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.keys.Revoke(userID, uint(keyID)); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *APIKeyController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "min", "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" имеет неверную длину")
+			case "gt", "lte":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" вне допустимого диапазона")
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
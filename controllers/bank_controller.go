@@ -1,13 +1,14 @@
 package controllers
 
 import (
-	"awesomeProject/database"
+	"awesomeProject/models"
 	"awesomeProject/services"
 	"encoding/json"
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,13 +16,15 @@ import (
 // BankController обрабатывает запросы, связанные с банковскими операциями
 type BankController struct {
 	bankService *services.BankService
+	twoFactor   *services.TwoFactorService
 	validator   *validator.Validate
 }
 
 // NewBankController создает новый экземпляр BankController
-func NewBankController(db *database.Database, email *services.EmailService) *BankController {
+func NewBankController(bankService *services.BankService, twoFactor *services.TwoFactorService) *BankController {
 	return &BankController{
-		bankService: services.NewBankService(db.DB, email),
+		bankService: bankService,
+		twoFactor:   twoFactor,
 		validator:   validator.New(),
 	}
 }
@@ -39,6 +42,8 @@ func (c *BankController) validateRequest(dto interface{}) error {
 				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше 0")
 			case "oneof":
 				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
 			}
 		}
 		return errors.New(strings.Join(errorMessages, "; "))
@@ -46,16 +51,10 @@ func (c *BankController) validateRequest(dto interface{}) error {
 	return nil
 }
 
-// validateAccountOwnership проверяет, что счет принадлежит пользователю
-func (c *BankController) validateAccountOwnership(accountID, userID uint) error {
-	account, err := c.bankService.GetById(accountID)
-	if err != nil {
-		return errors.New("банковский счет не найден")
-	}
-	if account.HolderID != userID {
-		return errors.New("нет доступа к данному счету")
-	}
-	return nil
+// authorizeAccount проверяет, что пользователь может выполнить действие со счетом
+func (c *BankController) authorizeAccount(accountID, userID uint, action models.AccountAction, amount float64) error {
+	_, err := services.AuthorizeAccount(c.bankService.GetDB(), userID, accountID, action, amount)
+	return err
 }
 
 // CreateBankAccount обрабатывает запрос на создание банковского счета
@@ -121,8 +120,8 @@ func (c *BankController) Deposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем владельца счета
-	if err := c.validateAccountOwnership(dto.AccountID, userID); err != nil {
+	// Проверяем права на счет
+	if err := c.authorizeAccount(dto.AccountID, userID, models.AccountActionDeposit, dto.Amount); err != nil {
 		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
@@ -165,14 +164,19 @@ func (c *BankController) Withdraw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем владельца счета
-	if err := c.validateAccountOwnership(dto.AccountID, userID); err != nil {
+	// Проверяем права на счет
+	if err := c.authorizeAccount(dto.AccountID, userID, models.AccountActionSpend, dto.Amount); err != nil {
 		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
 	// Снимаем средства
+	dto.SpenderID = userID
 	updatedAccount, err := c.bankService.Withdraw(dto)
+	if errors.Is(err, services.ErrSpendLimitExceeded) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -200,24 +204,31 @@ func (c *BankController) Transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := c.validateAccountOwnership(dto.SourceID, userID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-
 	// Валидируем DTO
 	if err := c.validateRequest(dto); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Проверяем владельца исходного счета
-	if err := c.validateAccountOwnership(dto.SourceID, userID); err != nil {
+	// Проверяем права на исходный счет
+	if err := c.authorizeAccount(dto.SourceID, userID, models.AccountActionSpend, dto.Amount); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Крупные переводы подтверждаются кодом 2FA
+	if err := c.twoFactor.RequireForTransfer(userID, dto.Amount, dto.TOTPCode); err != nil {
 		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
 	// Выполняем перевод
+	dto.SpenderID = userID
 	if err := c.bankService.Transfer(dto); err != nil {
+		if errors.Is(err, services.ErrSpendLimitExceeded) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -249,7 +260,13 @@ func (c *BankController) GetAccounts(w http.ResponseWriter, r *http.Request) {
 	// Конвертируем BankAccount в BankAccountDTO
 	var accountDTOs []services.BankAccountDTO
 	for _, account := range accounts {
-		// Получаем данные пользователя
+		// Роль показывает, свой это счет или совместный
+		role, err := services.AccountRoleOf(c.bankService.GetDB(), &account, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		accountDTO := services.BankAccountDTO{
 			ID: account.ID,
 			Holder: services.UserDTO{
@@ -258,6 +275,8 @@ func (c *BankController) GetAccounts(w http.ResponseWriter, r *http.Request) {
 				LastName:  account.Holder.LastName,
 				Email:     account.Holder.Email,
 			},
+			Role:      string(role),
+			Status:    string(account.Status),
 			Balance:   account.Balance,
 			Title:     account.Title,
 			Number:    account.Number,
@@ -273,6 +292,45 @@ func (c *BankController) GetAccounts(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(accountDTOs)
 }
 
+// CloseAccount обрабатывает запрос на закрытие счета с переводом остатка
+func (c *BankController) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста (установлен middleware)
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	// Тело необязательно: счет с нулевым балансом закрывается без перевода
+	var dto services.CloseAccountDTO
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := c.bankService.CloseAccount(userID, uint(accountID), dto); err != nil {
+		switch {
+		case errors.Is(err, services.ErrAccountNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrAccountAccessDenied):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // RegisterRoutes регистрирует маршруты контроллера
 func (c *BankController) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/accounts", c.CreateBankAccount).Methods("POST")
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"awesomeProject/utils"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CardController обрабатывает запросы, связанные с банковскими картами
+type CardController struct {
+	cardService *services.CardService
+	twoFactor   *services.TwoFactorService
+	validator   *validator.Validate
+}
+
+// NewCardController создает новый экземпляр CardController
+func NewCardController(cardService *services.CardService, twoFactor *services.TwoFactorService) *CardController {
+	return &CardController{
+		cardService: cardService,
+		twoFactor:   twoFactor,
+		validator:   validator.New(),
+	}
+}
+
+// CreateCard обрабатывает запрос на выпуск карты к банковскому счету
+func (c *CardController) CreateCard(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.CardDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Реквизиты карты генерируются банком, от клиента нужен только счет
+	if dto.AccountID == 0 {
+		http.Error(w, "поле AccountID обязательно", http.StatusBadRequest)
+		return
+	}
+	dto.UserID = userID
+
+	// Выпускаем карту
+	card, err := c.cardService.CreateCard(dto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(card)
+}
+
+// GetCards обрабатывает запрос на получение списка карт пользователя
+func (c *CardController) GetCards(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем список карт
+	cards, err := c.cardService.GetAllByUserID(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cards)
+}
+
+// ActivateCard обрабатывает запрос на активацию перевыпущенной карты
+func (c *CardController) ActivateCard(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID карты из URL
+	cardID, err := parseCardID(r)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	// Активируем карту
+	card, err := c.cardService.ActivateCard(userID, cardID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(card)
+}
+
+// SetPIN обрабатывает запрос на установку PIN-кода карты
+func (c *CardController) SetPIN(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID карты из URL
+	cardID, err := parseCardID(r)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.SetPINDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Валидируем DTO
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем PIN-код
+	if err := c.cardService.SetPIN(userID, cardID, dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "PIN-код установлен",
+	})
+}
+
+// ChangePIN обрабатывает запрос на смену PIN-кода карты
+func (c *CardController) ChangePIN(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID карты из URL
+	cardID, err := parseCardID(r)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.ChangePINDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Валидируем DTO
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Меняем PIN-код
+	if err := c.cardService.ChangePIN(userID, cardID, dto); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "PIN-код изменен",
+	})
+}
+
+// RevealCard обрабатывает запрос на показ полных реквизитов карты
+func (c *CardController) RevealCard(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID карты из URL
+	cardID, err := parseCardID(r)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.RevealCardDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Валидируем DTO
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Показываем реквизиты после подтверждения личности
+	details, err := c.cardService.RevealCard(userID, cardID, dto, services.RequestMeta{
+		IP:        utils.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if errors.Is(err, services.ErrRevealLocked) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Отправляем ответ. no-store запрещает кэширование и запись тела ответа в лог
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(details)
+}
+
+// Transfer обрабатывает запрос на перевод с карты на карту по номеру карты получателя
+func (c *CardController) Transfer(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя из контекста
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.CardTransferDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Валидируем DTO
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Крупные переводы подтверждаются кодом 2FA
+	if err := c.twoFactor.RequireForTransfer(userID, dto.Amount, dto.TOTPCode); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Выполняем перевод
+	receipt, err := c.cardService.TransferByCard(userID, dto)
+	if errors.Is(err, services.ErrRecipientNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// Authorize обрабатывает запрос на авторизацию операции по карте (банкомат, терминал, интернет)
+func (c *CardController) Authorize(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя эквайера из контекста
+	acquirerID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.CardAuthorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Валидируем DTO
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Проводим авторизацию
+	authorization, err := c.cardService.AuthorizeCard(acquirerID, dto)
+	if errors.Is(err, services.ErrChallengeRequired) {
+		// Операция удержана до ввода одноразового кода
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(authorization)
+		return
+	}
+	if errors.Is(err, services.ErrAccountNotFound) || errors.Is(err, services.ErrAccountAccessDenied) {
+		// Счет зачисления должен принадлежать эквайеру
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(authorization)
+}
+
+// CompleteChallenge обрабатывает ввод одноразового кода для удержанной авторизации
+func (c *CardController) CompleteChallenge(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID пользователя эквайера из контекста
+	acquirerID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Получаем ID авторизации из URL
+	authorizationID, err := parseCardID(r)
+	if err != nil {
+		http.Error(w, "Invalid authorization ID", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем DTO для запроса
+	var dto services.CardChallengeDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Валидируем DTO
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем код и завершаем авторизацию
+	authorization, err := c.cardService.CompleteChallenge(acquirerID, authorizationID, dto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+
+	// Отправляем ответ
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(authorization)
+}
+
+// parseCardID получает ID карты (или другой сущности) из URL
+func parseCardID(r *http.Request) (uint, error) {
+	cardID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(cardID), nil
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *CardController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "gt":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше 0")
+			case "len":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать "+e.Param()+" символов")
+			case "numeric":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать только цифры")
+			case "oneof":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"awesomeProject/database"
+	"awesomeProject/models"
 	"awesomeProject/services"
 	"encoding/json"
 	"errors"
@@ -110,8 +111,8 @@ func (c *CreditController) GetCredit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем, что кредит принадлежит пользователю
-	if credit.Account.Holder.ID != userID {
+	// Проверяем права на счет кредита
+	if _, err := services.AuthorizeAccount(c.creditService.GetDB(), userID, credit.AccountID, models.AccountActionView, 0); err != nil {
 		http.Error(w, "Access denied", http.StatusForbidden)
 		return
 	}
@@ -155,19 +156,24 @@ func (c *CreditController) PayCredit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем, что кредит принадлежит пользователю
+	// Проверяем права на списание со счета кредита
 	credit, err := c.creditService.GetCreditByID(uint(creditID))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if credit.Account.Holder.ID != userID {
-		http.Error(w, "Access denied", http.StatusForbidden)
+	if _, err := services.AuthorizeAccount(c.creditService.GetDB(), userID, credit.AccountID, models.AccountActionSpend, dto.Amount); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
 	// Погашаем кредит
+	dto.UserID = userID
 	payment, err := c.creditService.PayCredit(dto)
+	if errors.Is(err, services.ErrSpendLimitExceeded) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
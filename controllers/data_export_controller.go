@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+)
+
+// DataExportController обрабатывает запросы на выгрузку персональных данных
+type DataExportController struct {
+	exports *services.DataExportService
+}
+
+// NewDataExportController создает новый экземпляр DataExportController
+func NewDataExportController(exports *services.DataExportService) *DataExportController {
+	return &DataExportController{exports: exports}
+}
+
+// RequestExport запускает сборку архива. Ссылка на скачивание придет на email.
+func (c *DataExportController) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := c.exports.Request(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrDataExportTooSoon) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+// Download отдает готовый архив по токену из письма
+func (c *DataExportController) Download(w http.ResponseWriter, r *http.Request) {
+	file, err := c.exports.Download(r.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrDataExportNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeFile(w, r, file.Path)
+}
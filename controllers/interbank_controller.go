@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxNotificationSize максимальный размер уведомления платежной системы
+const maxNotificationSize = 64 << 10
+
+// InterbankController обрабатывает переводы в другие банки и уведомления платежной системы
+type InterbankController struct {
+	interbank *services.InterbankService
+	validator *validator.Validate
+}
+
+// NewInterbankController создает новый экземпляр InterbankController
+func NewInterbankController(interbank *services.InterbankService) *InterbankController {
+	return &InterbankController{
+		interbank: interbank,
+		validator: validator.New(),
+	}
+}
+
+// Send создает перевод в другой банк. Сумма удерживается на счете до расчета
+func (c *InterbankController) Send(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.OutgoingPaymentDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment, err := c.interbank.Send(userID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// List возвращает межбанковские платежи по счетам пользователя
+func (c *InterbankController) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	payments, err := c.interbank.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payments)
+}
+
+// Notify принимает подписанное уведомление СБП о входящем платеже или статусе исходящего.
+// Ответ 422 означает, что платеж не может быть зачислен и возвращается отправителю.
+func (c *InterbankController) Notify(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationSize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.interbank.HandleNotification(body, r.Header.Get(services.SBPSignatureHeader)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrRailSignatureInvalid):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, services.ErrInterbankPaymentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			log.Printf("Уведомление СБП не обработано: %v", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError отвечает 404 для ненайденного счета, 403 при отсутствии прав
+// и 400 для остальных ошибок
+func (c *InterbankController) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAccountAccessDenied), errors.Is(err, services.ErrSpendLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *InterbankController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "required_without":
+				errorMessages = append(errorMessages, "укажите "+e.Field()+" или "+e.Param())
+			case "gt":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше 0")
+			case "len":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать "+e.Param()+" символов")
+			case "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать максимум "+e.Param()+" символов")
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
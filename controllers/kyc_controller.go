@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// kycUploadMaxMemory объем загрузки, который держится в памяти при разборе формы
+const kycUploadMaxMemory = 6 << 20
+
+// KYCApproveRequest представляет подтверждение анкеты сотрудником
+type KYCApproveRequest struct {
+	Level  models.KYCLevel `json:"level" validate:"required,oneof=BASIC FULL"`
+	Reason string          `json:"reason" validate:"required,min=10,max=500"`
+}
+
+// KYCController обрабатывает анкеты клиентов и их проверку сотрудниками
+type KYCController struct {
+	kyc       *services.KYCService
+	validator *validator.Validate
+}
+
+// NewKYCController создает новый экземпляр KYCController
+func NewKYCController(kyc *services.KYCService) *KYCController {
+	return &KYCController{
+		kyc:       kyc,
+		validator: validator.New(),
+	}
+}
+
+// GetStatus возвращает уровень верификации и статус анкеты текущего пользователя
+func (c *KYCController) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := c.kyc.Status(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// Submit принимает анкету и отправляет ее на проверку
+func (c *KYCController) Submit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.KYCSubmissionDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := c.kyc.Submit(userID, dto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// UploadDocument принимает документ в поле file формы multipart/form-data.
+// Тип документа передается в поле type.
+func (c *KYCController) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, kycUploadMaxMemory)
+	if err := r.ParseMultipartForm(kycUploadMaxMemory); err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "поле file обязательно", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Invalid file", http.StatusBadRequest)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	document, err := c.kyc.UploadDocument(userID, models.KYCDocumentType(r.FormValue("type")), header.Filename, contentType, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(document)
+}
+
+// ListPending возвращает анкеты, ожидающие проверки
+func (c *KYCController) ListPending(w http.ResponseWriter, r *http.Request) {
+	profiles, err := c.kyc.ListPending()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// GetForReview возвращает расшифрованную анкету клиента
+func (c *KYCController) GetForReview(w http.ResponseWriter, r *http.Request) {
+	userID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	review, err := c.kyc.GetForReview(userID)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	// Заявка содержит расшифрованные паспортные данные и ИНН
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// GetDocument отдает расшифрованный документ клиента
+func (c *KYCController) GetDocument(w http.ResponseWriter, r *http.Request) {
+	userID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	documentID, err := c.pathID(r, "docId")
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	document, err := c.kyc.GetDocument(userID, documentID)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": document.FileName}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document.Data)
+}
+
+// Approve подтверждает анкету и присваивает уровень верификации
+func (c *KYCController) Approve(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req KYCApproveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.kyc.Approve(adminID, userID, req.Level, req.Reason); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reject отклоняет анкету с указанием причины
+func (c *KYCController) Reject(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := c.pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AdminReasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.kyc.Reject(adminID, userID, req.Reason); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pathID разбирает числовой идентификатор из пути запроса
+func (c *KYCController) pathID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// writeError отвечает 404 для ненайденных анкет и 400 для остальных ошибок
+func (c *KYCController) writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrAdminTargetNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *KYCController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "min", "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" имеет неверную длину")
+			case "oneof":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
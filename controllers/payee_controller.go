@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PayeeController обрабатывает сохраненных получателей и шаблоны переводов
+type PayeeController struct {
+	payees    *services.PayeeService
+	validator *validator.Validate
+}
+
+// NewPayeeController создает новый экземпляр PayeeController
+func NewPayeeController(payees *services.PayeeService) *PayeeController {
+	return &PayeeController{
+		payees:    payees,
+		validator: validator.New(),
+	}
+}
+
+// CreatePayee сохраняет получателя
+func (c *PayeeController) CreatePayee(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.CreatePayeeDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payee, err := c.payees.CreatePayee(userID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payee)
+}
+
+// ListPayees возвращает сохраненных получателей, частые — первыми
+func (c *PayeeController) ListPayees(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	payees, err := c.payees.ListPayees(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payees)
+}
+
+// UpdatePayee переименовывает получателя
+func (c *PayeeController) UpdatePayee(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	payeeID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid payee ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto services.UpdatePayeeDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payee, err := c.payees.UpdatePayee(userID, payeeID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payee)
+}
+
+// DeletePayee удаляет получателя и его шаблоны
+func (c *PayeeController) DeletePayee(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	payeeID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid payee ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.payees.DeletePayee(userID, payeeID); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTemplate создает шаблон перевода
+func (c *PayeeController) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.CreateTemplateDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := c.payees.CreateTemplate(userID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// ListTemplates возвращает шаблоны переводов, самые используемые — первыми
+func (c *PayeeController) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templates, err := c.payees.ListTemplates(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(templates)
+}
+
+// UpdateTemplate изменяет шаблон перевода
+func (c *PayeeController) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto services.UpdateTemplateDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := c.payees.UpdateTemplate(userID, templateID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(template)
+}
+
+// DeleteTemplate удаляет шаблон перевода
+func (c *PayeeController) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.payees.DeleteTemplate(userID, templateID); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExecuteTemplate выполняет перевод по шаблону
+func (c *PayeeController) ExecuteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto services.ExecuteTemplateDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := c.payees.ExecuteTemplate(userID, templateID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// pathID извлекает ID получателя или шаблона из пути запроса
+func (c *PayeeController) pathID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// writeError отвечает 404 для ненайденного получателя, шаблона или счета, 403 при отсутствии
+// прав и 400 для остальных ошибок
+func (c *PayeeController) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrPayeeNotFound), errors.Is(err, services.ErrTemplateNotFound),
+		errors.Is(err, services.ErrAccountNotFound), errors.Is(err, services.ErrRecipientNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAccountAccessDenied), errors.Is(err, services.ErrSpendLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *PayeeController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "gt":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше 0")
+			case "oneof":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			case "len":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать "+e.Param()+" символов")
+			case "numeric":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать только цифры")
+			case "min", "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" вне допустимого диапазона")
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
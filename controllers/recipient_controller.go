@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"strings"
+)
+
+// RecipientController обрабатывает переводы по номеру счета, email или телефону получателя
+type RecipientController struct {
+	recipients *services.RecipientService
+	validator  *validator.Validate
+}
+
+// NewRecipientController создает новый экземпляр RecipientController
+func NewRecipientController(recipients *services.RecipientService) *RecipientController {
+	return &RecipientController{
+		recipients: recipients,
+		validator:  validator.New(),
+	}
+}
+
+// PrepareTransfer находит получателя и возвращает его маскированные данные для подтверждения
+func (c *RecipientController) PrepareTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.PrepareTransferDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	preview, err := c.recipients.Prepare(userID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preview)
+}
+
+// ConfirmTransfer выполняет подготовленный перевод
+func (c *RecipientController) ConfirmTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.ConfirmTransferDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := c.recipients.Confirm(userID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// writeError отвечает 404 для ненайденного получателя или счета, 403 при отсутствии прав
+// и 400 для остальных ошибок
+func (c *RecipientController) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipientNotFound), errors.Is(err, services.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAccountAccessDenied), errors.Is(err, services.ErrSpendLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *RecipientController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "gt":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше 0")
+			case "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно содержать максимум "+e.Param()+" символов")
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
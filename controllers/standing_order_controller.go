@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"awesomeProject/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StandingOrderController обрабатывает постоянные поручения на переводы
+type StandingOrderController struct {
+	orders    *services.StandingOrderService
+	validator *validator.Validate
+}
+
+// NewStandingOrderController создает новый экземпляр StandingOrderController
+func NewStandingOrderController(orders *services.StandingOrderService) *StandingOrderController {
+	return &StandingOrderController{
+		orders:    orders,
+		validator: validator.New(),
+	}
+}
+
+// Create создает постоянное поручение
+func (c *StandingOrderController) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto services.CreateStandingOrderDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := c.orders.Create(userID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// List возвращает постоянные поручения пользователя
+func (c *StandingOrderController) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orders, err := c.orders.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// Update изменяет постоянное поручение
+func (c *StandingOrderController) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orderID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid standing order ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto services.UpdateStandingOrderDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.validateRequest(dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := c.orders.Update(userID, orderID, dto)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// Pause приостанавливает постоянное поручение
+func (c *StandingOrderController) Pause(w http.ResponseWriter, r *http.Request) {
+	c.changeStatus(w, r, c.orders.Pause)
+}
+
+// Resume возобновляет постоянное поручение
+func (c *StandingOrderController) Resume(w http.ResponseWriter, r *http.Request) {
+	c.changeStatus(w, r, c.orders.Resume)
+}
+
+// Cancel отменяет постоянное поручение
+func (c *StandingOrderController) Cancel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orderID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid standing order ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.orders.Cancel(userID, orderID); err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changeStatus выполняет действие со статусом поручения из пути запроса
+func (c *StandingOrderController) changeStatus(w http.ResponseWriter, r *http.Request, action func(userID, orderID uint) (*services.StandingOrderDTO, error)) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orderID, err := c.pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid standing order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := action(userID, orderID)
+	if err != nil {
+		c.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// pathID извлекает ID поручения из пути запроса
+func (c *StandingOrderController) pathID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// writeError отвечает 404 для ненайденного поручения или счета, 403 при отсутствии прав
+// и 400 для остальных ошибок
+func (c *StandingOrderController) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrStandingOrderNotFound), errors.Is(err, services.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAccountAccessDenied), errors.Is(err, services.ErrSpendLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// validateRequest валидирует DTO и возвращает ошибки валидации
+func (c *StandingOrderController) validateRequest(dto interface{}) error {
+	if err := c.validator.Struct(dto); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		var errorMessages []string
+		for _, e := range validationErrors {
+			switch e.Tag() {
+			case "required":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" обязательно")
+			case "gt":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть больше 0")
+			case "oneof":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть одним из: "+e.Param())
+			case "datetime":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" должно быть датой в формате ГГГГ-ММ-ДД")
+			case "min", "max":
+				errorMessages = append(errorMessages, "поле "+e.Field()+" вне допустимого диапазона")
+			default:
+				errorMessages = append(errorMessages, "поле "+e.Field()+" заполнено неверно")
+			}
+		}
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return nil
+}
@@ -144,6 +144,31 @@ func autoMigrate(db *gorm.DB) error {
 		&models.Transaction{},
 		&models.Credit{},
 		&models.Payment{},
+		&models.Card{},
+		&models.CardRevealAudit{},
+		&models.CardTransfer{},
+		&models.CardAuthorization{},
+		&models.Session{},
+		&models.RevokedToken{},
+		&models.RetiredRefreshToken{},
+		&models.UserTwoFactor{},
+		&models.RecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.UserToken{},
+		&models.AdminAction{},
+		&models.APIKey{},
+		&models.SignInEvent{},
+		&models.KYCProfile{},
+		&models.KYCDocument{},
+		&models.Notification{},
+		&models.DataExport{},
+		&models.AccountMember{},
+		&models.AccountInvitation{},
+		&models.TransferConfirmation{},
+		&models.InterbankPayment{},
+		&models.StandingOrder{},
+		&models.SavedPayee{},
+		&models.TransferTemplate{},
 	)
 	if err != nil {
 		return fmt.Errorf("ошибка автоматической миграции: %v", err)
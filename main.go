@@ -5,28 +5,75 @@ import (
 	"awesomeProject/controllers"
 	"awesomeProject/database"
 	"awesomeProject/middleware"
+	"awesomeProject/models"
 	"awesomeProject/services"
 	"fmt"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"time"
 )
 
 //TIP <p>To run your code, right-click the code and select <b>Run</b>.</p> <p>Alternatively, click
 // the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.</p>
 
-func initPaymentScheduler(db *database.Database, emailService *services.EmailService) {
+func initPaymentScheduler(db *database.Database, emailService *services.EmailService, standingOrderService *services.StandingOrderService) {
 	// Создаем сервис кредитов
 	creditService := services.NewCreditService(db.DB, emailService)
 
 	// Создаем планировщик платежей
-	scheduler := services.NewPaymentSchedulerService(db.DB, creditService)
+	scheduler := services.NewPaymentSchedulerService(db.DB, creditService, standingOrderService)
 
 	// Запускаем планировщик
 	scheduler.Start()
 	log.Println("Планировщик платежей запущен")
 }
 
+func initCardKeyRotation(cfg *config.Config, cardService *services.CardService) {
+	// Перешифровываем карты на активную версию ключа
+	cardService.StartKeyRotation(10*time.Minute, cfg.CardReencryptBatch)
+	log.Println("Перешифрование карт запущено")
+}
+
+func initCardScheduler(cardService *services.CardService) {
+	// Следим за сроками действия и перевыпуском карт
+	scheduler := services.NewCardSchedulerService(cardService)
+	scheduler.Start()
+	log.Println("Планировщик карт запущен")
+}
+
+func initDataExportCleanup(dataExportService *services.DataExportService) {
+	// Дособираем выгрузки, прерванные остановкой сервиса
+	count, err := dataExportService.ResumePending()
+	if err != nil {
+		log.Printf("Ошибка при возобновлении выгрузок данных: %v", err)
+	} else if count > 0 {
+		log.Printf("Возобновлена сборка выгрузок данных: %d", count)
+	}
+
+	// Удаляем архивы, срок ссылки на которые истек
+	dataExportService.StartCleanup(time.Hour)
+	log.Println("Очистка выгрузок данных запущена")
+}
+
+func initInterbankPolling(interbankService *services.InterbankService) {
+	// Повторно отправляем и опрашиваем незавершенные межбанковские платежи
+	interbankService.StartPolling(time.Minute)
+	log.Println("Опрос межбанковских платежей запущен")
+}
+
+func initEmailVerificationBackfill(verificationService *services.VerificationService) {
+	// Пользователи, зарегистрированные до подтверждения email, считаются подтвердившими адрес
+	count, err := verificationService.BackfillEmailVerification()
+	if err != nil {
+		log.Printf("Ошибка при заполнении подтверждения email: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Отмечено подтвержденными email: %d", count)
+	}
+}
+
 func main() {
 	// Инициализируем конфигурацию
 	cfg, err := config.NewConfig()
@@ -41,40 +88,276 @@ func main() {
 	}
 
 	// Инициализируем сервис email
-	emailService := services.NewEmailService(cfg)
+	emailService := services.NewEmailService(cfg, db.DB)
+
+	// Инициализируем менеджер ключей карт
+	cardKeys, err := services.NewCardKeyManager(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации ключей карт: %v", err)
+	}
+
+	// Инициализируем генератор номеров карт
+	cardNumbers, err := services.NewCardNumberGenerator(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации генератора номеров карт: %v", err)
+	}
+
+	// Инициализируем генератор номеров счетов
+	accountNumbers, err := services.NewAccountNumberGenerator(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации генератора номеров счетов: %v", err)
+	}
+
+	// Инициализируем сервис банковских счетов
+	bankService := services.NewBankService(db.DB, emailService, accountNumbers)
+
+	// Инициализируем сервис карт
+	cardService := services.NewCardService(
+		db.DB,
+		cfg,
+		cardKeys,
+		cardNumbers,
+		bankService,
+		services.NewUserService(db),
+		emailService,
+	)
+
+	// Запускаем перешифрование карт
+	initCardKeyRotation(cfg, cardService)
+
+	// Запускаем планировщик карт
+	initCardScheduler(cardService)
+
+	// Инициализируем выпуск токенов
+	tokenIssuer, err := services.NewTokenIssuer(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации ключей подписи JWT: %v", err)
+	}
+
+	// Инициализируем сервис сеансов
+	sessionService := services.NewSessionService(db.DB, cfg)
+	sessionService.StartCleanup(time.Hour)
+
+	// Инициализируем сервис двухфакторной аутентификации
+	twoFactorService := services.NewTwoFactorService(db.DB, cfg, cardKeys)
 
-	// Запускаем планировщик платежей
-	initPaymentScheduler(db, emailService)
+	// Инициализируем защиту входа от перебора паролей
+	signInGuard := services.NewSignInGuard(db.DB, cfg, emailService)
+
+	// Инициализируем сервис API-ключей
+	apiKeyService := services.NewAPIKeyService(db.DB, twoFactorService, signInGuard)
+
+	// Инициализируем сервис подтверждения email и сброса пароля
+	verificationService := services.NewVerificationService(db.DB, cfg, emailService, sessionService, apiKeyService)
+	initEmailVerificationBackfill(verificationService)
+
+	// Инициализируем журнал входов
+	signInHistory := services.NewSignInHistory(db.DB, emailService)
+
+	// Инициализируем сервис действий сотрудников банка
+	adminService := services.NewAdminService(db.DB, sessionService, apiKeyService, signInGuard)
+
+	// Инициализируем сервис подтверждения телефона кодом из SMS
+	phoneVerificationService := services.NewPhoneVerificationService(db.DB, services.NewSMSGateway(cfg))
+
+	// Инициализируем сервис профиля пользователя
+	profileService := services.NewProfileService(db.DB, emailService, sessionService, apiKeyService, phoneVerificationService)
+
+	// Инициализируем сервис идентификации клиентов
+	kycService := services.NewKYCService(db.DB, cfg, emailService)
+
+	// Инициализируем сервис выгрузки персональных данных
+	dataExportService := services.NewDataExportService(db.DB, cfg, emailService)
+	initDataExportCleanup(dataExportService)
+
+	// Инициализируем сервис участников совместных счетов
+	accountMemberService := services.NewAccountMemberService(db.DB, cfg, emailService)
+
+	// Инициализируем сервис переводов по номеру счета, email или телефону
+	recipientService := services.NewRecipientService(db.DB, bankService, twoFactorService)
+
+	// Инициализируем переводы в другие банки через СБП
+	interbankService := services.NewInterbankService(db.DB, services.NewSBPRail(cfg), accountNumbers, twoFactorService, emailService)
+	initInterbankPolling(interbankService)
+
+	// Инициализируем постоянные поручения и запускаем планировщик платежей
+	standingOrderService := services.NewStandingOrderService(db.DB, bankService, twoFactorService, emailService)
+	initPaymentScheduler(db, emailService, standingOrderService)
+
+	// Инициализируем сохраненных получателей и шаблоны переводов
+	payeeService := services.NewPayeeService(db.DB, bankService, cardService, interbankService, twoFactorService, cardKeys)
+
+	// Ограничение частоты запросов хранится в памяти процесса
+	rateLimiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(10*time.Minute), cfg.RateLimitTrustProxy)
 
 	// Создаем роутер
 	router := mux.NewRouter()
 
 	// Инициализируем контроллеры
-	authController := controllers.NewAuthController(db)
-	bankController := controllers.NewBankController(db, emailService)
+	authController := controllers.NewAuthController(db, sessionService, tokenIssuer, twoFactorService, verificationService, signInGuard, signInHistory, apiKeyService)
+	bankController := controllers.NewBankController(bankService, twoFactorService)
 	creditController := controllers.NewCreditController(db, emailService)
+	cardController := controllers.NewCardController(cardService, twoFactorService)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorService)
+	adminController := controllers.NewAdminController(adminService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	profileController := controllers.NewProfileController(profileService, verificationService, phoneVerificationService)
+	kycController := controllers.NewKYCController(kycService)
+	dataExportController := controllers.NewDataExportController(dataExportService)
+	accountMemberController := controllers.NewAccountMemberController(accountMemberService)
+	recipientController := controllers.NewRecipientController(recipientService)
+	interbankController := controllers.NewInterbankController(interbankService)
+	standingOrderController := controllers.NewStandingOrderController(standingOrderService)
+	payeeController := controllers.NewPayeeController(payeeService)
 
 	router.Use(middleware.LoggingMiddleware)
-	// Публичные маршруты для аутентификации
-	router.HandleFunc("/api/auth/signUp", authController.SignUp).Methods("POST")
-	router.HandleFunc("/api/auth/signIn", authController.SignIn).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", authController.JWKS).Methods("GET")
+
+	// Публичные маршруты для аутентификации ограничиваются по IP
+	public := router.PathPrefix("/api/auth").Subrouter()
+	public.Use(rateLimiter.ByIP("auth", middleware.PerMinute(cfg.RateLimitAuth)))
+	public.HandleFunc("/signUp", authController.SignUp).Methods("POST")
+	public.HandleFunc("/signIn", authController.SignIn).Methods("POST")
+	public.HandleFunc("/signIn/2fa", authController.SignInTwoFactor).Methods("POST")
+	public.HandleFunc("/refresh", authController.Refresh).Methods("POST")
+	public.HandleFunc("/verify-email", authController.VerifyEmail).Methods("POST")
+	public.HandleFunc("/confirm-email-change", authController.ConfirmEmailChange).Methods("POST")
+	public.HandleFunc("/forgot-password", authController.ForgotPassword).Methods("POST")
+	public.HandleFunc("/reset-password", authController.ResetPassword).Methods("POST")
+
+	// Ссылка на скачивание выгрузки приходит в письме и защищена токеном, а не JWT.
+	// Маршрут регистрируется до защищенных, чтобы префикс /api его не перехватил.
+	router.Handle("/api/me/export/download",
+		rateLimiter.ByIP("export", middleware.PerMinute(cfg.RateLimitAuth))(http.HandlerFunc(dataExportController.Download))).Methods("GET")
+
+	// Уведомления СБП подписываются общим секретом, а не JWT
+	router.HandleFunc("/api/interbank/sbp/notifications", interbankController.Notify).Methods("POST")
 
 	// Защищенные маршруты
 	protected := router.PathPrefix("/api").Subrouter()
-	protected.Use(middleware.AuthMiddleware([]byte(authController.GetJWTKey())))
+	protected.Use(middleware.AuthOrAPIKeyMiddleware(tokenIssuer, sessionService, apiKeyService, apiKeyRoutes))
+	protected.Use(rateLimiter.ByUser("api", middleware.PerMinute(cfg.RateLimitAPI)))
+
+	// Маршруты для завершения сеансов
+	protected.HandleFunc("/auth/logout", authController.Logout).Methods("POST")
+	protected.HandleFunc("/auth/logout-all", authController.LogoutAll).Methods("POST")
+	protected.HandleFunc("/auth/sessions", authController.ListSessions).Methods("GET")
+	protected.HandleFunc("/auth/sessions/{id}", authController.RevokeSession).Methods("DELETE")
+	protected.HandleFunc("/auth/sign-ins", authController.SignInHistory).Methods("GET")
+	protected.HandleFunc("/auth/verify-email/resend", authController.ResendVerification).Methods("POST")
+
+	// Маршруты для двухфакторной аутентификации
+	protected.HandleFunc("/auth/2fa/enroll", twoFactorController.Enroll).Methods("POST")
+	protected.HandleFunc("/auth/2fa/confirm", twoFactorController.Confirm).Methods("POST")
+	protected.HandleFunc("/auth/2fa/disable", twoFactorController.Disable).Methods("POST")
+	protected.HandleFunc("/auth/2fa/recovery-codes", twoFactorController.RegenerateRecoveryCodes).Methods("POST")
+
+	// Профиль пользователя
+	protected.HandleFunc("/profile", profileController.GetProfile).Methods("GET")
+	protected.HandleFunc("/profile", profileController.UpdateProfile).Methods("PUT")
+	protected.HandleFunc("/profile/password", profileController.ChangePassword).Methods("POST")
+	protected.HandleFunc("/profile/email", profileController.ChangeEmail).Methods("POST")
+	protected.HandleFunc("/profile/phone/code", profileController.SendPhoneCode).Methods("POST")
+	protected.HandleFunc("/profile/phone/verify", profileController.ConfirmPhone).Methods("POST")
+	protected.HandleFunc("/profile/close", profileController.CloseProfile).Methods("POST")
+	protected.HandleFunc("/me/export", dataExportController.RequestExport).Methods("POST")
+
+	// Анкета клиента и документы для идентификации
+	protected.HandleFunc("/kyc", kycController.GetStatus).Methods("GET")
+	protected.HandleFunc("/kyc", kycController.Submit).Methods("POST")
+	protected.HandleFunc("/kyc/documents", kycController.UploadDocument).Methods("POST")
+
+	// API-ключи выпускаются и отзываются только по JWT
+	protected.HandleFunc("/auth/api-keys", apiKeyController.Create).Methods("POST")
+	protected.HandleFunc("/auth/api-keys", apiKeyController.List).Methods("GET")
+	protected.HandleFunc("/auth/api-keys/{id}", apiKeyController.Revoke).Methods("DELETE")
+
+	// Операции со средствами доступны только после подтверждения email
+	money := protected.NewRoute().Subrouter()
+	money.Use(middleware.RequireVerifiedEmail(verificationService))
+	money.Use(rateLimiter.ByUser("money", middleware.PerMinute(cfg.RateLimitMoney)))
 
 	// Маршруты для работы с банковскими счетами
 	protected.HandleFunc("/bank/accounts", bankController.CreateBankAccount).Methods("POST")
 	protected.HandleFunc("/bank/accounts", bankController.GetAccounts).Methods("GET")
-	protected.HandleFunc("/bank/accounts/{id}/deposit", bankController.Deposit).Methods("POST")
-	protected.HandleFunc("/bank/accounts/{id}/withdraw", bankController.Withdraw).Methods("POST")
-	protected.HandleFunc("/bank/accounts/{id}/transfer", bankController.Transfer).Methods("POST")
+	money.HandleFunc("/bank/accounts/{id}/deposit", bankController.Deposit).Methods("POST")
+	money.HandleFunc("/bank/accounts/{id}/withdraw", bankController.Withdraw).Methods("POST")
+	money.HandleFunc("/bank/accounts/{id}/transfer", bankController.Transfer).Methods("POST")
+	protected.HandleFunc("/bank/accounts/{id}/close", bankController.CloseAccount).Methods("POST")
+
+	// Переводы по номеру счета, email или телефону. Поиск получателя ограничен
+	// отдельно, чтобы по нему нельзя было перебирать клиентов банка
+	money.Handle("/bank/transfers/prepare",
+		rateLimiter.ByUser("recipient", middleware.PerMinute(cfg.RateLimitRecipient))(http.HandlerFunc(recipientController.PrepareTransfer))).Methods("POST")
+	money.HandleFunc("/bank/transfers/confirm", recipientController.ConfirmTransfer).Methods("POST")
+
+	// Переводы в другие банки через СБП
+	money.HandleFunc("/bank/interbank", interbankController.Send).Methods("POST")
+	protected.HandleFunc("/bank/interbank", interbankController.List).Methods("GET")
+
+	// Постоянные поручения
+	money.HandleFunc("/bank/standing-orders", standingOrderController.Create).Methods("POST")
+	protected.HandleFunc("/bank/standing-orders", standingOrderController.List).Methods("GET")
+	money.HandleFunc("/bank/standing-orders/{id}", standingOrderController.Update).Methods("PUT")
+	protected.HandleFunc("/bank/standing-orders/{id}", standingOrderController.Cancel).Methods("DELETE")
+	protected.HandleFunc("/bank/standing-orders/{id}/pause", standingOrderController.Pause).Methods("POST")
+	money.HandleFunc("/bank/standing-orders/{id}/resume", standingOrderController.Resume).Methods("POST")
+
+	// Сохраненные получатели и шаблоны переводов
+	protected.HandleFunc("/bank/payees", payeeController.CreatePayee).Methods("POST")
+	protected.HandleFunc("/bank/payees", payeeController.ListPayees).Methods("GET")
+	protected.HandleFunc("/bank/payees/{id}", payeeController.UpdatePayee).Methods("PUT")
+	protected.HandleFunc("/bank/payees/{id}", payeeController.DeletePayee).Methods("DELETE")
+	protected.HandleFunc("/bank/templates", payeeController.CreateTemplate).Methods("POST")
+	protected.HandleFunc("/bank/templates", payeeController.ListTemplates).Methods("GET")
+	protected.HandleFunc("/bank/templates/{id}", payeeController.UpdateTemplate).Methods("PUT")
+	protected.HandleFunc("/bank/templates/{id}", payeeController.DeleteTemplate).Methods("DELETE")
+	money.HandleFunc("/bank/templates/{id}/execute", payeeController.ExecuteTemplate).Methods("POST")
+
+	// Совместные счета: участники и приглашения
+	protected.HandleFunc("/bank/accounts/{id}/members", accountMemberController.ListMembers).Methods("GET")
+	protected.HandleFunc("/bank/accounts/{id}/members/{userId}", accountMemberController.RemoveMember).Methods("DELETE")
+	protected.HandleFunc("/bank/accounts/{id}/invitations", accountMemberController.Invite).Methods("POST")
+	protected.HandleFunc("/bank/accounts/{id}/invitations/{invitationId}", accountMemberController.RevokeInvitation).Methods("DELETE")
+	protected.HandleFunc("/bank/invitations/accept", accountMemberController.AcceptInvitation).Methods("POST")
 
 	// Маршруты для работы с кредитами
-	protected.HandleFunc("/bank/credits", creditController.CreateCredit).Methods("POST")
+	money.HandleFunc("/bank/credits", creditController.CreateCredit).Methods("POST")
 	protected.HandleFunc("/bank/credits", creditController.GetCredits).Methods("GET")
 	protected.HandleFunc("/bank/credits/{id}", creditController.GetCredit).Methods("GET")
-	protected.HandleFunc("/bank/credits/{id}/pay", creditController.PayCredit).Methods("POST")
+	money.HandleFunc("/bank/credits/{id}/pay", creditController.PayCredit).Methods("POST")
+
+	// Маршруты для работы с картами
+	protected.HandleFunc("/cards", cardController.CreateCard).Methods("POST")
+	protected.HandleFunc("/cards", cardController.GetCards).Methods("GET")
+	money.HandleFunc("/cards/transfer", cardController.Transfer).Methods("POST")
+	protected.HandleFunc("/cards/{id}/activate", cardController.ActivateCard).Methods("POST")
+	protected.HandleFunc("/cards/{id}/pin", cardController.SetPIN).Methods("POST")
+	protected.HandleFunc("/cards/{id}/pin", cardController.ChangePIN).Methods("PUT")
+	protected.HandleFunc("/cards/{id}/reveal", cardController.RevealCard).Methods("POST")
+
+	// Авторизация операций по картам доступна только процессингу эквайера
+	protected.Handle("/cards/authorize", adminRoute(models.PermissionCardsAcquire, cardController.Authorize)).Methods("POST")
+	protected.Handle("/cards/authorizations/{id}/challenge", adminRoute(models.PermissionCardsAcquire, cardController.CompleteChallenge)).Methods("POST")
+
+	// Маршруты для сотрудников банка, каждый требует своего права
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Handle("/users", adminRoute(models.PermissionUsersRead, adminController.SearchUsers)).Methods("GET")
+	admin.Handle("/users/{id}", adminRoute(models.PermissionUsersRead, adminController.GetUser)).Methods("GET")
+	admin.Handle("/users/{id}/freeze", adminRoute(models.PermissionUsersFreeze, adminController.FreezeUser)).Methods("POST")
+	admin.Handle("/users/{id}/unfreeze", adminRoute(models.PermissionUsersFreeze, adminController.UnfreezeUser)).Methods("POST")
+	admin.Handle("/users/{id}/unlock", adminRoute(models.PermissionUsersUnlock, adminController.UnlockUser)).Methods("POST")
+	admin.Handle("/users/{id}/role", adminRoute(models.PermissionRolesManage, adminController.SetRole)).Methods("PUT")
+	admin.Handle("/credits/{id}/cancel", adminRoute(models.PermissionCreditsManage, adminController.CancelCredit)).Methods("POST")
+	admin.Handle("/credits/{id}/write-off", adminRoute(models.PermissionCreditsManage, adminController.WriteOffCredit)).Methods("POST")
+	admin.Handle("/accounts/{id}/adjustments", adminRoute(models.PermissionAccountsAdjust, adminController.AdjustBalance)).Methods("POST")
+	admin.Handle("/accounts/{id}/freeze", adminRoute(models.PermissionAccountsFreeze, adminController.FreezeAccount)).Methods("POST")
+	admin.Handle("/accounts/{id}/unfreeze", adminRoute(models.PermissionAccountsFreeze, adminController.UnfreezeAccount)).Methods("POST")
+	admin.Handle("/kyc", adminRoute(models.PermissionKYCReview, kycController.ListPending)).Methods("GET")
+	admin.Handle("/kyc/{id}", adminRoute(models.PermissionKYCReview, kycController.GetForReview)).Methods("GET")
+	admin.Handle("/kyc/{id}/documents/{docId}", adminRoute(models.PermissionKYCReview, kycController.GetDocument)).Methods("GET")
+	admin.Handle("/kyc/{id}/approve", adminRoute(models.PermissionKYCReview, kycController.Approve)).Methods("POST")
+	admin.Handle("/kyc/{id}/reject", adminRoute(models.PermissionKYCReview, kycController.Reject)).Methods("POST")
 
 	// Запускаем сервер
 	port := fmt.Sprintf(":%d", cfg.Server.Port)
@@ -83,3 +366,40 @@ func main() {
 		log.Fatalf("Ошибка запуска сервера: %v", err)
 	}
 }
+
+// adminRoute оборачивает обработчик проверкой права сотрудника или эквайера
+func adminRoute(permission models.Permission, handler http.HandlerFunc) http.Handler {
+	return middleware.RequirePermission(permission)(handler)
+}
+
+// apiKeyRoutes маршруты, доступные по API-ключу, и нужные для них области действия.
+// Остальные маршруты принимают только JWT.
+var apiKeyRoutes = middleware.APIKeyRoutes{
+	"GET /api/bank/accounts":                        models.APIKeyScopeAccountsRead,
+	"GET /api/bank/accounts/{id}/members":           models.APIKeyScopeAccountsRead,
+	"GET /api/bank/credits":                         models.APIKeyScopeCreditsRead,
+	"GET /api/bank/credits/{id}":                    models.APIKeyScopeCreditsRead,
+	"GET /api/cards":                                models.APIKeyScopeCardsRead,
+	"POST /api/bank/accounts/{id}/transfer":         models.APIKeyScopeTransfersCreate,
+	"POST /api/bank/transfers/prepare":              models.APIKeyScopeTransfersCreate,
+	"POST /api/bank/transfers/confirm":              models.APIKeyScopeTransfersCreate,
+	"POST /api/cards/transfer":                      models.APIKeyScopeTransfersCreate,
+	"POST /api/cards/authorize":                     models.APIKeyScopeCardsAcquire,
+	"POST /api/cards/authorizations/{id}/challenge": models.APIKeyScopeCardsAcquire,
+	"GET /api/bank/interbank":                       models.APIKeyScopeAccountsRead,
+	"POST /api/bank/interbank":                      models.APIKeyScopeTransfersCreate,
+	"GET /api/bank/standing-orders":                 models.APIKeyScopeAccountsRead,
+	"GET /api/bank/payees":                          models.APIKeyScopeAccountsRead,
+	"GET /api/bank/templates":                       models.APIKeyScopeAccountsRead,
+	"POST /api/bank/templates/{id}/execute":         models.APIKeyScopeTransfersCreate,
+	"GET /api/admin/users":                          models.APIKeyScopeAdmin,
+	"GET /api/admin/users/{id}":                     models.APIKeyScopeAdmin,
+	"POST /api/admin/users/{id}/freeze":             models.APIKeyScopeAdmin,
+	"POST /api/admin/users/{id}/unfreeze":           models.APIKeyScopeAdmin,
+	"POST /api/admin/users/{id}/unlock":             models.APIKeyScopeAdmin,
+	"POST /api/admin/credits/{id}/cancel":           models.APIKeyScopeAdmin,
+	"POST /api/admin/credits/{id}/write-off":        models.APIKeyScopeAdmin,
+	"POST /api/admin/accounts/{id}/adjustments":     models.APIKeyScopeAdmin,
+	"POST /api/admin/accounts/{id}/freeze":          models.APIKeyScopeAdmin,
+	"POST /api/admin/accounts/{id}/unfreeze":        models.APIKeyScopeAdmin,
+}
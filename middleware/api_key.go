@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"context"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// APIKeyHeader заголовок, в котором передается API-ключ
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator проверяет API-ключ и возвращает его вместе с владельцем
+type APIKeyAuthenticator interface {
+	Authenticate(key, ip string) (*models.APIKey, *models.User, error)
+}
+
+// APIKeyRoutes задает область действия, нужную API-ключу для маршрута.
+// Ключ имеет вид "МЕТОД шаблон-пути", например "GET /api/bank/accounts".
+// Маршруты, которых нет в таблице, по API-ключу недоступны.
+type APIKeyRoutes map[string]models.APIKeyScope
+
+// AuthOrAPIKeyMiddleware работает как AuthMiddleware, но дополнительно принимает
+// API-ключ в заголовке X-API-Key. Запрос по ключу выполняется от имени владельца
+// ключа и допускается только к маршрутам из routes с выданной ключу областью действия.
+func AuthOrAPIKeyMiddleware(tokens TokenVerifier, sessions SessionChecker, keys APIKeyAuthenticator, routes APIKeyRoutes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				r, ok := authenticateJWT(w, r, tokens, sessions)
+				if !ok {
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			apiKey, user, err := keys.Authenticate(key, utils.ClientIP(r))
+			if err != nil {
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+
+			scope, ok := routes[routeKey(r)]
+			if !ok {
+				http.Error(w, "Маршрут недоступен по API-ключу", http.StatusForbidden)
+				return
+			}
+			if !apiKey.HasScope(scope) {
+				http.Error(w, "У API-ключа нет области действия "+string(scope), http.StatusForbidden)
+				return
+			}
+
+			r.Header.Set("X-User-ID", strconv.FormatUint(uint64(user.ID), 10))
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, "user_id", user.ID)
+			ctx = context.WithValue(ctx, "email", user.Email)
+			ctx = context.WithValue(ctx, "role", user.Role)
+			ctx = context.WithValue(ctx, "api_key_id", apiKey.ID)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeKey возвращает ключ маршрута для таблицы APIKeyRoutes
+func routeKey(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return r.Method + " " + template
+}
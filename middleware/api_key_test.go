@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/services"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeAPIKeys struct{}
+
+func (fakeAPIKeys) Authenticate(key, ip string) (*models.APIKey, *models.User, error) {
+	if key != "ak_valid" {
+		return nil, nil, errors.New("invalid")
+	}
+	return &models.APIKey{ID: 3, UserID: 7, Scopes: "accounts:read"},
+		&models.User{ID: 7, Email: "robot@example.com", Role: models.RoleCustomer}, nil
+}
+
+type rejectingTokens struct{}
+
+func (rejectingTokens) Parse(string) (*services.AccessClaims, error) {
+	return nil, errors.New("invalid")
+}
+
+type noSessions struct{}
+
+func (noSessions) IsActive(uint, string) bool { return false }
+
+func TestAuthOrAPIKeyMiddlewareScopes(t *testing.T) {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(AuthOrAPIKeyMiddleware(rejectingTokens{}, noSessions{}, fakeAPIKeys{}, APIKeyRoutes{
+		"GET /api/bank/accounts":                models.APIKeyScopeAccountsRead,
+		"POST /api/bank/accounts/{id}/transfer": models.APIKeyScopeTransfersCreate,
+	}))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value("user_id").(uint) != 7 {
+			t.Error("user_id from API key owner is missing in context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	api.HandleFunc("/bank/accounts", handler).Methods("GET")
+	api.HandleFunc("/bank/accounts/{id}/transfer", handler).Methods("POST")
+	api.HandleFunc("/auth/logout", handler).Methods("POST")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"scope granted", http.MethodGet, "/api/bank/accounts", "ak_valid", http.StatusOK},
+		{"scope missing", http.MethodPost, "/api/bank/accounts/1/transfer", "ak_valid", http.StatusForbidden},
+		{"route not allowed for keys", http.MethodPost, "/api/auth/logout", "ak_valid", http.StatusForbidden},
+		{"invalid key", http.MethodGet, "/api/bank/accounts", "ak_stolen", http.StatusUnauthorized},
+		{"no key falls back to JWT", http.MethodGet, "/api/bank/accounts", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				r.Header.Set(APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package middleware
 
 import (
+	"awesomeProject/services"
 	"context"
 	"fmt"
-	"github.com/golang-jwt/jwt/v5"
 	"log"
 	"net/http"
 	"strconv"
@@ -40,6 +40,12 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// Обрабатываем запрос
 		next.ServeHTTP(lrw, r)
 
+		// Тело ответов с конфиденциальными данными (Cache-Control: no-store) не логируем
+		body := string(lrw.body)
+		if lrw.Header().Get("Cache-Control") == "no-store" {
+			body = "[скрыто]"
+		}
+
 		// Логируем информацию
 		duration := time.Since(start)
 		log.Printf(
@@ -48,65 +54,80 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			r.URL.Path,
 			lrw.statusCode,
 			duration,
-			string(lrw.body),
+			body,
 		)
 	})
 }
 
-// AuthMiddleware проверяет JWT токен и добавляет заголовок X-User-ID
-func AuthMiddleware(jwtKey []byte) func(http.Handler) http.Handler {
+// SessionChecker проверяет, что сеанс и access-токен не были отозваны
+type SessionChecker interface {
+	IsActive(sessionID uint, jti string) bool
+}
+
+// TokenVerifier проверяет подпись и claims access-токена
+type TokenVerifier interface {
+	Parse(tokenString string) (*services.AccessClaims, error)
+}
+
+// AuthMiddleware проверяет JWT токен и добавляет заголовок X-User-ID.
+// Токены отозванных сеансов и отозванные по jti токены отклоняются.
+func AuthMiddleware(tokens TokenVerifier, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Получаем токен из заголовка
-			tokenString := r.Header.Get("Authorization")
-			if tokenString == "" {
-				http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+			r, ok := authenticateJWT(w, r, tokens, sessions)
+			if !ok {
 				return
 			}
 
-			// Убираем префикс "Bearer " если он есть
-			if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-				tokenString = tokenString[7:]
-			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticateJWT проверяет access-токен из заголовка Authorization и возвращает запрос
+// с данными пользователя в контексте. При ошибке ответ уже записан и возвращается false.
+func authenticateJWT(w http.ResponseWriter, r *http.Request, tokens TokenVerifier, sessions SessionChecker) (*http.Request, bool) {
+	// Получаем токен из заголовка
+	tokenString := r.Header.Get("Authorization")
+	if tokenString == "" {
+		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+		return r, false
+	}
 
-			// Парсим и проверяем токен
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return jwtKey, nil
-			})
+	// Убираем префикс "Bearer " если он есть
+	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+		tokenString = tokenString[7:]
+	}
 
-			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
-				return
-			}
+	// Проверяем подпись, срок действия, iss, aud, sub и jti
+	claims, err := tokens.Parse(tokenString)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return r, false
+	}
 
-			// Проверяем claims
-			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-				// Получаем user_id из claims
-				userID, ok := claims["user_id"].(float64)
-				if !ok {
-					http.Error(w, "Invalid user_id in token", http.StatusUnauthorized)
-					return
-				}
-
-				// Добавляем заголовок X-User-ID
-				r.Header.Set("X-User-ID", strconv.FormatUint(uint64(userID), 10))
-
-				// Добавляем информацию о пользователе в контекст запроса
-				ctx := r.Context()
-				ctx = context.WithValue(ctx, "user_id", uint(userID))
-				ctx = context.WithValue(ctx, "email", claims["email"].(string))
-				r = r.WithContext(ctx)
-			} else {
-				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-				return
-			}
+	userID, err := claims.UserID()
+	if err != nil {
+		http.Error(w, "Invalid subject in token", http.StatusUnauthorized)
+		return r, false
+	}
 
-			next.ServeHTTP(w, r)
-		})
+	// Токен должен принадлежать действующему сеансу
+	if !sessions.IsActive(claims.SessionID, claims.ID) {
+		http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+		return r, false
 	}
+
+	// Добавляем заголовок X-User-ID
+	r.Header.Set("X-User-ID", strconv.FormatUint(uint64(userID), 10))
+
+	// Добавляем информацию о пользователе в контекст запроса
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, "user_id", userID)
+	ctx = context.WithValue(ctx, "email", claims.Email)
+	ctx = context.WithValue(ctx, "session_id", claims.SessionID)
+	ctx = context.WithValue(ctx, "role", claims.Role)
+	return r.WithContext(ctx), true
 }
 
 // GetUserFromContext получает информацию о пользователе из контекста
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// EmailVerificationChecker проверяет, подтвердил ли пользователь email
+type EmailVerificationChecker interface {
+	IsEmailVerified(userID uint) bool
+}
+
+// RequireVerifiedEmail пропускает запрос только для пользователей с подтвержденным email.
+// Используется после AuthMiddleware для маршрутов, работающих с деньгами.
+func RequireVerifiedEmail(checker EmailVerificationChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value("user_id").(uint)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !checker.IsEmailVerified(userID) {
+				http.Error(w, "Подтвердите email, чтобы выполнять операции со средствами", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"awesomeProject/models"
+	"net/http"
+)
+
+// RequirePermission пропускает запрос только если роль пользователя из access-токена
+// имеет право permission. Используется после AuthMiddleware.
+func RequirePermission(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value("role").(models.Role)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !role.Has(permission) {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
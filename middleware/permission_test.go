@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"awesomeProject/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	handler := RequirePermission(models.PermissionCreditsManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name string
+		role interface{}
+		want int
+	}{
+		{"admin", models.RoleAdmin, http.StatusOK},
+		{"support without permission", models.RoleSupport, http.StatusForbidden},
+		{"customer", models.RoleCustomer, http.StatusForbidden},
+		{"unknown role", models.Role("ROOT"), http.StatusForbidden},
+		{"no role in context", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/credits/1/cancel", nil)
+			if tt.role != nil {
+				r = r.WithContext(context.WithValue(r.Context(), "role", tt.role))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"awesomeProject/utils"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit описывает ведро токенов: Burst запросов подряд,
+// затем пополнение со скоростью Rate запросов в секунду
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute возвращает лимит в n запросов в минуту с запасом в n запросов подряд
+func PerMinute(n int) RateLimit {
+	return RateLimit{Rate: float64(n) / 60, Burst: n}
+}
+
+// RateLimitStore хранит состояние ведер токенов. Реализация в памяти подходит
+// для одного экземпляра сервиса; для нескольких экземпляров нужно общее хранилище.
+type RateLimitStore interface {
+	// Take списывает токен из ведра key. Если токенов нет, возвращает false
+	// и время до появления следующего токена.
+	Take(key string, limit RateLimit, now time.Time) (bool, time.Duration)
+}
+
+// tokenBucket состояние одного ведра
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	lastSeen time.Time
+}
+
+// MemoryRateLimitStore хранит ведра токенов в памяти процесса
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	idleTTL time.Duration
+}
+
+// NewMemoryRateLimitStore создает хранилище в памяти. Ведра, к которым не было
+// обращений дольше idleTTL, периодически удаляются.
+func NewMemoryRateLimitStore(idleTTL time.Duration) *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		idleTTL: idleTTL,
+	}
+
+	ticker := time.NewTicker(idleTTL)
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				store.cleanup(now)
+			}
+		}
+	}()
+
+	return store
+}
+
+// Take реализует RateLimitStore
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+
+	// Пополняем ведро за прошедшее время, но не выше Burst
+	elapsed := now.Sub(bucket.updated).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.Rate)
+		bucket.updated = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	if limit.Rate <= 0 {
+		return false, time.Minute
+	}
+	wait := time.Duration((1 - bucket.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup удаляет давно не использовавшиеся ведра
+func (s *MemoryRateLimitStore) cleanup(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.lastSeen) > s.idleTTL {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimiter ограничивает частоту запросов по IP-адресу или пользователю
+type RateLimiter struct {
+	store      RateLimitStore
+	trustProxy bool
+}
+
+// NewRateLimiter создает RateLimiter. Если trustProxy включен, IP клиента
+// берется из X-Forwarded-For — только когда сервис стоит за доверенным прокси.
+func NewRateLimiter(store RateLimitStore, trustProxy bool) *RateLimiter {
+	return &RateLimiter{
+		store:      store,
+		trustProxy: trustProxy,
+	}
+}
+
+// ByIP ограничивает запросы группы маршрутов name с одного IP-адреса
+func (l *RateLimiter) ByIP(name string, limit RateLimit) func(http.Handler) http.Handler {
+	return l.middleware(limit, func(r *http.Request) string {
+		return name + ":ip:" + l.clientIP(r)
+	})
+}
+
+// ByUser ограничивает запросы группы маршрутов name от одного пользователя.
+// Используется после AuthMiddleware; без пользователя в контексте ограничивает по IP.
+func (l *RateLimiter) ByUser(name string, limit RateLimit) func(http.Handler) http.Handler {
+	return l.middleware(limit, func(r *http.Request) string {
+		if userID, ok := r.Context().Value("user_id").(uint); ok {
+			return name + ":user:" + strconv.FormatUint(uint64(userID), 10)
+		}
+		return name + ":ip:" + l.clientIP(r)
+	})
+}
+
+func (l *RateLimiter) middleware(limit RateLimit, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter := l.store.Take(key(r), limit, time.Now())
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, "Слишком много запросов, повторите позже", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP возвращает IP-адрес клиента для ключа ограничения
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		return utils.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
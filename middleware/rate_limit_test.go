@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreBurstAndRefill(t *testing.T) {
+	store := &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), idleTTL: time.Hour}
+	limit := RateLimit{Rate: 1, Burst: 3}
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := store.Take("key", limit, now); !ok {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+
+	ok, retryAfter := store.Take("key", limit, now)
+	if ok {
+		t.Fatal("request over burst was allowed")
+	}
+	if retryAfter != time.Second {
+		t.Errorf("retryAfter = %v, want 1s", retryAfter)
+	}
+
+	// Другой ключ не зависит от первого
+	if ok, _ := store.Take("other", limit, now); !ok {
+		t.Error("independent key was rejected")
+	}
+
+	// Через секунду появляется ровно один токен
+	now = now.Add(time.Second)
+	if ok, _ := store.Take("key", limit, now); !ok {
+		t.Error("request after refill was rejected")
+	}
+	if ok, _ := store.Take("key", limit, now); ok {
+		t.Error("second request after single refill was allowed")
+	}
+
+	// Ведро не наполняется выше Burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		store.Take("key", limit, now)
+	}
+	if ok, _ := store.Take("key", limit, now); ok {
+		t.Error("bucket refilled above burst")
+	}
+}
+
+func TestRateLimiterByIP(t *testing.T) {
+	store := &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), idleTTL: time.Hour}
+	limiter := NewRateLimiter(store, false)
+	handler := limiter.ByIP("auth", RateLimit{Rate: 0.001, Burst: 2})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/signIn", nil)
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	request("10.0.0.1:1234", "")
+	request("10.0.0.1:1235", "")
+
+	// Подмена X-Forwarded-For не обходит ограничение без доверенного прокси
+	w := request("10.0.0.1:1236", "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header is missing")
+	}
+
+	if w := request("10.0.0.2:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("other IP got status %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+)
+
+// AccountRole представляет роль участника счета
+type AccountRole string
+
+const (
+	AccountRoleOwner   AccountRole = "OWNER"    // Владелец счета (HolderID), хранится в самом счете
+	AccountRoleCoOwner AccountRole = "CO_OWNER" // Совладелец: те же права, что у владельца, кроме назначения совладельцев
+	AccountRoleViewer  AccountRole = "VIEWER"   // Просмотр счета, операций и кредитов
+	AccountRoleSpender AccountRole = "SPENDER"  // Расходные операции в пределах дневного лимита списаний
+)
+
+// AccountAction представляет действие со счетом, требующее прав
+type AccountAction string
+
+const (
+	AccountActionView    AccountAction = "view"    // Просмотр счета, карт и кредитов
+	AccountActionDeposit AccountAction = "deposit" // Пополнение счета
+	AccountActionSpend   AccountAction = "spend"   // Списание, переводы, платежи по кредиту и операции по картам
+	AccountActionManage  AccountAction = "manage"  // Выпуск карт, кредиты и управление участниками
+)
+
+// accountRoleActions действия, доступные каждой роли участника
+var accountRoleActions = map[AccountRole][]AccountAction{
+	AccountRoleOwner:   {AccountActionView, AccountActionDeposit, AccountActionSpend, AccountActionManage},
+	AccountRoleCoOwner: {AccountActionView, AccountActionDeposit, AccountActionSpend, AccountActionManage},
+	AccountRoleViewer:  {AccountActionView},
+	AccountRoleSpender: {AccountActionView, AccountActionDeposit, AccountActionSpend},
+}
+
+// Can проверяет, доступно ли действие роли
+func (r AccountRole) Can(action AccountAction) bool {
+	for _, a := range accountRoleActions[r] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInvitable проверяет, что роль можно выдать по приглашению.
+// Владелец у счета один, он задается при открытии счета.
+func (r AccountRole) IsInvitable() bool {
+	return r == AccountRoleCoOwner || r == AccountRoleViewer || r == AccountRoleSpender
+}
+
+// AccountMember представляет участника совместного счета или доверенное лицо
+type AccountMember struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement"`
+	AccountID  uint        `gorm:"not null;uniqueIndex:idx_account_members_account_user"`
+	UserID     uint        `gorm:"not null;uniqueIndex:idx_account_members_account_user;index"`
+	User       User        `gorm:"foreignKey:UserID"`
+	Role       AccountRole `gorm:"type:varchar(10);not null"`
+	SpendLimit float64     `gorm:"type:decimal(20,2);not null;default:0"` // Дневной лимит списаний для SPENDER
+	SpentToday float64     `gorm:"type:decimal(20,2);not null;default:0"` // Списано участником за день SpentOn
+	SpentOn    *time.Time  // День, за который учтена сумма SpentToday
+	InvitedBy  uint        `gorm:"not null"`
+	CreatedAt  time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели AccountMember
+func (AccountMember) TableName() string {
+	return "account_members"
+}
+
+// AccountInvitation представляет приглашение к счету по email
+type AccountInvitation struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement"`
+	AccountID  uint        `gorm:"not null;index"`
+	Email      string      `gorm:"size:100;not null"`
+	Role       AccountRole `gorm:"type:varchar(10);not null"`
+	SpendLimit float64     `gorm:"type:decimal(20,2);not null;default:0"`
+	TokenHash  string      `gorm:"size:64;not null;uniqueIndex"` // SHA-256 токена из ссылки
+	InvitedBy  uint        `gorm:"not null"`
+	ExpiresAt  time.Time   `gorm:"not null"`
+	AcceptedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели AccountInvitation
+func (AccountInvitation) TableName() string {
+	return "account_invitations"
+}
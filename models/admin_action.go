@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminAction представляет запись журнала действий сотрудников банка
+type AdminAction struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	AdminID    uint      `gorm:"not null;index"`
+	Action     string    `gorm:"size:50;not null;index"`
+	TargetType string    `gorm:"size:30;not null"` // user, account, credit
+	TargetID   uint      `gorm:"not null"`
+	Reason     string    `gorm:"size:500;not null"`
+	Details    string    `gorm:"size:500"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели AdminAction
+func (AdminAction) TableName() string {
+	return "admin_actions"
+}
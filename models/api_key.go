@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKeyScope представляет область действия API-ключа
+type APIKeyScope string
+
+const (
+	APIKeyScopeAccountsRead    APIKeyScope = "accounts:read"    // Просмотр счетов
+	APIKeyScopeCreditsRead     APIKeyScope = "credits:read"     // Просмотр кредитов
+	APIKeyScopeCardsRead       APIKeyScope = "cards:read"       // Просмотр карт
+	APIKeyScopeTransfersCreate APIKeyScope = "transfers:create" // Переводы между счетами и картами
+	APIKeyScopeCardsAcquire    APIKeyScope = "cards:acquire"    // Авторизация операций по картам для эквайера
+	APIKeyScopeAdmin           APIKeyScope = "admin"            // Административные маршруты в пределах роли владельца
+)
+
+// APIKeyScopes все известные области действия
+var APIKeyScopes = []APIKeyScope{
+	APIKeyScopeAccountsRead,
+	APIKeyScopeCreditsRead,
+	APIKeyScopeCardsRead,
+	APIKeyScopeTransfersCreate,
+	APIKeyScopeCardsAcquire,
+	APIKeyScopeAdmin,
+}
+
+// IsValid проверяет, что область действия существует
+func (s APIKeyScope) IsValid() bool {
+	for _, scope := range APIKeyScopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey представляет ключ для межсервисных интеграций. Ключ действует от имени
+// владельца и только в пределах выданных областей действия.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `gorm:"not null;index"`
+	Name       string     `gorm:"size:100;not null"`
+	Prefix     string     `gorm:"size:16;not null"`             // Начало ключа для отображения в списке
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex"` // SHA-256 ключа
+	Scopes     string     `gorm:"size:255;not null"`            // Области действия через запятую
+	ExpiresAt  *time.Time // Без срока действия ключ действует до отзыва
+	LastUsedAt *time.Time
+	LastUsedIP string     `gorm:"size:45"`
+	RevokedAt  *time.Time `gorm:"index"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList возвращает области действия ключа
+func (k *APIKey) ScopeList() []APIKeyScope {
+	var scopes []APIKeyScope
+	for _, scope := range strings.Split(k.Scopes, ",") {
+		if scope != "" {
+			scopes = append(scopes, APIKeyScope(scope))
+		}
+	}
+	return scopes
+}
+
+// HasScope проверяет, выдана ли ключу область действия
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
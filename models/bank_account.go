@@ -4,18 +4,67 @@ import (
 	"time"
 )
 
+// AccountStatus представляет статус банковского счета
+type AccountStatus string
+
+const (
+	AccountStatusActive       AccountStatus = "ACTIVE"        // Счет открыт, операции разрешены
+	AccountStatusFrozen       AccountStatus = "FROZEN"        // Счет заморожен банком, операции запрещены
+	AccountStatusDebitBlocked AccountStatus = "DEBIT_BLOCKED" // Запрещены списания, зачисления разрешены
+	AccountStatusClosed       AccountStatus = "CLOSED"        // Счет закрыт
+)
+
+// CanDebit проверяет, разрешены ли списания со счета
+func (s AccountStatus) CanDebit() bool {
+	return s == AccountStatusActive
+}
+
+// CanCredit проверяет, разрешены ли зачисления на счет
+func (s AccountStatus) CanCredit() bool {
+	return s == AccountStatusActive || s == AccountStatusDebitBlocked
+}
+
+// AccountBlockReason представляет код причины ограничения счета сотрудником банка
+type AccountBlockReason string
+
+const (
+	AccountBlockFraudSuspected  AccountBlockReason = "FRAUD_SUSPECTED"  // Подозрение на мошенничество
+	AccountBlockCourtOrder      AccountBlockReason = "COURT_ORDER"      // Решение суда или требование пристава
+	AccountBlockAMLReview       AccountBlockReason = "AML_REVIEW"       // Проверка по 115-ФЗ
+	AccountBlockCustomerRequest AccountBlockReason = "CUSTOMER_REQUEST" // Обращение клиента
+	AccountBlockDeceased        AccountBlockReason = "DECEASED"         // Смерть владельца счета
+)
+
+// IsValid проверяет, что код причины известен
+func (r AccountBlockReason) IsValid() bool {
+	switch r {
+	case AccountBlockFraudSuspected, AccountBlockCourtOrder, AccountBlockAMLReview, AccountBlockCustomerRequest, AccountBlockDeceased:
+		return true
+	}
+	return false
+}
+
 type BankAccount struct {
-	ID          uint          `gorm:"primaryKey;autoIncrement"`
-	Bank        string        `gorm:"column:bank;not null"`
-	Number      string        `gorm:"column:number;unique;not null"`
-	Title       string        `gorm:"column:title;not null"`
-	Balance     float64       `gorm:"column:balance;type:decimal(20,2);not null;default:0.0"`
-	HolderID    uint          `gorm:"column:holder_id;not null"`
-	Holder      User          `gorm:"foreignKey:HolderID;references:ID"`
-	Transaction []Transaction `gorm:"foreignKey:AccountID"`
-	Cards       []Card        `gorm:"foreignKey:AccountID"`
-	CreatedAt   time.Time     `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time     `gorm:"column:updated_at;default:CURRENT_TIMESTAMP"`
+	ID          uint               `gorm:"primaryKey;autoIncrement"`
+	Bank        string             `gorm:"column:bank;not null"`
+	Number      string             `gorm:"column:number;unique;not null"`
+	Title       string             `gorm:"column:title;not null"`
+	Balance     float64            `gorm:"column:balance;type:decimal(20,2);not null;default:0.0"`
+	HeldAmount  float64            `gorm:"column:held_amount;type:decimal(20,2);not null;default:0.0"` // Средства, удержанные до завершения межбанковских платежей
+	HolderID    uint               `gorm:"column:holder_id;not null"`
+	Holder      User               `gorm:"foreignKey:HolderID;references:ID"`
+	Status      AccountStatus      `gorm:"column:status;type:varchar(15);not null;default:'ACTIVE'"`
+	BlockReason AccountBlockReason `gorm:"column:block_reason;type:varchar(20)"` // Код причины заморозки или блокировки списаний
+	ClosedAt    *time.Time         `gorm:"column:closed_at"`
+	Transaction []Transaction      `gorm:"foreignKey:AccountID"`
+	Cards       []Card             `gorm:"foreignKey:AccountID"`
+	CreatedAt   time.Time          `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time          `gorm:"column:updated_at;default:CURRENT_TIMESTAMP"`
+}
+
+// Available возвращает остаток, доступный для списания, за вычетом удержаний
+func (a *BankAccount) Available() float64 {
+	return a.Balance - a.HeldAmount
 }
 
 func (BankAccount) TableName() string {
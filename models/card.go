@@ -2,16 +2,42 @@ package models
 
 import (
 	"gorm.io/gorm"
+	"time"
+)
+
+// CardStatus представляет статус карты
+type CardStatus string
+
+const (
+	CardStatusActive   CardStatus = "ACTIVE"   // Карта активна
+	CardStatusBlocked  CardStatus = "BLOCKED"  // Карта заблокирована
+	CardStatusIssued   CardStatus = "ISSUED"   // Перевыпущенная карта ожидает активации
+	CardStatusExpired  CardStatus = "EXPIRED"  // Срок действия карты истек
+	CardStatusReplaced CardStatus = "REPLACED" // Карта заменена активированной перевыпущенной картой
+	CardStatusClosed   CardStatus = "CLOSED"   // Карта закрыта вместе со счетом
 )
 
 // Card представляет банковскую карту
 type Card struct {
 	gorm.Model
 	NumberEncrypted     string      `gorm:"not null"`
-	NumberHMAC          string      `gorm:"not null"`
+	NumberHMAC          string      `gorm:"not null;index"`
+	NumberMasked        string      // Маскированный номер для отображения без расшифровки
 	ExpirationEncrypted string      `gorm:"not null"`
 	ExpirationHMAC      string      `gorm:"not null"`
+	ExpiresAt           *time.Time  `gorm:"index"` // Окончание срока действия для выборки карт без расшифровки
 	CVV                 string      `gorm:"not null"`
+	KeyVersion          int         `gorm:"not null;default:1;index"` // Версия ключей, которой зашифрованы данные
+	Status              CardStatus  `gorm:"type:varchar(20);not null;default:'ACTIVE'"`
+	PINHash             string      // Ключевой хэш PIN-кода
+	PINAttempts         int         `gorm:"not null;default:0"` // Неудачные попытки ввода PIN подряд
+	CVVAttempts         int         `gorm:"not null;default:0"` // Неверные CVV и сроки действия подряд
+	DynamicCVVHash      string      // Хэш динамического CVV, выданного при показе реквизитов
+	DynamicCVVExpiresAt *time.Time  // Срок действия динамического CVV
+	SingleLimit         float64     `gorm:"type:decimal(20,2);not null;default:0"` // Лимит на одну операцию
+	DailyLimit          float64     `gorm:"type:decimal(20,2);not null;default:0"` // Лимит операций за сутки
+	ReplacesCardID      *uint       `gorm:"index"`                                 // Карта, взамен которой выпущена эта
+	ReplacedByCardID    *uint       // Перевыпущенная карта
 	AccountID           uint        `gorm:"not null"`
 	Account             BankAccount `gorm:"foreignKey:AccountID"`
 }
@@ -20,3 +46,35 @@ type Card struct {
 func (Card) TableName() string {
 	return "cards"
 }
+
+// CardRevealAudit представляет запись журнала показа реквизитов карты
+type CardRevealAudit struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	CardID    uint      `gorm:"not null;index"`
+	UserID    uint      `gorm:"not null;index"`
+	IP        string    `gorm:"size:45"`
+	UserAgent string    `gorm:"size:255"`
+	Success   bool      `gorm:"not null"`
+	Reason    string    `gorm:"size:255"` // Причина отказа
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели CardRevealAudit
+func (CardRevealAudit) TableName() string {
+	return "card_reveal_audits"
+}
+
+// CardTransfer представляет перевод с карты на карту
+type CardTransfer struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement"`
+	SourceCardID      uint      `gorm:"not null;index"`
+	DestinationCardID uint      `gorm:"not null;index"`
+	Amount            float64   `gorm:"type:decimal(20,2);not null"`
+	Fee               float64   `gorm:"type:decimal(20,2);not null;default:0"`
+	CreatedAt         time.Time `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+// TableName возвращает имя таблицы для модели CardTransfer
+func (CardTransfer) TableName() string {
+	return "card_transfers"
+}
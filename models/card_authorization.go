@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// CardAuthorizationStatus представляет статус авторизации по карте
+type CardAuthorizationStatus string
+
+const (
+	CardAuthorizationApproved         CardAuthorizationStatus = "APPROVED"          // Операция одобрена
+	CardAuthorizationDeclined         CardAuthorizationStatus = "DECLINED"          // Операция отклонена
+	CardAuthorizationPendingChallenge CardAuthorizationStatus = "PENDING_CHALLENGE" // Ожидается одноразовый код
+	CardAuthorizationExpired          CardAuthorizationStatus = "EXPIRED"           // Код не был введен вовремя
+)
+
+// CardAuthorization представляет авторизацию операции по карте
+type CardAuthorization struct {
+	ID                  uint                    `gorm:"primaryKey;autoIncrement"`
+	CardID              uint                    `gorm:"not null;index"`
+	Card                Card                    `gorm:"foreignKey:CardID"`
+	AcquirerID          uint                    `gorm:"not null;default:0;index"` // Пользователь эквайера, запросивший авторизацию
+	SettlementAccountID uint                    `gorm:"not null;default:0"`       // Счет эквайера для зачисления средств
+	Amount              float64                 `gorm:"type:decimal(20,2);not null"`
+	Channel             string                  `gorm:"size:10;not null"`
+	Merchant            string                  `gorm:"size:100"`
+	Status              CardAuthorizationStatus `gorm:"type:varchar(20);not null;index"`
+	DeclineReason       string                  `gorm:"size:255"`
+	ChallengeCodeHash   string                  // Хэш одноразового кода подтверждения
+	ChallengeTokenHash  string                  `gorm:"size:64;index"` // SHA-256 токена, по которому эквайер подтверждает операцию
+	ChallengeAttempts   int                     `gorm:"not null;default:0"`
+	ChallengeExpiresAt  *time.Time
+	CreatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели CardAuthorization
+func (CardAuthorization) TableName() string {
+	return "card_authorizations"
+}
@@ -22,10 +22,11 @@ type Credit struct {
 type CreditStatus string
 
 const (
-	CreditStatusActive   CreditStatus = "ACTIVE"
-	CreditStatusPaid     CreditStatus = "PAID"
-	CreditStatusOverdue  CreditStatus = "OVERDUE"
-	CreditStatusCanceled CreditStatus = "CANCELED"
+	CreditStatusActive     CreditStatus = "ACTIVE"
+	CreditStatusPaid       CreditStatus = "PAID"
+	CreditStatusOverdue    CreditStatus = "OVERDUE"
+	CreditStatusCanceled   CreditStatus = "CANCELED"
+	CreditStatusWrittenOff CreditStatus = "WRITTEN_OFF" // Задолженность списана банком
 )
 
 // TableName возвращает имя таблицы для модели Credit
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// DataExportStatus представляет статус выгрузки персональных данных
+type DataExportStatus string
+
+const (
+	DataExportStatusPending DataExportStatus = "PENDING" // Архив собирается
+	DataExportStatusReady   DataExportStatus = "READY"   // Архив готов, ссылка отправлена
+	DataExportStatusFailed  DataExportStatus = "FAILED"  // Собрать архив не удалось
+	DataExportStatusExpired DataExportStatus = "EXPIRED" // Срок ссылки истек, архив удален
+)
+
+// DataExport представляет запрос пользователя на выгрузку его данных
+type DataExport struct {
+	ID          uint             `gorm:"primaryKey;autoIncrement"`
+	UserID      uint             `gorm:"not null;index"`
+	Status      DataExportStatus `gorm:"type:varchar(10);not null;default:'PENDING'"`
+	TokenHash   string           `gorm:"size:64;index"` // SHA-256 токена ссылки на скачивание
+	FilePath    string           `gorm:"size:255"`
+	Size        int64
+	ExpiresAt   *time.Time `gorm:"index"`
+	CompletedAt *time.Time
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели DataExport
+func (DataExport) TableName() string {
+	return "data_exports"
+}
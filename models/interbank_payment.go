@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// InterbankDirection представляет направление межбанковского платежа
+type InterbankDirection string
+
+const (
+	InterbankOutgoing InterbankDirection = "OUTGOING" // Платеж клиента в другой банк
+	InterbankIncoming InterbankDirection = "INCOMING" // Платеж из другого банка клиенту
+)
+
+// InterbankStatus представляет статус межбанковского платежа
+type InterbankStatus string
+
+const (
+	InterbankStatusCreated  InterbankStatus = "CREATED"  // Средства удержаны, платеж еще не принят платежной системой
+	InterbankStatusSent     InterbankStatus = "SENT"     // Платеж принят платежной системой, ожидается расчет
+	InterbankStatusSettled  InterbankStatus = "SETTLED"  // Расчет завершен, средства списаны или зачислены
+	InterbankStatusRejected InterbankStatus = "REJECTED" // Платеж отклонен, удержание снято
+	InterbankStatusReturned InterbankStatus = "RETURNED" // Банк получателя вернул средства после расчета
+)
+
+// IsFinal проверяет, что статус больше не изменится без возврата
+func (s InterbankStatus) IsFinal() bool {
+	return s == InterbankStatusRejected || s == InterbankStatusReturned
+}
+
+// InterbankPayment представляет платеж между нашим банком и другим банком
+type InterbankPayment struct {
+	ID                  uint               `gorm:"primaryKey;autoIncrement"`
+	Direction           InterbankDirection `gorm:"type:varchar(10);not null;index"`
+	Rail                string             `gorm:"size:20;not null"` // Платежная система, через которую прошел платеж
+	AccountID           uint               `gorm:"not null;index"`   // Счет клиента нашего банка
+	UserID              *uint              // Инициатор исходящего платежа
+	Amount              float64            `gorm:"type:decimal(20,2);not null"`
+	Status              InterbankStatus    `gorm:"type:varchar(10);not null;default:'CREATED';index"`
+	ExternalID          *string            `gorm:"size:64;uniqueIndex"` // Идентификатор платежа в платежной системе
+	CounterpartyBIK     string             `gorm:"size:9;not null"`
+	CounterpartyAccount string             `gorm:"size:20"`
+	CounterpartyPhone   string             `gorm:"size:16"`
+	CounterpartyName    string             `gorm:"size:100"`
+	Purpose             string             `gorm:"size:210"`
+	RejectReason        string             `gorm:"size:255"`
+	SentAt              *time.Time
+	SettledAt           *time.Time
+	CreatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели InterbankPayment
+func (InterbankPayment) TableName() string {
+	return "interbank_payments"
+}
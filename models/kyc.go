@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+// KYCLevel представляет уровень верификации клиента
+type KYCLevel string
+
+const (
+	KYCLevelNone  KYCLevel = "NONE"  // Клиент не прошел идентификацию
+	KYCLevelBasic KYCLevel = "BASIC" // Подтверждены паспортные данные
+	KYCLevelFull  KYCLevel = "FULL"  // Подтверждены паспортные данные и документы
+)
+
+// kycBalanceLimits предельный суммарный остаток на счетах клиента по уровням.
+// Нулевой лимит означает отсутствие ограничения.
+var kycBalanceLimits = map[KYCLevel]float64{
+	KYCLevelNone:  15000,
+	KYCLevelBasic: 600000,
+	KYCLevelFull:  0,
+}
+
+// BalanceLimit возвращает предельный суммарный остаток для уровня (0 — без ограничения)
+func (l KYCLevel) BalanceLimit() float64 {
+	limit, ok := kycBalanceLimits[l]
+	if !ok {
+		return kycBalanceLimits[KYCLevelNone]
+	}
+	return limit
+}
+
+// CanTakeCredit проверяет, доступны ли кредиты на этом уровне
+func (l KYCLevel) CanTakeCredit() bool {
+	return l == KYCLevelFull
+}
+
+// KYCStatus представляет статус проверки анкеты
+type KYCStatus string
+
+const (
+	KYCStatusPending  KYCStatus = "PENDING"  // Анкета ожидает проверки сотрудником
+	KYCStatusApproved KYCStatus = "APPROVED" // Анкета одобрена
+	KYCStatusRejected KYCStatus = "REJECTED" // Анкета отклонена, клиент может исправить и отправить снова
+)
+
+// KYCProfile представляет анкету клиента для идентификации.
+// Паспортные данные, дата рождения, ИНН и адрес хранятся зашифрованными PGP.
+type KYCProfile struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement"`
+	UserID          uint      `gorm:"not null;uniqueIndex"`
+	DataEncrypted   string    `gorm:"type:text;not null"` // Анкета в JSON, зашифрованная PGP
+	Status          KYCStatus `gorm:"type:varchar(20);not null;index"`
+	RejectionReason string    `gorm:"size:500"`
+	SubmittedAt     time.Time `gorm:"not null"`
+	ReviewedBy      *uint
+	ReviewedAt      *time.Time
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели KYCProfile
+func (KYCProfile) TableName() string {
+	return "kyc_profiles"
+}
+
+// KYCDocumentType представляет тип документа анкеты
+type KYCDocumentType string
+
+const (
+	KYCDocumentPassportMain         KYCDocumentType = "PASSPORT_MAIN"         // Разворот паспорта с фотографией
+	KYCDocumentPassportRegistration KYCDocumentType = "PASSPORT_REGISTRATION" // Страница с регистрацией
+	KYCDocumentSelfie               KYCDocumentType = "SELFIE"                // Фотография с паспортом
+	KYCDocumentOther                KYCDocumentType = "OTHER"
+)
+
+// KYCDocument представляет загруженный документ. Содержимое хранится зашифрованным PGP.
+type KYCDocument struct {
+	ID            uint            `gorm:"primaryKey;autoIncrement"`
+	UserID        uint            `gorm:"not null;index"`
+	Type          KYCDocumentType `gorm:"type:varchar(30);not null"`
+	FileName      string          `gorm:"size:255;not null"`
+	ContentType   string          `gorm:"size:100;not null"`
+	Size          int64           `gorm:"not null"`
+	DataEncrypted string          `gorm:"type:text;not null"`
+	CreatedAt     time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели KYCDocument
+func (KYCDocument) TableName() string {
+	return "kyc_documents"
+}
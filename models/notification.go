@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationStatus представляет результат отправки уведомления
+type NotificationStatus string
+
+const (
+	NotificationStatusSent   NotificationStatus = "SENT"   // Письмо принято SMTP-сервером
+	NotificationStatusFailed NotificationStatus = "FAILED" // Письмо отправить не удалось
+)
+
+// Notification представляет запись истории уведомлений.
+// Текст письма не сохраняется: в нем бывают ссылки с токенами и одноразовые коды.
+type Notification struct {
+	ID        uint               `gorm:"primaryKey;autoIncrement"`
+	UserID    *uint              `gorm:"index"` // Пустой, если адрес не принадлежит пользователю
+	Recipient string             `gorm:"size:100;not null"`
+	Subject   string             `gorm:"size:255;not null"`
+	Status    NotificationStatus `gorm:"type:varchar(10);not null"`
+	CreatedAt time.Time          `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+// TableName возвращает имя таблицы для модели Notification
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,72 @@
+package models
+
+// Role представляет роль пользователя
+type Role string
+
+const (
+	RoleCustomer Role = "CUSTOMER" // Клиент банка
+	RoleSupport  Role = "SUPPORT"  // Сотрудник поддержки
+	RoleAdmin    Role = "ADMIN"    // Администратор
+	RoleAcquirer Role = "ACQUIRER" // Процессинг эквайера: авторизация операций по картам
+)
+
+// Permission представляет право на выполнение административного действия
+type Permission string
+
+const (
+	PermissionUsersRead      Permission = "users:read"      // Просмотр пользователей, счетов и кредитов
+	PermissionUsersUnlock    Permission = "users:unlock"    // Снятие блокировки входа
+	PermissionUsersFreeze    Permission = "users:freeze"    // Заморозка и разморозка учетной записи
+	PermissionCreditsManage  Permission = "credits:manage"  // Отмена и списание кредитов
+	PermissionAccountsAdjust Permission = "accounts:adjust" // Ручные корректировки баланса
+	PermissionRolesManage    Permission = "roles:manage"    // Назначение ролей
+	PermissionKYCReview      Permission = "kyc:review"      // Проверка анкет клиентов
+	PermissionAccountsFreeze Permission = "accounts:freeze" // Заморозка счетов и блокировка списаний
+	PermissionCardsAcquire   Permission = "cards:acquire"   // Авторизация операций по картам от имени эквайера
+)
+
+// rolePermissions права, доступные каждой роли
+var rolePermissions = map[Role][]Permission{
+	RoleCustomer: {},
+	RoleSupport: {
+		PermissionUsersRead,
+		PermissionUsersUnlock,
+		PermissionUsersFreeze,
+		PermissionKYCReview,
+		PermissionAccountsFreeze,
+	},
+	RoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersUnlock,
+		PermissionUsersFreeze,
+		PermissionCreditsManage,
+		PermissionAccountsAdjust,
+		PermissionRolesManage,
+		PermissionKYCReview,
+		PermissionAccountsFreeze,
+	},
+	RoleAcquirer: {
+		PermissionCardsAcquire,
+	},
+}
+
+// Permissions возвращает права роли
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// Has проверяет, есть ли у роли право
+func (r Role) Has(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid проверяет, что роль существует
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// PayeeKind представляет вид реквизитов сохраненного получателя
+type PayeeKind string
+
+const (
+	PayeeKindAccount  PayeeKind = "ACCOUNT"  // Счет в нашем банке
+	PayeeKindCard     PayeeKind = "CARD"     // Карта нашего банка
+	PayeeKindExternal PayeeKind = "EXTERNAL" // Счет или телефон в другом банке, перевод через СБП
+)
+
+// SavedPayee представляет получателя, сохраненного пользователем для повторных переводов
+type SavedPayee struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	UserID         uint      `gorm:"not null;index"`
+	Name           string    `gorm:"size:100;not null"` // Название, которое видит пользователь
+	Kind           PayeeKind `gorm:"type:varchar(10);not null"`
+	AccountNumber  string    `gorm:"size:20"`
+	CardEncrypted  string    `gorm:"type:text"` // Номер карты, зашифрованный ключом карт
+	CardKeyVersion int       // Версия ключа, которой зашифрован номер карты
+	CardMasked     string    `gorm:"size:19"`
+	BIK            string    `gorm:"size:9"`
+	Phone          string    `gorm:"size:16"`
+	RecipientName  string    `gorm:"size:100"`
+	UsageCount     int       `gorm:"not null;default:0"` // Число переводов этому получателю, по шаблонам и без них
+	LastUsedAt     *time.Time
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели SavedPayee
+func (SavedPayee) TableName() string {
+	return "saved_payees"
+}
+
+// TransferTemplate представляет шаблон перевода сохраненному получателю
+// с суммой и назначением по умолчанию
+type TransferTemplate struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement"`
+	UserID          uint       `gorm:"not null;index"`
+	PayeeID         uint       `gorm:"not null;index"`
+	Payee           SavedPayee `gorm:"foreignKey:PayeeID"`
+	Name            string     `gorm:"size:100;not null"`
+	SourceAccountID *uint      // Счет списания для переводов на счет и в другой банк
+	SourceCardID    *uint      // Карта списания для переводов на карту
+	Amount          float64    `gorm:"type:decimal(20,2);not null;default:0"` // 0 — сумма указывается при переводе
+	Description     string     `gorm:"size:140"`
+	UsageCount      int        `gorm:"not null;default:0"`
+	LastUsedAt      *time.Time
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели TransferTemplate
+func (TransferTemplate) TableName() string {
+	return "transfer_templates"
+}
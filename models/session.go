@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Session представляет сеанс пользователя, к которому привязан refresh-токен
+type Session struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement"`
+	UserID           uint       `gorm:"not null;index"`
+	RefreshTokenHash string     `gorm:"size:64;not null;uniqueIndex"` // SHA-256 текущего refresh-токена
+	AccessJTI        string     `gorm:"column:access_jti;size:64"`    // Идентификатор последнего выданного access-токена
+	IP               string     `gorm:"size:45"`
+	UserAgent        string     `gorm:"size:255"`
+	ExpiresAt        time.Time  `gorm:"not null"`
+	LastUsedAt       time.Time  `gorm:"not null"`
+	RevokedAt        *time.Time `gorm:"index"`
+	CreatedAt        time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// RetiredRefreshToken представляет refresh-токен, замененный при обновлении сеанса.
+// Все токены сеанса образуют одно семейство: предъявление замененного токена
+// означает, что он утек, и сеансы пользователя отзываются.
+type RetiredRefreshToken struct {
+	TokenHash string    `gorm:"size:64;primaryKey"` // SHA-256 замененного refresh-токена
+	SessionID uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"` // Срок сеанса, после которого запись можно удалить
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели RetiredRefreshToken
+func (RetiredRefreshToken) TableName() string {
+	return "retired_refresh_tokens"
+}
+
+// RevokedToken представляет отозванный до истечения срока access-токен
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"not null;index"` // После этого момента запись можно удалить
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели RevokedToken
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// SignInOutcome представляет результат попытки входа
+type SignInOutcome string
+
+const (
+	SignInOutcomeSuccess         SignInOutcome = "SUCCESS"           // Вход выполнен, сеанс открыт
+	SignInOutcomeInvalidPassword SignInOutcome = "INVALID_PASSWORD"  // Неверный пароль
+	SignInOutcomeLocked          SignInOutcome = "LOCKED"            // Вход заблокирован после неудачных попыток
+	SignInOutcomeFrozen          SignInOutcome = "FROZEN"            // Учетная запись заморожена
+	SignInOutcomeTwoFactor       SignInOutcome = "TWO_FACTOR"        // Пароль верный, ожидается второй фактор
+	SignInOutcomeTwoFactorFailed SignInOutcome = "TWO_FACTOR_FAILED" // Неверный код второго шага
+)
+
+// SignInEvent представляет запись журнала входов пользователя
+type SignInEvent struct {
+	ID                uint          `gorm:"primaryKey;autoIncrement"`
+	UserID            uint          `gorm:"not null;index:idx_sign_in_events_user_device"`
+	IP                string        `gorm:"size:45"`
+	UserAgent         string        `gorm:"size:255"`
+	DeviceFingerprint string        `gorm:"size:64;not null;index:idx_sign_in_events_user_device"` // SHA-256 признаков устройства
+	Outcome           SignInOutcome `gorm:"type:varchar(20);not null"`
+	CreatedAt         time.Time     `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+// TableName возвращает имя таблицы для модели SignInEvent
+func (SignInEvent) TableName() string {
+	return "sign_in_events"
+}
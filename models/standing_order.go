@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// StandingOrderSchedule представляет периодичность постоянного поручения
+type StandingOrderSchedule string
+
+const (
+	StandingOrderOnce            StandingOrderSchedule = "ONCE"              // Однократный перевод в указанную дату
+	StandingOrderWeekly          StandingOrderSchedule = "WEEKLY"            // Каждую неделю в день недели даты начала
+	StandingOrderMonthly         StandingOrderSchedule = "MONTHLY"           // Каждый месяц в указанное число
+	StandingOrderLastBusinessDay StandingOrderSchedule = "LAST_BUSINESS_DAY" // В последний рабочий день месяца
+)
+
+// IsValid проверяет, что периодичность известна
+func (s StandingOrderSchedule) IsValid() bool {
+	switch s {
+	case StandingOrderOnce, StandingOrderWeekly, StandingOrderMonthly, StandingOrderLastBusinessDay:
+		return true
+	}
+	return false
+}
+
+// StandingOrderStatus представляет статус постоянного поручения
+type StandingOrderStatus string
+
+const (
+	StandingOrderActive    StandingOrderStatus = "ACTIVE"    // Переводы выполняются по расписанию
+	StandingOrderPaused    StandingOrderStatus = "PAUSED"    // Приостановлено клиентом
+	StandingOrderCompleted StandingOrderStatus = "COMPLETED" // Достигнута дата окончания или число переводов
+	StandingOrderCancelled StandingOrderStatus = "CANCELLED" // Отменено клиентом
+	StandingOrderFailed    StandingOrderStatus = "FAILED"    // Остановлено из-за ошибки, которую не исправить повтором
+)
+
+// StandingOrder представляет постоянное поручение на перевод между счетами банка
+type StandingOrder struct {
+	ID            uint                  `gorm:"primaryKey;autoIncrement"`
+	UserID        uint                  `gorm:"not null;index"`
+	SourceID      uint                  `gorm:"not null;index"`
+	DestinationID uint                  `gorm:"not null"`
+	Amount        float64               `gorm:"type:decimal(20,2);not null"`
+	Description   string                `gorm:"size:140"`
+	Schedule      StandingOrderSchedule `gorm:"type:varchar(20);not null"`
+	DayOfMonth    int                   // Число месяца для MONTHLY; в коротких месяцах — последний день
+	StartDate     time.Time             `gorm:"type:date;not null"`
+	EndDate       *time.Time            `gorm:"type:date"` // Последняя дата, в которую возможен перевод
+	MaxRuns       *int                  // Число переводов, после которого поручение завершается
+	RunCount      int                   `gorm:"not null;default:0"` // Выполненные переводы; пропущенные из-за нехватки средств не учитываются
+	NextRunAt     *time.Time            `gorm:"index"`              // Дата очередного перевода
+	RetryAt       *time.Time            // Время повторной попытки, если на счете не хватило средств
+	RetryCount    int                   `gorm:"not null;default:0"`
+	LastRunAt     *time.Time
+	LastError     string              `gorm:"size:255"`
+	Status        StandingOrderStatus `gorm:"type:varchar(10);not null;default:'ACTIVE';index"`
+	CreatedAt     time.Time           `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time           `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели StandingOrder
+func (StandingOrder) TableName() string {
+	return "standing_orders"
+}
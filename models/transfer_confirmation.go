@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// TransferConfirmation представляет подготовленный перевод, ожидающий подтверждения.
+// Пользователь видит маскированные данные получателя и подтверждает перевод токеном.
+type TransferConfirmation struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement"`
+	UserID           uint      `gorm:"not null;index"`
+	SourceID         uint      `gorm:"not null"`
+	DestinationID    uint      `gorm:"not null"`
+	Amount           float64   `gorm:"type:decimal(20,2);not null"`
+	RecipientName    string    `gorm:"size:60;not null"` // Маскированное имя получателя, например «Иван П.»
+	RecipientAccount string    `gorm:"size:30;not null"` // Маскированный номер счета получателя
+	TokenHash        string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	UsedAt           *time.Time
+	CreatedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели TransferConfirmation
+func (TransferConfirmation) TableName() string {
+	return "transfer_confirmations"
+}
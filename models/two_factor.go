@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// UserTwoFactor представляет настройки двухфакторной аутентификации пользователя
+type UserTwoFactor struct {
+	ID              uint   `gorm:"primaryKey;autoIncrement"`
+	UserID          uint   `gorm:"not null;uniqueIndex"`
+	SecretEncrypted string `gorm:"type:text;not null"` // Зашифрованный секрет TOTP
+	KeyVersion      int    `gorm:"not null;default:1"` // Версия ключа шифрования секрета
+	Enabled         bool   `gorm:"not null;default:false"`
+	LastCounter     int64  `gorm:"not null;default:0"` // Шаг последнего принятого кода, защищает от повторного использования
+	FailedAttempts  int    `gorm:"not null;default:0"`
+	LockedUntil     *time.Time
+	EnabledAt       *time.Time
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели UserTwoFactor
+func (UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// RecoveryCode представляет одноразовый код восстановления доступа
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `gorm:"not null;index"`
+	CodeHash  string     `gorm:"size:64;not null"` // SHA-256 кода
+	UsedAt    *time.Time // Код использован и больше недействителен
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// TwoFactorChallenge представляет незавершенный вход, ожидающий второго фактора
+type TwoFactorChallenge struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"` // SHA-256 токена второго шага
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели TwoFactorChallenge
+func (TwoFactorChallenge) TableName() string {
+	return "two_factor_challenges"
+}
@@ -8,13 +8,28 @@ import (
 )
 
 type User struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement"`
-	FirstName string    `gorm:"column:first_name;not null;size:50"`
-	LastName  string    `gorm:"column:last_name;not null;size:50"`
-	Email     string    `gorm:"column:email;unique;not null;size:100;index"`
-	Password  string    `gorm:"column:password;not null;size:100"`
-	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP"`
+	ID                 uint       `gorm:"primaryKey;autoIncrement"`
+	FirstName          string     `gorm:"column:first_name;not null;size:50"`
+	LastName           string     `gorm:"column:last_name;not null;size:50"`
+	Email              string     `gorm:"column:email;unique;not null;size:100;index"`
+	Phone              *string    `gorm:"column:phone;size:16;uniqueIndex"` // Подтвержденный телефон в формате +7XXXXXXXXXX для переводов по номеру
+	PhoneVerifiedAt    *time.Time `gorm:"column:phone_verified_at"`         // Переводы по номеру находят только подтвержденный телефон
+	PendingPhone       string     `gorm:"column:pending_phone;size:16"`     // Новый телефон, ожидающий подтверждения кодом из SMS
+	PhoneCodeHash      string     `gorm:"column:phone_code_hash;size:100"`  // Хэш кода подтверждения телефона
+	PhoneCodeExpiresAt *time.Time `gorm:"column:phone_code_expires_at"`
+	PhoneCodeAttempts  int        `gorm:"column:phone_code_attempts;not null;default:0"` // Неверные коды подряд
+	Password           string     `gorm:"column:password;not null;size:100"`
+	EmailVerifiedAt    *time.Time `gorm:"column:email_verified_at"`                  // До подтверждения email операции с деньгами недоступны
+	FailedSignIns      int        `gorm:"column:failed_sign_ins;not null;default:0"` // Неудачные попытки входа подряд
+	LockoutLevel       int        `gorm:"column:lockout_level;not null;default:0"`   // Количество блокировок подряд, увеличивает срок следующей
+	LockedUntil        *time.Time `gorm:"column:locked_until"`
+	Role               Role       `gorm:"column:role;type:varchar(20);not null;default:'CUSTOMER'"`
+	FrozenAt           *time.Time `gorm:"column:frozen_at"`              // Замороженная учетная запись не может войти в систему
+	PendingEmail       string     `gorm:"column:pending_email;size:100"` // Новый email, ожидающий подтверждения
+	ClosedAt           *time.Time `gorm:"column:closed_at"`              // Профиль закрыт, персональные данные обезличены
+	KYCLevel           KYCLevel   `gorm:"column:kyc_level;type:varchar(10);not null;default:'NONE'"`
+	CreatedAt          time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time  `gorm:"column:updated_at;default:CURRENT_TIMESTAMP"`
 }
 
 func (User) TableName() string {
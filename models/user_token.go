@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// UserTokenPurpose представляет назначение одноразового токена пользователя
+type UserTokenPurpose string
+
+const (
+	UserTokenEmailVerification UserTokenPurpose = "EMAIL_VERIFICATION" // Подтверждение email
+	UserTokenPasswordReset     UserTokenPurpose = "PASSWORD_RESET"     // Сброс пароля
+	UserTokenEmailChange       UserTokenPurpose = "EMAIL_CHANGE"       // Подтверждение нового email
+)
+
+// UserToken представляет одноразовый токен, отправленный пользователю по email
+type UserToken struct {
+	ID        uint             `gorm:"primaryKey;autoIncrement"`
+	UserID    uint             `gorm:"not null;index"`
+	Purpose   UserTokenPurpose `gorm:"type:varchar(20);not null"`
+	TokenHash string           `gorm:"size:64;not null;uniqueIndex"` // SHA-256 токена
+	ExpiresAt time.Time        `gorm:"not null"`
+	UsedAt    *time.Time       // Токен использован или заменен новым
+	CreatedAt time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName возвращает имя таблицы для модели UserToken
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
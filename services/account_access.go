@@ -0,0 +1,169 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"time"
+)
+
+// ErrAccountNotFound возвращается, если счет не существует
+var ErrAccountNotFound = errors.New("банковский счет не найден")
+
+// ErrAccountAccessDenied возвращается, если у пользователя нет прав на действие со счетом
+var ErrAccountAccessDenied = errors.New("нет доступа к данному счету")
+
+// ErrAccountClosed возвращается при операции по закрытому счету
+var ErrAccountClosed = errors.New("счет закрыт")
+
+// ErrAccountDebitBlocked возвращается, если списания со счета запрещены банком
+var ErrAccountDebitBlocked = errors.New("списания со счета запрещены банком")
+
+// ErrAccountCreditBlocked возвращается, если зачисления на счет запрещены банком
+var ErrAccountCreditBlocked = errors.New("счет заморожен, зачисления запрещены")
+
+// ErrSpendLimitExceeded возвращается, если списание превышает дневной лимит участника SPENDER
+var ErrSpendLimitExceeded = errors.New("превышен дневной лимит списаний по этому счету")
+
+// AuthorizeAccount проверяет, что пользователь может выполнить действие со счетом,
+// и возвращает счет. Владелец счета (HolderID) имеет все права, остальные
+// пользователи — права своей роли участника. Для расходных операций amount
+// предварительно сравнивается с дневным лимитом участника с ролью SPENDER
+// (0 означает проверку без суммы); списанное за день учитывает chargeSpendLimit.
+func AuthorizeAccount(db *gorm.DB, userID, accountID uint, action models.AccountAction, amount float64) (*models.BankAccount, error) {
+	var account models.BankAccount
+	if err := db.First(&account, accountID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, errors.New("ошибка при поиске банковского счета")
+	}
+
+	role, spendLimit, err := accountRole(db, &account, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountRole(role, spendLimit, action, amount); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// AccountRoleOf возвращает роль пользователя на счете
+func AccountRoleOf(db *gorm.DB, account *models.BankAccount, userID uint) (models.AccountRole, error) {
+	role, _, err := accountRole(db, account, userID)
+	return role, err
+}
+
+// accessibleAccountIDs возвращает подзапрос с ID счетов, которыми пользователь
+// владеет или в которых участвует
+func accessibleAccountIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.BankAccount{}).
+		Select("id").
+		Where("holder_id = ? OR id IN (?)", userID,
+			db.Model(&models.AccountMember{}).Select("account_id").Where("user_id = ?", userID))
+}
+
+// accountRole определяет роль пользователя на счете и его дневной лимит списаний
+func accountRole(db *gorm.DB, account *models.BankAccount, userID uint) (models.AccountRole, float64, error) {
+	if account.HolderID == userID {
+		return models.AccountRoleOwner, 0, nil
+	}
+
+	var member models.AccountMember
+	if err := db.Where("account_id = ? AND user_id = ?", account.ID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", 0, ErrAccountAccessDenied
+		}
+		return "", 0, errors.New("ошибка при проверке доступа к счету")
+	}
+	return member.Role, member.SpendLimit, nil
+}
+
+// checkAccountRole проверяет, что роли доступно действие на указанную сумму
+func checkAccountRole(role models.AccountRole, spendLimit float64, action models.AccountAction, amount float64) error {
+	if !role.Can(action) {
+		return ErrAccountAccessDenied
+	}
+	if role == models.AccountRoleSpender && action == models.AccountActionSpend {
+		return checkSpendLimit(spendLimit, 0, amount)
+	}
+	return nil
+}
+
+// checkSpendLimit проверяет, что списание amount вместе с уже списанным за день spent
+// не превышает дневной лимит участника
+func checkSpendLimit(limit, spent, amount float64) error {
+	if spent+amount > limit {
+		return fmt.Errorf("%w: лимит %.2f, доступно %.2f", ErrSpendLimitExceeded, limit, math.Max(limit-spent, 0))
+	}
+	return nil
+}
+
+// chargeSpendLimit учитывает списание пользователя userID в дневном лимите,
+// если он участвует в счете с ролью SPENDER. Вызывается в транзакции списания:
+// строка участника блокируется, чтобы параллельные списания не превысили лимит вместе.
+// Для владельца счета и при userID = 0 (списание банком) ничего не делает.
+func chargeSpendLimit(tx *gorm.DB, userID uint, account *models.BankAccount, amount float64) error {
+	if userID == 0 || account.HolderID == userID {
+		return nil
+	}
+
+	var member models.AccountMember
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("account_id = ? AND user_id = ?", account.ID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccountAccessDenied
+		}
+		return errors.New("ошибка при проверке доступа к счету")
+	}
+	if !member.Role.Can(models.AccountActionSpend) {
+		return ErrAccountAccessDenied
+	}
+	if member.Role != models.AccountRoleSpender {
+		return nil
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	spent := member.SpentToday
+	if member.SpentOn == nil || member.SpentOn.Before(today) {
+		spent = 0
+	}
+	if err := checkSpendLimit(member.SpendLimit, spent, amount); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&member).Updates(map[string]interface{}{
+		"spent_today": spent + amount,
+		"spent_on":    today,
+	}).Error; err != nil {
+		return errors.New("не удалось учесть списание в лимите")
+	}
+	return nil
+}
+
+// checkAccountDebit проверяет, что статус счета разрешает списание
+func checkAccountDebit(account *models.BankAccount) error {
+	if account.Status == models.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	if !account.Status.CanDebit() {
+		return ErrAccountDebitBlocked
+	}
+	return nil
+}
+
+// checkAccountCredit проверяет, что статус счета разрешает зачисление
+func checkAccountCredit(account *models.BankAccount) error {
+	if account.Status == models.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	if !account.Status.CanCredit() {
+		return ErrAccountCreditBlocked
+	}
+	return nil
+}
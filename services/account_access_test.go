@@ -0,0 +1,91 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"testing"
+)
+
+func TestCheckAccountRole(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       models.AccountRole
+		spendLimit float64
+		action     models.AccountAction
+		amount     float64
+		wantErr    bool
+	}{
+		{"владелец управляет счетом", models.AccountRoleOwner, 0, models.AccountActionManage, 0, false},
+		{"владелец тратит без лимита", models.AccountRoleOwner, 0, models.AccountActionSpend, 1e9, false},
+		{"совладелец управляет счетом", models.AccountRoleCoOwner, 0, models.AccountActionManage, 0, false},
+		{"совладелец тратит без лимита", models.AccountRoleCoOwner, 0, models.AccountActionSpend, 1e9, false},
+		{"наблюдатель видит счет", models.AccountRoleViewer, 0, models.AccountActionView, 0, false},
+		{"наблюдатель не пополняет", models.AccountRoleViewer, 0, models.AccountActionDeposit, 100, true},
+		{"наблюдатель не тратит", models.AccountRoleViewer, 0, models.AccountActionSpend, 1, true},
+		{"доверенное лицо тратит в пределах лимита", models.AccountRoleSpender, 5000, models.AccountActionSpend, 5000, false},
+		{"доверенное лицо превышает лимит", models.AccountRoleSpender, 5000, models.AccountActionSpend, 5000.01, true},
+		{"пополнение не ограничено лимитом", models.AccountRoleSpender, 5000, models.AccountActionDeposit, 10000, false},
+		{"доверенное лицо не управляет счетом", models.AccountRoleSpender, 5000, models.AccountActionManage, 0, true},
+		{"неизвестная роль", models.AccountRole("UNKNOWN"), 0, models.AccountActionView, 0, true},
+	}
+
+	for _, tc := range cases {
+		err := checkAccountRole(tc.role, tc.spendLimit, tc.action, tc.amount)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkAccountRole() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+
+	// Отсутствие права и превышение лимита различаются
+	if err := checkAccountRole(models.AccountRoleViewer, 0, models.AccountActionSpend, 1); !errors.Is(err, ErrAccountAccessDenied) {
+		t.Errorf("viewer spend: error = %v, want ErrAccountAccessDenied", err)
+	}
+	if err := checkAccountRole(models.AccountRoleSpender, 10, models.AccountActionSpend, 20); errors.Is(err, ErrAccountAccessDenied) {
+		t.Errorf("spender over limit: error = %v, want limit error", err)
+	}
+}
+
+func TestCheckAccountStatus(t *testing.T) {
+	cases := []struct {
+		status    models.AccountStatus
+		debitErr  error
+		creditErr error
+	}{
+		{models.AccountStatusActive, nil, nil},
+		{models.AccountStatusDebitBlocked, ErrAccountDebitBlocked, nil},
+		{models.AccountStatusFrozen, ErrAccountDebitBlocked, ErrAccountCreditBlocked},
+		{models.AccountStatusClosed, ErrAccountClosed, ErrAccountClosed},
+	}
+
+	for _, tc := range cases {
+		account := &models.BankAccount{Status: tc.status}
+		if err := checkAccountDebit(account); !errors.Is(err, tc.debitErr) {
+			t.Errorf("%s: checkAccountDebit() error = %v, want %v", tc.status, err, tc.debitErr)
+		}
+		if err := checkAccountCredit(account); !errors.Is(err, tc.creditErr) {
+			t.Errorf("%s: checkAccountCredit() error = %v, want %v", tc.status, err, tc.creditErr)
+		}
+	}
+}
+
+func TestCheckSpendLimit(t *testing.T) {
+	cases := []struct {
+		name                 string
+		limit, spent, amount float64
+		wantErr              bool
+	}{
+		{"первое списание за день в пределах лимита", 5000, 0, 3000, false},
+		{"списания за день ровно до лимита", 5000, 3000, 2000, false},
+		{"сумма за день превышает лимит", 5000, 3000, 2000.01, true},
+		{"лимит уже исчерпан", 5000, 5000, 0.01, true},
+	}
+	for _, tc := range cases {
+		err := checkSpendLimit(tc.limit, tc.spent, tc.amount)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkSpendLimit() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrSpendLimitExceeded) {
+			t.Errorf("%s: error = %v, want ErrSpendLimitExceeded", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// accountInvitationTTL время жизни приглашения к счету
+const accountInvitationTTL = 7 * 24 * time.Hour
+
+// InviteAccountMemberDTO представляет приглашение пользователя к счету
+type InviteAccountMemberDTO struct {
+	Email      string  `json:"email" validate:"required,email,max=100"`
+	Role       string  `json:"role" validate:"required,oneof=CO_OWNER VIEWER SPENDER"`
+	SpendLimit float64 `json:"spend_limit" validate:"gte=0"` // Обязателен для SPENDER
+}
+
+// AcceptInvitationDTO представляет принятие приглашения
+type AcceptInvitationDTO struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AccountMemberDTO представляет участника счета
+type AccountMemberDTO struct {
+	UserID     uint      `json:"user_id"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	SpendLimit float64   `json:"spend_limit,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AccountInvitationDTO представляет приглашение, ожидающее принятия
+type AccountInvitationDTO struct {
+	ID         uint      `json:"id"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	SpendLimit float64   `json:"spend_limit,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AccountMembersDTO представляет участников счета и приглашения.
+// Приглашения видны только тем, кто может управлять участниками.
+type AccountMembersDTO struct {
+	Members     []AccountMemberDTO     `json:"members"`
+	Invitations []AccountInvitationDTO `json:"invitations,omitempty"`
+}
+
+// AccountMemberService управляет участниками совместных счетов и приглашениями
+type AccountMemberService struct {
+	db     *gorm.DB
+	config *config.Config
+	email  *EmailService
+}
+
+// NewAccountMemberService создает новый экземпляр AccountMemberService
+func NewAccountMemberService(db *gorm.DB, cfg *config.Config, email *EmailService) *AccountMemberService {
+	return &AccountMemberService{
+		db:     db,
+		config: cfg,
+		email:  email,
+	}
+}
+
+// Invite приглашает пользователя к счету по email. Совладельцев может
+// приглашать только владелец, остальные роли — владелец и совладельцы.
+// Предыдущее приглашение на тот же адрес отзывается.
+func (s *AccountMemberService) Invite(inviterID, accountID uint, dto InviteAccountMemberDTO) (*AccountInvitationDTO, error) {
+	role := models.AccountRole(dto.Role)
+	if !role.IsInvitable() {
+		return nil, errors.New("недопустимая роль участника")
+	}
+	if role == models.AccountRoleSpender && dto.SpendLimit <= 0 {
+		return nil, errors.New("для роли SPENDER нужно указать дневной лимит списаний")
+	}
+	if role != models.AccountRoleSpender {
+		dto.SpendLimit = 0
+	}
+	email := strings.ToLower(strings.TrimSpace(dto.Email))
+
+	account, err := AuthorizeAccount(s.db, inviterID, accountID, models.AccountActionManage, 0)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.AccountRoleCoOwner && account.HolderID != inviterID {
+		return nil, errors.New("назначать совладельцев может только владелец счета")
+	}
+
+	var inviter models.User
+	if err := s.db.First(&inviter, inviterID).Error; err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+	if strings.EqualFold(inviter.Email, email) {
+		return nil, errors.New("нельзя пригласить самого себя")
+	}
+
+	// Существующий пользователь не должен уже иметь доступ к счету
+	var invitee models.User
+	if err := s.db.Where("LOWER(email) = ?", email).First(&invitee).Error; err == nil {
+		if _, err := AccountRoleOf(s.db, account, invitee.ID); err == nil {
+			return nil, errors.New("пользователь уже имеет доступ к счету")
+		}
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.AccountInvitation{
+		AccountID:  accountID,
+		Email:      email,
+		Role:       role,
+		SpendLimit: dto.SpendLimit,
+		TokenHash:  utils.HashToken(token),
+		InvitedBy:  inviterID,
+		ExpiresAt:  time.Now().Add(accountInvitationTTL),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AccountInvitation{}).
+			Where("account_id = ? AND email = ? AND accepted_at IS NULL AND revoked_at IS NULL", accountID, email).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, errors.New("не удалось создать приглашение")
+	}
+
+	link := s.config.Server.BaseURL + "/accept-invitation?token=" + url.QueryEscape(token)
+	inviterName := strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+	if err := s.email.SendAccountInvitation(email, inviterName, account.Title, string(role), link); err != nil {
+		log.Printf("Ошибка отправки приглашения к счету %d: %v", accountID, err)
+	}
+
+	return toAccountInvitationDTO(*invitation), nil
+}
+
+// Accept принимает приглашение. Приглашение действует только для пользователя
+// с подтвержденным email, на который оно было отправлено.
+func (s *AccountMemberService) Accept(userID uint, token string) (*AccountMemberDTO, error) {
+	var member models.AccountMember
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.AccountInvitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(token)).
+			First(&invitation).Error; err != nil {
+			return errors.New("приглашение не найдено")
+		}
+		if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || time.Now().After(invitation.ExpiresAt) {
+			return errors.New("приглашение недействительно или срок его действия истек")
+		}
+
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return errors.New("пользователь не найден")
+		}
+		if !strings.EqualFold(user.Email, invitation.Email) {
+			return errors.New("приглашение отправлено на другой email")
+		}
+		if user.EmailVerifiedAt == nil {
+			return errors.New("подтвердите email, чтобы принять приглашение")
+		}
+
+		var account models.BankAccount
+		if err := tx.First(&account, invitation.AccountID).Error; err != nil {
+			return ErrAccountNotFound
+		}
+		if _, err := AccountRoleOf(tx, &account, userID); err == nil {
+			return errors.New("у вас уже есть доступ к этому счету")
+		}
+
+		member = models.AccountMember{
+			AccountID:  invitation.AccountID,
+			UserID:     userID,
+			Role:       invitation.Role,
+			SpendLimit: invitation.SpendLimit,
+			InvitedBy:  invitation.InvitedBy,
+		}
+		if err := tx.Create(&member).Error; err != nil {
+			return errors.New("не удалось принять приглашение")
+		}
+		member.User = user
+
+		return tx.Model(&invitation).Update("accepted_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAccountMemberDTO(member), nil
+}
+
+// List возвращает владельца и участников счета
+func (s *AccountMemberService) List(userID, accountID uint) (*AccountMembersDTO, error) {
+	account, err := AuthorizeAccount(s.db, userID, accountID, models.AccountActionView, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var holder models.User
+	if err := s.db.First(&holder, account.HolderID).Error; err != nil {
+		return nil, errors.New("владелец счета не найден")
+	}
+	result := &AccountMembersDTO{
+		Members: []AccountMemberDTO{{
+			UserID:    holder.ID,
+			FirstName: holder.FirstName,
+			LastName:  holder.LastName,
+			Email:     holder.Email,
+			Role:      string(models.AccountRoleOwner),
+			CreatedAt: account.CreatedAt,
+		}},
+	}
+
+	var members []models.AccountMember
+	if err := s.db.Preload("User").Where("account_id = ?", accountID).Order("id").Find(&members).Error; err != nil {
+		return nil, errors.New("ошибка при получении участников счета")
+	}
+	for _, member := range members {
+		result.Members = append(result.Members, *toAccountMemberDTO(member))
+	}
+
+	role, err := AccountRoleOf(s.db, account, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role.Can(models.AccountActionManage) {
+		var invitations []models.AccountInvitation
+		if err := s.db.Where("account_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", accountID, time.Now()).
+			Order("id").
+			Find(&invitations).Error; err != nil {
+			return nil, errors.New("ошибка при получении приглашений")
+		}
+		for _, invitation := range invitations {
+			result.Invitations = append(result.Invitations, *toAccountInvitationDTO(invitation))
+		}
+	}
+
+	return result, nil
+}
+
+// RemoveMember отключает участника от счета. Участник может выйти сам,
+// совладельцев отключает только владелец, остальных — владелец и совладельцы.
+func (s *AccountMemberService) RemoveMember(userID, accountID, memberUserID uint) error {
+	var account models.BankAccount
+	if err := s.db.First(&account, accountID).Error; err != nil {
+		return ErrAccountNotFound
+	}
+
+	var member models.AccountMember
+	if err := s.db.Where("account_id = ? AND user_id = ?", accountID, memberUserID).First(&member).Error; err != nil {
+		if account.HolderID == memberUserID {
+			return errors.New("владельца счета отключить нельзя")
+		}
+		return errors.New("участник не найден")
+	}
+
+	if userID != memberUserID {
+		if _, err := AuthorizeAccount(s.db, userID, accountID, models.AccountActionManage, 0); err != nil {
+			return err
+		}
+		if member.Role == models.AccountRoleCoOwner && account.HolderID != userID {
+			return errors.New("отключать совладельцев может только владелец счета")
+		}
+	}
+
+	if err := s.db.Delete(&member).Error; err != nil {
+		return errors.New("не удалось отключить участника")
+	}
+	return nil
+}
+
+// RevokeInvitation отзывает приглашение, которое еще не принято
+func (s *AccountMemberService) RevokeInvitation(userID, accountID, invitationID uint) error {
+	if _, err := AuthorizeAccount(s.db, userID, accountID, models.AccountActionManage, 0); err != nil {
+		return err
+	}
+
+	result := s.db.Model(&models.AccountInvitation{}).
+		Where("id = ? AND account_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationID, accountID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.New("не удалось отозвать приглашение")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("приглашение не найдено")
+	}
+	return nil
+}
+
+func toAccountMemberDTO(member models.AccountMember) *AccountMemberDTO {
+	return &AccountMemberDTO{
+		UserID:     member.UserID,
+		FirstName:  member.User.FirstName,
+		LastName:   member.User.LastName,
+		Email:      member.User.Email,
+		Role:       string(member.Role),
+		SpendLimit: member.SpendLimit,
+		CreatedAt:  member.CreatedAt,
+	}
+}
+
+func toAccountInvitationDTO(invitation models.AccountInvitation) *AccountInvitationDTO {
+	return &AccountInvitationDTO{
+		ID:         invitation.ID,
+		Email:      invitation.Email,
+		Role:       string(invitation.Role),
+		SpendLimit: invitation.SpendLimit,
+		ExpiresAt:  invitation.ExpiresAt,
+		CreatedAt:  invitation.CreatedAt,
+	}
+}
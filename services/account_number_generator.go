@@ -0,0 +1,69 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/utils"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+const (
+	// accountBalancePrefix балансовый счет второго порядка для счетов физических лиц
+	accountBalancePrefix = "40817"
+	// accountBranchCode код подразделения банка в номере счета
+	accountBranchCode = "0000"
+	// accountSerialLength длина порядкового номера лицевого счета
+	accountSerialLength = 7
+)
+
+// AccountNumberGenerator генерирует номера счетов по структуре Банка России:
+// балансовый счет, код валюты, контрольный ключ, подразделение и лицевой счет
+type AccountNumberGenerator struct {
+	bik      string
+	currency string
+	random   io.Reader
+}
+
+// NewAccountNumberGenerator создает генератор с БИК и валютой из конфигурации
+func NewAccountNumberGenerator(cfg *config.Config) (*AccountNumberGenerator, error) {
+	if len(cfg.AccountCurrencyCode) != 3 || !isDigits(cfg.AccountCurrencyCode) {
+		return nil, fmt.Errorf("код валюты счетов должен состоять из 3 цифр: %q", cfg.AccountCurrencyCode)
+	}
+	generator := newAccountNumberGenerator(cfg.BankBIK, cfg.AccountCurrencyCode, rand.Reader)
+	// Проверяем БИК расчетом ключа для пробного номера
+	if _, err := utils.AccountControlKey(generator.bik, accountBalancePrefix+generator.currency+"0"+accountBranchCode+"0000000"); err != nil {
+		return nil, err
+	}
+	return generator, nil
+}
+
+// newAccountNumberGenerator создает генератор с заданным источником случайности
+func newAccountNumberGenerator(bik, currency string, random io.Reader) *AccountNumberGenerator {
+	return &AccountNumberGenerator{
+		bik:      bik,
+		currency: currency,
+		random:   random,
+	}
+}
+
+// BIK возвращает БИК банка, по которому рассчитываются ключи
+func (g *AccountNumberGenerator) BIK() string {
+	return g.bik
+}
+
+// Generate генерирует номер счета с корректным контрольным ключом
+func (g *AccountNumberGenerator) Generate() (string, error) {
+	serial, err := randomDigits(g.random, accountSerialLength)
+	if err != nil {
+		return "", err
+	}
+
+	number := []byte(accountBalancePrefix + g.currency + "0" + accountBranchCode + serial)
+	key, err := utils.AccountControlKey(g.bik, string(number))
+	if err != nil {
+		return "", err
+	}
+	number[len(accountBalancePrefix)+len(g.currency)] = key
+	return string(number), nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"awesomeProject/utils"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestAccountNumberGeneratorGenerate(t *testing.T) {
+	generator := newAccountNumberGenerator("044525999", "810", rand.Reader)
+
+	for i := 0; i < 100; i++ {
+		number, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(number) != 20 || !strings.HasPrefix(number, "40817810") || number[9:13] != "0000" {
+			t.Fatalf("Generate() = %s, unexpected structure", number)
+		}
+		if err := utils.ValidateAccountNumber("044525999", number); err != nil {
+			t.Fatalf("Generate() = %s, ValidateAccountNumber() error = %v", number, err)
+		}
+	}
+}
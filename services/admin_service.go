@@ -0,0 +1,552 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"strings"
+	"time"
+)
+
+// adminReasonMinLength минимальная длина обоснования действия сотрудника
+const adminReasonMinLength = 10
+
+// Действия сотрудников, записываемые в журнал admin_actions
+const (
+	adminActionFreeze          = "user.freeze"
+	adminActionUnfreeze        = "user.unfreeze"
+	adminActionUnlock          = "user.unlock"
+	adminActionSetRole         = "user.set_role"
+	adminActionCancelCredit    = "credit.cancel"
+	adminActionWriteOff        = "credit.write_off"
+	adminActionAdjust          = "account.adjust"
+	adminActionKYCApprove      = "kyc.approve"
+	adminActionKYCReject       = "kyc.reject"
+	adminActionAccountFreeze   = "account.freeze"
+	adminActionAccountUnfreeze = "account.unfreeze"
+)
+
+// ErrAdminTargetNotFound возвращается, когда объект действия сотрудника не найден
+var ErrAdminTargetNotFound = errors.New("объект не найден")
+
+// ErrAdminReasonRequired возвращается, если действие выполняется без обоснования
+var ErrAdminReasonRequired = fmt.Errorf("укажите причину действия (не короче %d символов)", adminReasonMinLength)
+
+// AdminAccountDTO представляет счет пользователя в административном API
+type AdminAccountDTO struct {
+	ID          uint    `json:"id"`
+	Number      string  `json:"number"`
+	Title       string  `json:"title"`
+	Balance     float64 `json:"balance"`
+	Status      string  `json:"status"`
+	BlockReason string  `json:"block_reason,omitempty"`
+}
+
+// AdminCreditDTO представляет кредит пользователя в административном API
+type AdminCreditDTO struct {
+	ID        uint      `json:"id"`
+	AccountID uint      `json:"account_id"`
+	Amount    float64   `json:"amount"`
+	Rate      float64   `json:"rate"`
+	Status    string    `json:"status"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+// AdminUserDTO представляет карточку пользователя для сотрудника банка
+type AdminUserDTO struct {
+	ID              uint              `json:"id"`
+	FirstName       string            `json:"first_name"`
+	LastName        string            `json:"last_name"`
+	Email           string            `json:"email"`
+	Role            string            `json:"role"`
+	EmailVerifiedAt *time.Time        `json:"email_verified_at,omitempty"`
+	LockedUntil     *time.Time        `json:"locked_until,omitempty"`
+	FrozenAt        *time.Time        `json:"frozen_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	Accounts        []AdminAccountDTO `json:"accounts,omitempty"`
+	Credits         []AdminCreditDTO  `json:"credits,omitempty"`
+}
+
+// AdminService выполняет действия сотрудников банка. Каждое изменяющее действие
+// требует обоснования и записывается в журнал в той же транзакции.
+type AdminService struct {
+	db          *gorm.DB
+	sessions    *SessionService
+	apiKeys     *APIKeyService
+	signInGuard *SignInGuard
+}
+
+// NewAdminService создает новый экземпляр AdminService
+func NewAdminService(db *gorm.DB, sessions *SessionService, apiKeys *APIKeyService, signInGuard *SignInGuard) *AdminService {
+	return &AdminService{
+		db:          db,
+		sessions:    sessions,
+		apiKeys:     apiKeys,
+		signInGuard: signInGuard,
+	}
+}
+
+// SearchUsers ищет пользователей по части email или точному номеру счета
+func (s *AdminService) SearchUsers(query string) ([]AdminUserDTO, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("укажите email или номер счета")
+	}
+
+	var users []models.User
+	if err := s.db.
+		Where(`LOWER(email) LIKE LOWER(?) ESCAPE '\'`, "%"+escapeLike(query)+"%").
+		Or("id IN (?)", s.db.Model(&models.BankAccount{}).Select("holder_id").Where("number = ?", query)).
+		Order("id").
+		Limit(50).
+		Find(&users).Error; err != nil {
+		return nil, errors.New("ошибка при поиске пользователей")
+	}
+
+	result := make([]AdminUserDTO, len(users))
+	for i, user := range users {
+		result[i] = toAdminUserDTO(user)
+	}
+	return result, nil
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike экранирует спецсимволы LIKE, чтобы запрос искался как обычный текст
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// GetUser возвращает карточку пользователя со счетами и кредитами
+func (s *AdminService) GetUser(userID uint) (*AdminUserDTO, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminTargetNotFound
+		}
+		return nil, errors.New("ошибка при поиске пользователя")
+	}
+
+	var accounts []models.BankAccount
+	if err := s.db.Where("holder_id = ?", userID).Order("id").Find(&accounts).Error; err != nil {
+		return nil, errors.New("ошибка при получении списка счетов")
+	}
+
+	var credits []models.Credit
+	if err := s.db.Joins("JOIN bank_accounts ON bank_accounts.id = credits.account_id").
+		Where("bank_accounts.holder_id = ?", userID).
+		Order("credits.id").
+		Find(&credits).Error; err != nil {
+		return nil, errors.New("ошибка при получении списка кредитов")
+	}
+
+	dto := toAdminUserDTO(user)
+	for _, account := range accounts {
+		dto.Accounts = append(dto.Accounts, toAdminAccountDTO(account))
+	}
+	for _, credit := range credits {
+		dto.Credits = append(dto.Credits, AdminCreditDTO{
+			ID:        credit.ID,
+			AccountID: credit.AccountID,
+			Amount:    credit.Amount,
+			Rate:      credit.Rate,
+			Status:    string(credit.Status),
+			StartDate: credit.StartDate,
+			EndDate:   credit.EndDate,
+		})
+	}
+	return &dto, nil
+}
+
+// FreezeUser замораживает учетную запись: вход запрещается, все сеансы и API-ключи отзываются
+func (s *AdminService) FreezeUser(adminID, userID uint, reason string) error {
+	if adminID == userID {
+		return errors.New("нельзя заморозить собственную учетную запись")
+	}
+
+	err := s.withAction(adminID, adminActionFreeze, "user", userID, reason, func(tx *gorm.DB) (string, error) {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return "", err
+		}
+		if user.FrozenAt != nil {
+			return "", errors.New("учетная запись уже заморожена")
+		}
+		return "", tx.Model(user).Update("frozen_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка отзыва сеансов замороженного пользователя %d: %v", userID, err)
+	}
+	if err := s.apiKeys.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка отзыва API-ключей замороженного пользователя %d: %v", userID, err)
+	}
+	return nil
+}
+
+// UnfreezeUser снимает заморозку учетной записи
+func (s *AdminService) UnfreezeUser(adminID, userID uint, reason string) error {
+	return s.withAction(adminID, adminActionUnfreeze, "user", userID, reason, func(tx *gorm.DB) (string, error) {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return "", err
+		}
+		if user.FrozenAt == nil {
+			return "", errors.New("учетная запись не заморожена")
+		}
+		return "", tx.Model(user).Update("frozen_at", nil).Error
+	})
+}
+
+// UnlockUser снимает блокировку входа после неудачных попыток
+func (s *AdminService) UnlockUser(adminID, userID uint, reason string) error {
+	return s.withAction(adminID, adminActionUnlock, "user", userID, reason, func(tx *gorm.DB) (string, error) {
+		return "", s.signInGuard.Unlock(tx, userID)
+	})
+}
+
+// SetRole назначает пользователю роль. Сеансы пользователя отзываются,
+// чтобы новые права применялись сразу, а не после истечения access-токена.
+func (s *AdminService) SetRole(adminID, userID uint, role models.Role, reason string) error {
+	if !role.IsValid() {
+		return errors.New("неизвестная роль")
+	}
+	if adminID == userID {
+		return errors.New("нельзя изменить собственную роль")
+	}
+
+	err := s.withAction(adminID, adminActionSetRole, "user", userID, reason, func(tx *gorm.DB) (string, error) {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return "", err
+		}
+		if user.Role == role {
+			return "", errors.New("у пользователя уже есть эта роль")
+		}
+		details := fmt.Sprintf("%s -> %s", user.Role, role)
+		return details, tx.Model(user).Update("role", role).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка отзыва сеансов пользователя %d после смены роли: %v", userID, err)
+	}
+	return nil
+}
+
+// CancelCredit отменяет кредит, по которому еще не было платежей:
+// выданная сумма списывается со счета, график платежей отменяется
+func (s *AdminService) CancelCredit(adminID, creditID uint, reason string) error {
+	return s.withAction(adminID, adminActionCancelCredit, "credit", creditID, reason, func(tx *gorm.DB) (string, error) {
+		credit, err := lockCredit(tx, creditID)
+		if err != nil {
+			return "", err
+		}
+		if credit.Status != models.CreditStatusActive {
+			return "", errors.New("отменить можно только активный кредит")
+		}
+
+		var paid int64
+		if err := tx.Model(&models.Payment{}).
+			Where("credit_id = ? AND status = ?", creditID, models.PaymentStatusPaid).
+			Count(&paid).Error; err != nil {
+			return "", err
+		}
+		if paid > 0 {
+			return "", errors.New("по кредиту уже были платежи, используйте списание")
+		}
+
+		var account models.BankAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, credit.AccountID).Error; err != nil {
+			return "", err
+		}
+		if account.Available() < credit.Amount {
+			return "", errors.New("на счете недостаточно средств для возврата суммы кредита")
+		}
+
+		balanceBefore := account.Balance
+		account.Balance -= credit.Amount
+		if err := tx.Model(&account).Updates(map[string]interface{}{
+			"balance":    account.Balance,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return "", err
+		}
+
+		if err := tx.Create(&models.Transaction{
+			AccountID:     account.ID,
+			Amount:        credit.Amount,
+			Type:          string(TransactionTypeWithdraw),
+			BalanceBefore: balanceBefore,
+			BalanceAfter:  account.Balance,
+			Description:   "Credit cancellation",
+		}).Error; err != nil {
+			return "", err
+		}
+
+		if err := cancelOpenPayments(tx, creditID); err != nil {
+			return "", err
+		}
+
+		details := fmt.Sprintf("списано со счета %s: %.2f", account.Number, credit.Amount)
+		return details, tx.Model(credit).Update("status", models.CreditStatusCanceled).Error
+	})
+}
+
+// WriteOffCredit списывает оставшуюся задолженность по кредиту
+func (s *AdminService) WriteOffCredit(adminID, creditID uint, reason string) error {
+	return s.withAction(adminID, adminActionWriteOff, "credit", creditID, reason, func(tx *gorm.DB) (string, error) {
+		credit, err := lockCredit(tx, creditID)
+		if err != nil {
+			return "", err
+		}
+		if credit.Status != models.CreditStatusActive && credit.Status != models.CreditStatusOverdue {
+			return "", errors.New("списать можно только активный или просроченный кредит")
+		}
+
+		var remaining float64
+		if err := tx.Model(&models.Payment{}).
+			Where("credit_id = ? AND status IN ?", creditID, []models.PaymentStatus{models.PaymentStatusPlanned, models.PaymentStatusOverdue}).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&remaining).Error; err != nil {
+			return "", err
+		}
+
+		if err := cancelOpenPayments(tx, creditID); err != nil {
+			return "", err
+		}
+
+		details := fmt.Sprintf("списанная задолженность: %.2f", remaining)
+		return details, tx.Model(credit).Update("status", models.CreditStatusWrittenOff).Error
+	})
+}
+
+// AdjustBalance вручную изменяет баланс счета на amount (положительная сумма
+// зачисляется, отрицательная списывается). Баланс не может стать отрицательным.
+func (s *AdminService) AdjustBalance(adminID, accountID uint, amount float64, reason string) (*AdminAccountDTO, error) {
+	if amount == 0 {
+		return nil, errors.New("сумма корректировки не может быть нулевой")
+	}
+
+	var account models.BankAccount
+	err := s.withAction(adminID, adminActionAdjust, "account", accountID, reason, func(tx *gorm.DB) (string, error) {
+		locked, err := lockAccount(tx, accountID)
+		if err != nil {
+			return "", err
+		}
+		account = *locked
+		if account.Status == models.AccountStatusClosed {
+			return "", ErrAccountClosed
+		}
+
+		balanceBefore := account.Balance
+		if account.Available()+amount < 0 {
+			return "", errors.New("недостаточно средств на счете")
+		}
+		account.Balance = balanceBefore + amount
+
+		if err := tx.Model(&account).Updates(map[string]interface{}{
+			"balance":    account.Balance,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return "", err
+		}
+
+		if err := tx.Create(&models.Transaction{
+			AccountID:     account.ID,
+			Amount:        amount,
+			Type:          string(TransactionTypeAdjustment),
+			BalanceBefore: balanceBefore,
+			BalanceAfter:  account.Balance,
+			Description:   "Manual adjustment",
+		}).Error; err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%+.2f: %.2f -> %.2f", amount, balanceBefore, account.Balance), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toAdminAccountDTO(account)
+	return &dto, nil
+}
+
+// FreezeAccount ограничивает операции по счету: FROZEN запрещает все операции,
+// DEBIT_BLOCKED — только списания. Ограничение можно усилить или сменить код причины.
+func (s *AdminService) FreezeAccount(adminID, accountID uint, status models.AccountStatus, reasonCode models.AccountBlockReason, reason string) (*AdminAccountDTO, error) {
+	if status != models.AccountStatusFrozen && status != models.AccountStatusDebitBlocked {
+		return nil, errors.New("недопустимый статус ограничения счета")
+	}
+	if !reasonCode.IsValid() {
+		return nil, errors.New("неизвестный код причины ограничения")
+	}
+
+	var account models.BankAccount
+	err := s.withAction(adminID, adminActionAccountFreeze, "account", accountID, reason, func(tx *gorm.DB) (string, error) {
+		locked, err := lockAccount(tx, accountID)
+		if err != nil {
+			return "", err
+		}
+		account = *locked
+		if account.Status == models.AccountStatusClosed {
+			return "", ErrAccountClosed
+		}
+		if account.Status == status && account.BlockReason == reasonCode {
+			return "", errors.New("ограничение уже установлено")
+		}
+
+		previous := account.Status
+		account.Status = status
+		account.BlockReason = reasonCode
+		if err := tx.Model(&account).Updates(map[string]interface{}{
+			"status":       account.Status,
+			"block_reason": account.BlockReason,
+			"updated_at":   time.Now(),
+		}).Error; err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s -> %s (%s)", previous, status, reasonCode), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toAdminAccountDTO(account)
+	return &dto, nil
+}
+
+// UnfreezeAccount снимает ограничение со счета
+func (s *AdminService) UnfreezeAccount(adminID, accountID uint, reason string) error {
+	return s.withAction(adminID, adminActionAccountUnfreeze, "account", accountID, reason, func(tx *gorm.DB) (string, error) {
+		account, err := lockAccount(tx, accountID)
+		if err != nil {
+			return "", err
+		}
+		if account.Status != models.AccountStatusFrozen && account.Status != models.AccountStatusDebitBlocked {
+			return "", errors.New("счет не ограничен")
+		}
+
+		details := fmt.Sprintf("%s (%s) -> %s", account.Status, account.BlockReason, models.AccountStatusActive)
+		return details, tx.Model(account).Updates(map[string]interface{}{
+			"status":       models.AccountStatusActive,
+			"block_reason": "",
+			"updated_at":   time.Now(),
+		}).Error
+	})
+}
+
+// withAction выполняет действие сотрудника в транзакции и записывает его в журнал.
+// Функция fn возвращает подробности для журнала.
+func (s *AdminService) withAction(adminID uint, action, targetType string, targetID uint, reason string, fn func(tx *gorm.DB) (string, error)) error {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) < adminReasonMinLength {
+		return ErrAdminReasonRequired
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		details, err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		return recordAdminAction(tx, adminID, action, targetType, targetID, reason, details)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Сотрудник %d выполнил %s для %s %d", adminID, action, targetType, targetID)
+	return nil
+}
+
+// recordAdminAction записывает действие сотрудника в журнал в рамках транзакции tx
+func recordAdminAction(tx *gorm.DB, adminID uint, action, targetType string, targetID uint, reason, details string) error {
+	return tx.Create(&models.AdminAction{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Details:    details,
+	}).Error
+}
+
+// lockUser загружает пользователя с блокировкой строки
+func lockUser(tx *gorm.DB, userID uint) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminTargetNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// lockAccount загружает счет с блокировкой строки
+func lockAccount(tx *gorm.DB, accountID uint) (*models.BankAccount, error) {
+	var account models.BankAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, accountID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminTargetNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// lockCredit загружает кредит с блокировкой строки
+func lockCredit(tx *gorm.DB, creditID uint) (*models.Credit, error) {
+	var credit models.Credit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&credit, creditID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminTargetNotFound
+		}
+		return nil, err
+	}
+	return &credit, nil
+}
+
+// cancelOpenPayments отменяет неоплаченные платежи по кредиту
+func cancelOpenPayments(tx *gorm.DB, creditID uint) error {
+	return tx.Model(&models.Payment{}).
+		Where("credit_id = ? AND status IN ?", creditID, []models.PaymentStatus{models.PaymentStatusPlanned, models.PaymentStatusOverdue}).
+		Update("status", models.PaymentStatusCanceled).Error
+}
+
+func toAdminAccountDTO(account models.BankAccount) AdminAccountDTO {
+	return AdminAccountDTO{
+		ID:          account.ID,
+		Number:      account.Number,
+		Title:       account.Title,
+		Balance:     account.Balance,
+		Status:      string(account.Status),
+		BlockReason: string(account.BlockReason),
+	}
+}
+
+func toAdminUserDTO(user models.User) AdminUserDTO {
+	return AdminUserDTO{
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		LockedUntil:     user.LockedUntil,
+		FrozenAt:        user.FrozenAt,
+		CreatedAt:       user.CreatedAt,
+	}
+}
@@ -0,0 +1,18 @@
+package services
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"user@example.com": "user@example.com",
+		"%":                `\%`,
+		"a_b":              `a\_b`,
+		`back\slash`:       `back\\slash`,
+		`50%_\`:            `50\%\_\\`,
+	}
+	for input, want := range cases {
+		if got := escapeLike(input); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
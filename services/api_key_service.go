@@ -0,0 +1,253 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyPrefix отличает API-ключи от других токенов, например при поиске утечек в логах
+	apiKeyPrefix = "ak_"
+	// apiKeyLength длина случайной части ключа в байтах
+	apiKeyLength = 32
+	// maxAPIKeysPerUser ограничивает число действующих ключей пользователя
+	maxAPIKeysPerUser = 10
+	// apiKeyLastUsedInterval как часто обновлять время последнего использования ключа
+	apiKeyLastUsedInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey возвращается при неизвестном, отозванном или просроченном ключе
+	ErrInvalidAPIKey = errors.New("недействительный API-ключ")
+	// ErrIdentityNotConfirmed возвращается, если выпуск ключа не подтвержден паролем или кодом 2FA
+	ErrIdentityNotConfirmed = errors.New("не удалось подтвердить личность")
+)
+
+// CreateAPIKeyDTO представляет данные для выпуска API-ключа
+type CreateAPIKeyDTO struct {
+	Name          string   `json:"name" validate:"required,min=2,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,gt=0,lte=3650"`
+
+	// Подтверждение личности: текущий пароль или код 2FA
+	Password string `json:"password"`
+	Code     string `json:"code" validate:"omitempty,min=6,max=11"`
+}
+
+// APIKeyDTO представляет API-ключ в списке ключей пользователя
+type APIKeyDTO struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyDTO содержит выпущенный ключ. Ключ показывается только один раз.
+type CreatedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
+
+// APIKeyService выпускает, проверяет и отзывает API-ключи
+type APIKeyService struct {
+	db          *gorm.DB
+	twoFactor   *TwoFactorService
+	signInGuard *SignInGuard
+}
+
+// NewAPIKeyService создает новый экземпляр APIKeyService
+func NewAPIKeyService(db *gorm.DB, twoFactor *TwoFactorService, signInGuard *SignInGuard) *APIKeyService {
+	return &APIKeyService{
+		db:          db,
+		twoFactor:   twoFactor,
+		signInGuard: signInGuard,
+	}
+}
+
+// Create выпускает новый API-ключ пользователя. Ключ действует без сеанса,
+// поэтому выпуск подтверждается текущим паролем или кодом 2FA.
+func (s *APIKeyService) Create(userID uint, dto CreateAPIKeyDTO, ip string) (*CreatedAPIKeyDTO, error) {
+	if err := s.confirmIdentity(userID, dto.Password, dto.Code, ip); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIdentityNotConfirmed, err)
+	}
+
+	scopes := make([]string, 0, len(dto.Scopes))
+	for _, scope := range dto.Scopes {
+		if !models.APIKeyScope(scope).IsValid() {
+			return nil, errors.New("неизвестная область действия: " + scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	var active int64
+	if err := s.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&active).Error; err != nil {
+		return nil, errors.New("ошибка при проверке количества ключей")
+	}
+	if active >= maxAPIKeysPerUser {
+		return nil, errors.New("достигнуто максимальное количество действующих API-ключей")
+	}
+
+	secret, err := utils.GenerateSecureToken(apiKeyLength)
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + strings.TrimRight(secret, "=")
+
+	apiKey := &models.APIKey{
+		UserID:  userID,
+		Name:    dto.Name,
+		Prefix:  key[:len(apiKeyPrefix)+8],
+		KeyHash: utils.HashToken(key),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if dto.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, dto.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(apiKey).Error; err != nil {
+		return nil, errors.New("не удалось создать API-ключ")
+	}
+
+	return &CreatedAPIKeyDTO{
+		APIKeyDTO: toAPIKeyDTO(*apiKey),
+		Key:       key,
+	}, nil
+}
+
+// List возвращает ключи пользователя, включая отозванные
+func (s *APIKeyService) List(userID uint) ([]APIKeyDTO, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, errors.New("ошибка при получении списка API-ключей")
+	}
+
+	result := make([]APIKeyDTO, len(keys))
+	for i, key := range keys {
+		result[i] = toAPIKeyDTO(key)
+	}
+	return result, nil
+}
+
+// Revoke отзывает ключ пользователя
+func (s *APIKeyService) Revoke(userID, keyID uint) error {
+	result := s.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.New("не удалось отозвать API-ключ")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("API-ключ не найден")
+	}
+	return nil
+}
+
+// RevokeAll отзывает все действующие ключи пользователя, например после смены
+// пароля или заморозки учетной записи
+func (s *APIKeyService) RevokeAll(userID uint) error {
+	if err := s.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("не удалось отозвать API-ключи")
+	}
+	return nil
+}
+
+// confirmIdentity проверяет код 2FA или текущий пароль. Неверный пароль
+// учитывается защитой входа так же, как неудачная попытка входа.
+func (s *APIKeyService) confirmIdentity(userID uint, password, code, ip string) error {
+	if code != "" {
+		if !s.twoFactor.IsEnabled(userID) {
+			return errors.New("двухфакторная аутентификация не включена")
+		}
+		return s.twoFactor.Verify(userID, code)
+	}
+	if password == "" {
+		return errors.New("укажите текущий пароль или код 2FA")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("пользователь не найден")
+	}
+	if err := s.signInGuard.CheckLocked(&user); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.signInGuard.RecordFailure(userID, ip)
+		return errors.New("неверный пароль")
+	}
+	return nil
+}
+
+// Authenticate проверяет ключ и возвращает его вместе с владельцем.
+// Ключи замороженных пользователей не принимаются.
+func (s *APIKeyService) Authenticate(key, ip string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var apiKey models.APIKey
+	if err := s.db.Where("key_hash = ?", utils.HashToken(key)).First(&apiKey).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var user models.User
+	if err := s.db.First(&user, apiKey.UserID).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if user.FrozenAt != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	// Время использования обновляем не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedInterval || apiKey.LastUsedIP != ip {
+		if err := s.db.Model(&apiKey).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			log.Printf("Ошибка обновления времени использования API-ключа %d: %v", apiKey.ID, err)
+		}
+	}
+
+	return &apiKey, &user, nil
+}
+
+func toAPIKeyDTO(key models.APIKey) APIKeyDTO {
+	scopes := make([]string, 0)
+	for _, scope := range key.ScopeList() {
+		scopes = append(scopes, string(scope))
+	}
+
+	return APIKeyDTO{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -3,11 +3,11 @@ package services
 import (
 	"awesomeProject/models"
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
-	"math/rand"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -19,11 +19,16 @@ const (
 	TransactionTypeDeposit  TransactionType = "DEPOSIT"
 	TransactionTypeWithdraw TransactionType = "WITHDRAW"
 	TransactionTypeTransfer TransactionType = "TRANSFER"
+	TransactionTypeFee      TransactionType = "FEE"
+	// TransactionTypeAdjustment ручная корректировка баланса сотрудником банка
+	TransactionTypeAdjustment TransactionType = "ADJUSTMENT"
 )
 
 type BankAccountDTO struct {
 	ID        uint    `json:"id"`
 	Holder    UserDTO `json:"holder"`
+	Role      string  `json:"role,omitempty"` // Роль текущего пользователя на счете
+	Status    string  `json:"status,omitempty"`
 	Balance   float64 `json:"balance"`
 	Title     string  `json:"title"`
 	Number    string  `json:"number"`
@@ -36,6 +41,12 @@ type TransferRequest struct {
 	SourceID      uint    `json:"source_id" validate:"required"`
 	DestinationID uint    `json:"destination_id" validate:"required"`
 	Amount        float64 `json:"amount" validate:"required,gt=0"`
+	TOTPCode      string  `json:"totp_code" validate:"omitempty,min=6,max=11"` // Код 2FA для переводов выше порога
+	// Description назначение перевода для выписки
+	Description string `json:"-"`
+	// SpenderID пользователь, выполняющий перевод, для учета дневного лимита участника SPENDER
+	// и частоты использования его сохраненных получателей
+	SpenderID uint `json:"-"`
 }
 
 // TransactionRequest представляет данные для транзакции
@@ -43,6 +54,16 @@ type TransactionRequest struct {
 	AccountID uint            `json:"account_id" validate:"required"`
 	Amount    float64         `json:"amount" validate:"required,gt=0"`
 	Type      TransactionType `json:"type" validate:"required,oneof=DEPOSIT WITHDRAW TRANSFER"`
+	// Description описание операции для выписки (по умолчанию "ATM")
+	Description string `json:"-"`
+	// SpenderID пользователь, выполняющий списание, для учета дневного лимита участника SPENDER
+	SpenderID uint `json:"-"`
+}
+
+// CloseAccountDTO представляет запрос на закрытие счета
+type CloseAccountDTO struct {
+	// TargetAccountID счет для перевода остатка, обязателен при ненулевом балансе
+	TargetAccountID uint `json:"target_account_id"`
 }
 
 // CreateBankAccountDTO представляет данные для создания банковского счета
@@ -53,19 +74,24 @@ type CreateBankAccountDTO struct {
 	UserID   uint    `json:"user_id" validate:"required"`
 }
 
+// ErrInsufficientFunds возвращается, если доступного остатка не хватает для списания
+var ErrInsufficientFunds = errors.New("недостаточно средств на счете")
+
 // BankService предоставляет методы для работы с банковскими счетами
 type BankService struct {
 	db        *gorm.DB
 	validator *validator.Validate
 	email     *EmailService
+	numbers   *AccountNumberGenerator
 }
 
 // NewBankService создает новый экземпляр BankService
-func NewBankService(db *gorm.DB, email *EmailService) *BankService {
+func NewBankService(db *gorm.DB, email *EmailService, numbers *AccountNumberGenerator) *BankService {
 	return &BankService{
 		db:        db,
 		validator: validator.New(),
 		email:     email,
+		numbers:   numbers,
 	}
 }
 
@@ -89,12 +115,12 @@ func (s *BankService) GetById(id uint) (*models.BankAccount, error) {
 	return &account, nil
 }
 
-// GetAllByUserId возвращает все банковские счета пользователя
+// GetAllByUserId возвращает все банковские счета пользователя, включая совместные
 func (s *BankService) GetAllByUserId(userId uint) ([]models.BankAccount, error) {
 	var accounts []models.BankAccount
 
 	// Ищем все счета пользователя
-	if err := s.db.Where("holder_id = ?", userId).Find(&accounts).Error; err != nil {
+	if err := s.db.Where("id IN (?)", accessibleAccountIDs(s.db, userId)).Find(&accounts).Error; err != nil {
 		return nil, errors.New("ошибка при поиске банковских счетов")
 	}
 
@@ -132,8 +158,18 @@ func (s *BankService) CreateBankAccount(dto CreateBankAccountDTO) (*BankAccountD
 		dto.Title = "Go White"
 	}
 
+	// Начальный остаток тоже ограничен уровнем верификации владельца
+	if dto.Balance > 0 {
+		if err := checkBalanceLimit(s.db, dto.UserID, dto.Balance); err != nil {
+			return nil, err
+		}
+	}
+
 	// Генерируем номер счета
-	accountNumber := s.generateAccountNumber()
+	accountNumber, err := s.generateAccountNumber()
+	if err != nil {
+		return nil, err
+	}
 
 	// Создаем счет
 	account := &models.BankAccount{
@@ -174,18 +210,26 @@ func (s *BankService) CreateBankAccount(dto CreateBankAccountDTO) (*BankAccountD
 	}, nil
 }
 
-// generateAccountNumber генерирует номер банковского счета
-func (s *BankService) generateAccountNumber() string {
-	// Инициализируем генератор случайных чисел
-	rand.Seed(time.Now().UnixNano())
+// accountNumberAttempts количество попыток подобрать свободный номер счета
+const accountNumberAttempts = 10
 
-	// Генерируем 20 случайных цифр
-	var number strings.Builder
-	for i := 0; i < 20; i++ {
-		number.WriteString(strconv.Itoa(rand.Intn(10)))
-	}
+// generateAccountNumber генерирует номер счета, которого еще нет в базе
+func (s *BankService) generateAccountNumber() (string, error) {
+	for attempt := 0; attempt < accountNumberAttempts; attempt++ {
+		number, err := s.numbers.Generate()
+		if err != nil {
+			return "", err
+		}
 
-	return number.String()
+		var count int64
+		if err := s.db.Model(&models.BankAccount{}).Where("number = ?", number).Count(&count).Error; err != nil {
+			return "", errors.New("ошибка при проверке номера счета")
+		}
+		if count == 0 {
+			return number, nil
+		}
+	}
+	return "", errors.New("не удалось подобрать свободный номер счета")
 }
 
 // Deposit пополняет банковский счет
@@ -213,9 +257,9 @@ func (s *BankService) Deposit(request TransactionRequest) (*BankAccountDTO, erro
 		return nil, errors.New("ошибка при начале транзакции")
 	}
 
-	// Получаем счет
+	// Получаем счет с блокировкой строки, чтобы не перезаписать параллельные изменения баланса и удержаний
 	var account models.BankAccount
-	if err := tx.First(&account, request.AccountID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, request.AccountID).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("банковский счет не найден")
@@ -223,6 +267,20 @@ func (s *BankService) Deposit(request TransactionRequest) (*BankAccountDTO, erro
 		return nil, errors.New("ошибка при поиске банковского счета")
 	}
 
+	if err := checkAccountCredit(&account); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Пополнение не должно превышать лимит остатка для уровня верификации.
+	// Переводы проверяют лимит в transferTx.
+	if request.Type == TransactionTypeDeposit {
+		if err := checkBalanceLimit(tx, account.HolderID, request.Amount); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	// Обновляем баланс
 	account.Balance += request.Amount
 	account.UpdatedAt = time.Now()
@@ -300,9 +358,9 @@ func (s *BankService) Withdraw(request TransactionRequest) (*BankAccountDTO, err
 		return nil, errors.New("ошибка при начале транзакции")
 	}
 
-	// Получаем счет
+	// Получаем счет с блокировкой строки, чтобы не перезаписать параллельные изменения баланса и удержаний
 	var account models.BankAccount
-	if err := tx.First(&account, request.AccountID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, request.AccountID).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("банковский счет не найден")
@@ -310,10 +368,19 @@ func (s *BankService) Withdraw(request TransactionRequest) (*BankAccountDTO, err
 		return nil, errors.New("ошибка при поиске банковского счета")
 	}
 
+	if err := checkAccountDebit(&account); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := chargeSpendLimit(tx, request.SpenderID, &account, request.Amount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Проверяем достаточность средств
-	if account.Balance < request.Amount {
+	if account.Available() < request.Amount {
 		tx.Rollback()
-		return nil, errors.New("недостаточно средств на счете")
+		return nil, ErrInsufficientFunds
 	}
 
 	// Обновляем баланс
@@ -327,12 +394,17 @@ func (s *BankService) Withdraw(request TransactionRequest) (*BankAccountDTO, err
 	}
 
 	if request.Type == TransactionTypeWithdraw {
+		description := request.Description
+		if description == "" {
+			description = "ATM"
+		}
+
 		// Создаем запись о транзакции
 		transaction := &models.Transaction{
 			AccountID:   request.AccountID,
 			Amount:      request.Amount,
 			Type:        string(TransactionTypeWithdraw),
-			Description: "ATM",
+			Description: description,
 		}
 
 		// Сохраняем транзакцию
@@ -363,7 +435,9 @@ func (s *BankService) Withdraw(request TransactionRequest) (*BankAccountDTO, err
 	}, nil
 }
 
-// Transfer переводит средства между счетами
+// Transfer переводит средства между счетами. Проводки выполняет transferTx: это
+// единственная реализация перевода между счетами банка, постоянные поручения вызывают
+// ее внутри своей транзакции.
 func (s *BankService) Transfer(request TransferRequest) error {
 	// Валидируем запрос
 	if err := s.validator.Struct(request); err != nil {
@@ -380,82 +454,293 @@ func (s *BankService) Transfer(request TransferRequest) error {
 		return errors.New(strings.Join(errorMessages, "; "))
 	}
 
-	// Проверяем, что счета разные
-	if request.SourceID == request.DestinationID {
-		return errors.New("нельзя перевести средства на тот же счет")
-	}
-
-	// Начинаем транзакцию
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return errors.New("ошибка при начале транзакции")
-	}
-
-	// Снимаем средства с исходного счета
-	sourceAccount, err := s.Withdraw(TransactionRequest{
-		AccountID: request.SourceID,
-		Amount:    request.Amount,
-		Type:      TransactionTypeTransfer,
+	// Оба счета блокируются, проверяются и изменяются в одной транзакции
+	var destination *models.BankAccount
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		destination, err = s.transferTx(tx, request)
+		return err
 	})
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	// Зачисляем средства на целевой счет
-	destinationAccount, err := s.Deposit(TransactionRequest{
-		AccountID: request.DestinationID,
-		Amount:    request.Amount,
-		Type:      TransactionTypeTransfer,
-	})
-	if err != nil {
-		tx.Rollback()
-		return err
+	// Перевод по инициативе пользователя учитывается у его сохраненных получателей
+	if request.SpenderID != 0 {
+		recordPayeeUsage(s.db, nil, request.SpenderID, payeeDetails{Kind: models.PayeeKindAccount, AccountNumber: destination.Number})
 	}
 
-	// Создаем запись о транзакции перевода
-	sourceTransaction := &models.Transaction{
-		AccountID:   request.SourceID,
-		Amount:      request.Amount,
-		Type:        string(TransactionTypeTransfer),
-		Description: "Transfer to account " + destinationAccount.Number,
+	return nil
+}
+
+// transferTx переводит средства между счетами банка в рамках транзакции tx и возвращает
+// счет получателя. Списание и зачисление фиксируются вместе с остальными изменениями
+// этой транзакции.
+func (s *BankService) transferTx(tx *gorm.DB, request TransferRequest) (*models.BankAccount, error) {
+	if request.SourceID == request.DestinationID {
+		return nil, errors.New("нельзя перевести средства на тот же счет")
 	}
 
-	// Создаем запись о транзакции перевода
-	destinationTransaction := &models.Transaction{
-		AccountID:   request.DestinationID,
-		Amount:      request.Amount,
-		Type:        string(TransactionTypeTransfer),
-		Description: "Transfer from account " + sourceAccount.Number,
+	// Блокируем оба счета в порядке возрастания ID, чтобы избежать взаимоблокировок
+	var accounts []models.BankAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", []uint{request.SourceID, request.DestinationID}).
+		Order("id ASC").
+		Find(&accounts).Error; err != nil {
+		return nil, errors.New("ошибка при поиске банковских счетов")
 	}
 
-	// Сохраняем транзакцию
-	if err := tx.Create(sourceTransaction).Error; err != nil {
-		tx.Rollback()
-		return errors.New("ошибка при сохранении транзакции")
+	var source, destination *models.BankAccount
+	for i := range accounts {
+		switch accounts[i].ID {
+		case request.SourceID:
+			source = &accounts[i]
+		case request.DestinationID:
+			destination = &accounts[i]
+		}
+	}
+	if source == nil || destination == nil {
+		return nil, errors.New("банковский счет не найден")
 	}
 
-	// Сохраняем транзакцию
-	if err := tx.Create(destinationTransaction).Error; err != nil {
-		tx.Rollback()
-		return errors.New("ошибка при сохранении транзакции")
+	if err := checkAccountDebit(source); err != nil {
+		return nil, err
+	}
+	if err := checkAccountCredit(destination); err != nil {
+		return nil, fmt.Errorf("счет получателя недоступен: %w", err)
+	}
+	if source.HolderID != destination.HolderID {
+		if err := checkBalanceLimit(tx, destination.HolderID, request.Amount); err != nil {
+			if errors.Is(err, ErrKYCLimitExceeded) {
+				return nil, errors.New("перевод превышает лимит остатка получателя")
+			}
+			return nil, err
+		}
+	}
+	if err := chargeSpendLimit(tx, request.SpenderID, source, request.Amount); err != nil {
+		return nil, err
+	}
+	if source.Available() < request.Amount {
+		return nil, ErrInsufficientFunds
 	}
 
-	// Подтверждаем транзакцию
-	if err := tx.Commit().Error; err != nil {
-		return errors.New("ошибка при подтверждении транзакции")
+	now := time.Now()
+	transactions := []models.Transaction{
+		{
+			AccountID:     source.ID,
+			Amount:        request.Amount,
+			Type:          string(TransactionTypeTransfer),
+			BalanceBefore: source.Balance,
+			BalanceAfter:  source.Balance - request.Amount,
+			Description:   transferDescription("Transfer to account "+destination.Number, request.Description),
+		},
+		{
+			AccountID:     destination.ID,
+			Amount:        request.Amount,
+			Type:          string(TransactionTypeTransfer),
+			BalanceBefore: destination.Balance,
+			BalanceAfter:  destination.Balance + request.Amount,
+			Description:   transferDescription("Transfer from account "+source.Number, request.Description),
+		},
+	}
+	if err := tx.Model(source).Updates(map[string]interface{}{
+		"balance":    source.Balance - request.Amount,
+		"updated_at": now,
+	}).Error; err != nil {
+		return nil, errors.New("ошибка при обновлении баланса")
+	}
+	if err := tx.Model(destination).Updates(map[string]interface{}{
+		"balance":    destination.Balance + request.Amount,
+		"updated_at": now,
+	}).Error; err != nil {
+		return nil, errors.New("ошибка при обновлении баланса")
+	}
+	if err := tx.Create(&transactions).Error; err != nil {
+		return nil, errors.New("ошибка при сохранении транзакции")
 	}
+	return destination, nil
+}
 
-	return nil
+// transferDescription добавляет к описанию перевода назначение, если оно указано
+func transferDescription(base, purpose string) string {
+	if purpose == "" {
+		return base
+	}
+	return base + ": " + purpose
 }
 
-// GetAccountsByUserID возвращает список банковских счетов пользователя
+// GetAccountsByUserID возвращает список банковских счетов пользователя, включая совместные
 func (s *BankService) GetAccountsByUserID(userID uint) ([]models.BankAccount, error) {
 	var accounts []models.BankAccount
-	if err := s.db.Where("holder_id = ?", userID).
+	if err := s.db.Where("id IN (?)", accessibleAccountIDs(s.db, userID)).
 		Preload("Holder").
 		Find(&accounts).Error; err != nil {
 		return nil, errors.New("ошибка при получении списка счетов")
 	}
 	return accounts, nil
 }
+
+// CloseAccount закрывает счет по запросу владельца. Остаток переводится на
+// другой доступный пользователю счет, карты счета закрываются, участники
+// отключаются. Счет с действующим кредитом или ограничением банка закрыть нельзя.
+func (s *BankService) CloseAccount(userID, accountID uint, dto CloseAccountDTO) error {
+	if dto.TargetAccountID == accountID {
+		return errors.New("остаток нельзя перевести на закрываемый счет")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем счета в порядке возрастания ID, чтобы избежать взаимоблокировок
+		ids := []uint{accountID}
+		if dto.TargetAccountID != 0 {
+			ids = append(ids, dto.TargetAccountID)
+		}
+		var accounts []models.BankAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).
+			Order("id ASC").
+			Find(&accounts).Error; err != nil {
+			return errors.New("ошибка при поиске банковских счетов")
+		}
+
+		var account, target *models.BankAccount
+		for i := range accounts {
+			switch accounts[i].ID {
+			case accountID:
+				account = &accounts[i]
+			case dto.TargetAccountID:
+				target = &accounts[i]
+			}
+		}
+		if account == nil {
+			return ErrAccountNotFound
+		}
+
+		// Совладельцы управляют счетом, но закрыть его может только владелец
+		role, err := AccountRoleOf(tx, account, userID)
+		if err != nil {
+			return err
+		}
+		if role != models.AccountRoleOwner {
+			return errors.New("закрыть счет может только его владелец")
+		}
+
+		switch account.Status {
+		case models.AccountStatusClosed:
+			return errors.New("счет уже закрыт")
+		case models.AccountStatusActive:
+		default:
+			return errors.New("счет ограничен банком, закрытие невозможно")
+		}
+
+		var openCredits int64
+		if err := tx.Model(&models.Credit{}).
+			Where("account_id = ? AND status IN ?", accountID, []models.CreditStatus{models.CreditStatusActive, models.CreditStatusOverdue}).
+			Count(&openCredits).Error; err != nil {
+			return errors.New("ошибка при проверке кредитов")
+		}
+		if openCredits > 0 {
+			return errors.New("по счету есть непогашенный кредит")
+		}
+
+		if account.HeldAmount > 0 {
+			return errors.New("по счету есть незавершенные межбанковские платежи")
+		}
+		if account.Balance > 0 {
+			if target == nil {
+				if dto.TargetAccountID != 0 {
+					return errors.New("счет для перевода остатка не найден")
+				}
+				return errors.New("укажите счет для перевода остатка")
+			}
+			if err := s.sweepBalance(tx, userID, account, target); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(account).Updates(map[string]interface{}{
+			"status":     models.AccountStatusClosed,
+			"closed_at":  now,
+			"updated_at": now,
+		}).Error; err != nil {
+			return errors.New("не удалось закрыть счет")
+		}
+
+		if err := tx.Model(&models.Card{}).
+			Where("account_id = ? AND status IN ?", accountID, []models.CardStatus{models.CardStatusActive, models.CardStatusBlocked, models.CardStatusIssued}).
+			Update("status", models.CardStatusClosed).Error; err != nil {
+			return errors.New("не удалось закрыть карты счета")
+		}
+
+		if err := cancelStandingOrders(tx, []uint{accountID}); err != nil {
+			return errors.New("не удалось отменить постоянные поручения счета")
+		}
+
+		if err := tx.Where("account_id = ?", accountID).Delete(&models.AccountMember{}).Error; err != nil {
+			return errors.New("не удалось отключить участников счета")
+		}
+		return tx.Model(&models.AccountInvitation{}).
+			Where("account_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", accountID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// sweepBalance переводит весь остаток закрываемого счета на счет target в рамках транзакции tx
+func (s *BankService) sweepBalance(tx *gorm.DB, userID uint, account, target *models.BankAccount) error {
+	if _, err := AuthorizeAccount(tx, userID, target.ID, models.AccountActionDeposit, 0); err != nil {
+		return err
+	}
+	if err := checkAccountCredit(target); err != nil {
+		return fmt.Errorf("счет для перевода остатка недоступен: %w", err)
+	}
+
+	amount := account.Balance
+	if target.HolderID != account.HolderID {
+		if err := checkBalanceLimit(tx, target.HolderID, amount); err != nil {
+			if errors.Is(err, ErrKYCLimitExceeded) {
+				return errors.New("перевод остатка превышает лимит остатка получателя")
+			}
+			return err
+		}
+	}
+
+	now := time.Now()
+	targetBalanceBefore := target.Balance
+	target.Balance += amount
+	if err := tx.Model(target).Updates(map[string]interface{}{
+		"balance":    target.Balance,
+		"updated_at": now,
+	}).Error; err != nil {
+		return errors.New("ошибка при обновлении баланса")
+	}
+	if err := tx.Model(account).Updates(map[string]interface{}{
+		"balance":    0,
+		"updated_at": now,
+	}).Error; err != nil {
+		return errors.New("ошибка при обновлении баланса")
+	}
+	account.Balance = 0
+
+	transactions := []models.Transaction{
+		{
+			AccountID:     account.ID,
+			Amount:        amount,
+			Type:          string(TransactionTypeTransfer),
+			BalanceBefore: amount,
+			BalanceAfter:  0,
+			Description:   "Account closure: transfer to account " + target.Number,
+		},
+		{
+			AccountID:     target.ID,
+			Amount:        amount,
+			Type:          string(TransactionTypeTransfer),
+			BalanceBefore: targetBalanceBefore,
+			BalanceAfter:  target.Balance,
+			Description:   "Account closure: transfer from account " + account.Number,
+		},
+	}
+	if err := tx.Create(&transactions).Error; err != nil {
+		return errors.New("ошибка при сохранении транзакции")
+	}
+	return nil
+}
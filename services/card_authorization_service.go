@@ -0,0 +1,435 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// CardChannel представляет канал проведения карточной операции
+type CardChannel string
+
+const (
+	CardChannelATM  CardChannel = "ATM"  // Снятие наличных в банкомате
+	CardChannelPOS  CardChannel = "POS"  // Оплата в терминале
+	CardChannelECOM CardChannel = "ECOM" // Оплата в интернете
+)
+
+// CardAuthorizationRequest представляет запрос на авторизацию операции по карте
+type CardAuthorizationRequest struct {
+	Number     string      `json:"number" validate:"required,len=16,numeric"`
+	Expiration string      `json:"expiration" validate:"required,len=5"`
+	CVV        string      `json:"cvv" validate:"omitempty,len=3,numeric"`
+	PIN        string      `json:"pin" validate:"omitempty,len=4,numeric"`
+	Amount     float64     `json:"amount" validate:"required,gt=0"`
+	Channel    CardChannel `json:"channel" validate:"required,oneof=ATM POS ECOM"`
+	Merchant   string      `json:"merchant" validate:"omitempty,max=100"`
+
+	// Счет эквайера, на который зачисляются средства по одобренной операции
+	SettlementAccountID uint `json:"settlement_account_id" validate:"required"`
+}
+
+// CardChallengeDTO представляет одноразовый код для подтверждения авторизации.
+// Токен выдается эквайеру в ответе на удержанную авторизацию.
+type CardChallengeDTO struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// CardAuthorizationResponseDTO представляет результат авторизации
+type CardAuthorizationResponseDTO struct {
+	ID                 uint    `json:"id"`
+	Status             string  `json:"status"`
+	CardNumber         string  `json:"card_number"`
+	Amount             float64 `json:"amount"`
+	Channel            string  `json:"channel"`
+	DeclineReason      string  `json:"decline_reason,omitempty"`
+	ChallengeToken     string  `json:"challenge_token,omitempty"` // Выдается один раз для подтверждения кодом
+	ChallengeExpiresAt string  `json:"challenge_expires_at,omitempty"`
+	CreatedAt          string  `json:"created_at"`
+}
+
+// ChallengeNotifier доставляет клиенту одноразовые коды подтверждения операций
+type ChallengeNotifier interface {
+	SendChallengeCode(to, code, description string) error
+}
+
+// ErrChallengeRequired возвращается, когда операция ожидает подтверждения одноразовым кодом
+var ErrChallengeRequired = errors.New("требуется подтверждение операции одноразовым кодом")
+
+// AuthorizeCard проводит авторизацию операции по карте от имени эквайера: списывает средства
+// со счета карты и зачисляет их на расчетный счет эквайера.
+// Для операций с присутствием карты (ATM, POS) проверяется PIN-код, для интернет-платежей — CVV.
+// Интернет-платежи выше порога риска удерживаются до ввода одноразового кода:
+// в этом случае возвращается авторизация в статусе PENDING_CHALLENGE и ErrChallengeRequired.
+func (s *CardService) AuthorizeCard(acquirerID uint, request CardAuthorizationRequest) (*CardAuthorizationResponseDTO, error) {
+	if _, err := AuthorizeAccount(s.db, acquirerID, request.SettlementAccountID, models.AccountActionDeposit, 0); err != nil {
+		return nil, err
+	}
+
+	card, err := s.FindByNumber(request.Number)
+	if err != nil {
+		return nil, errors.New("операция отклонена")
+	}
+	if card.AccountID == request.SettlementAccountID {
+		return nil, errors.New("счет зачисления совпадает со счетом карты")
+	}
+
+	authorization := &models.CardAuthorization{
+		CardID:              card.ID,
+		AcquirerID:          acquirerID,
+		SettlementAccountID: request.SettlementAccountID,
+		Amount:              request.Amount,
+		Channel:             string(request.Channel),
+		Merchant:            request.Merchant,
+	}
+
+	if err := s.checkCardCredentials(card, request); err != nil {
+		s.declineAuthorization(authorization, err.Error())
+		return nil, err
+	}
+
+	if request.Channel == CardChannelECOM && request.Amount > s.config.CardChallengeThreshold {
+		token, err := s.startChallenge(card, authorization)
+		if err != nil {
+			return nil, err
+		}
+		response := s.authorizationToResponseDTO(authorization, card)
+		response.ChallengeToken = token
+		return response, ErrChallengeRequired
+	}
+
+	if err := s.captureAuthorization(card, authorization); err != nil {
+		return nil, err
+	}
+
+	return s.authorizationToResponseDTO(authorization, card), nil
+}
+
+// CompleteChallenge проверяет одноразовый код и завершает удержанную авторизацию.
+// Подтвердить операцию может только эквайер, который ее начал, по выданному ему токену.
+// После исчерпания попыток или истечения срока кода авторизация отклоняется.
+func (s *CardService) CompleteChallenge(acquirerID, authorizationID uint, dto CardChallengeDTO) (*CardAuthorizationResponseDTO, error) {
+	var authorization models.CardAuthorization
+	var challengeErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем авторизацию, чтобы параллельные попытки не обошли счетчик
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND challenge_token_hash = ? AND acquirer_id = ?", authorizationID, utils.HashToken(dto.ChallengeToken), acquirerID).
+			First(&authorization).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("авторизация не найдена")
+			}
+			return errors.New("ошибка при поиске авторизации")
+		}
+
+		if authorization.Status != models.CardAuthorizationPendingChallenge {
+			return errors.New("авторизация не ожидает подтверждения")
+		}
+
+		if authorization.ChallengeExpiresAt == nil || time.Now().After(*authorization.ChallengeExpiresAt) {
+			authorization.Status = models.CardAuthorizationExpired
+			authorization.DeclineReason = "истек срок действия кода"
+			challengeErr = errors.New("истек срок действия кода подтверждения")
+			return tx.Save(&authorization).Error
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(authorization.ChallengeCodeHash), []byte(dto.Code)) != nil {
+			authorization.ChallengeAttempts++
+			challengeErr = errors.New("неверный код подтверждения")
+			if authorization.ChallengeAttempts >= s.config.CardChallengeMaxAttempts {
+				authorization.Status = models.CardAuthorizationDeclined
+				authorization.DeclineReason = "превышено количество попыток ввода кода"
+				challengeErr = errors.New("превышено количество попыток ввода кода, операция отклонена")
+			}
+			return tx.Save(&authorization).Error
+		}
+
+		// Код верный: снимаем удержание, списание выполняется ниже
+		authorization.ChallengeCodeHash = ""
+		return tx.Model(&authorization).Update("challenge_code_hash", "").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if challengeErr != nil {
+		return nil, challengeErr
+	}
+
+	var card models.Card
+	if err := s.db.First(&card, authorization.CardID).Error; err != nil {
+		s.declineAuthorization(&authorization, "карта не найдена")
+		return nil, errors.New("операция отклонена")
+	}
+
+	// Карта могла быть заблокирована, пока операция ожидала подтверждения
+	if card.Status != models.CardStatusActive {
+		s.declineAuthorization(&authorization, "карта неактивна")
+		return nil, errors.New("операция отклонена")
+	}
+
+	if err := s.captureAuthorization(&card, &authorization); err != nil {
+		return nil, err
+	}
+
+	return s.authorizationToResponseDTO(&authorization, &card), nil
+}
+
+// startChallenge сохраняет авторизацию в статусе PENDING_CHALLENGE, отправляет код владельцу
+// карты и возвращает токен, по которому эквайер подтвердит операцию
+func (s *CardService) startChallenge(card *models.Card, authorization *models.CardAuthorization) (string, error) {
+	code, err := randomDigits(rand.Reader, 6)
+	if err != nil {
+		return "", err
+	}
+
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(time.Duration(s.config.CardChallengeTTL) * time.Minute)
+	authorization.Status = models.CardAuthorizationPendingChallenge
+	authorization.ChallengeCodeHash = string(codeHash)
+	authorization.ChallengeTokenHash = utils.HashToken(token)
+	authorization.ChallengeExpiresAt = &expiresAt
+
+	if err := s.db.Create(authorization).Error; err != nil {
+		return "", errors.New("не удалось сохранить авторизацию")
+	}
+
+	description := fmt.Sprintf("Оплата %.2f по карте %s", authorization.Amount, card.NumberMasked)
+	if authorization.Merchant != "" {
+		description += " в " + authorization.Merchant
+	}
+	if err := s.challengeNotifier.SendChallengeCode(card.Account.Holder.Email, code, description); err != nil {
+		log.Printf("Ошибка отправки кода подтверждения по авторизации %d: %v", authorization.ID, err)
+		s.declineAuthorization(authorization, "не удалось отправить код подтверждения")
+		return "", errors.New("не удалось отправить код подтверждения")
+	}
+
+	return token, nil
+}
+
+// captureAuthorization списывает средства со счета карты и зачисляет их на счет эквайера.
+// Лимиты карты проверяются после блокировки счета, поэтому параллельные операции
+// по одной карте не превысят суточный лимит.
+func (s *CardService) captureAuthorization(card *models.Card, authorization *models.CardAuthorization) error {
+	description := "Card payment"
+	switch CardChannel(authorization.Channel) {
+	case CardChannelATM:
+		description = "ATM"
+	case CardChannelECOM, CardChannelPOS:
+		if authorization.Merchant != "" {
+			description = "Card payment: " + authorization.Merchant
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем оба счета в порядке возрастания ID, чтобы избежать взаимоблокировок
+		var accounts []models.BankAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{card.AccountID, authorization.SettlementAccountID}).
+			Order("id ASC").
+			Find(&accounts).Error; err != nil {
+			return errors.New("ошибка при поиске банковских счетов")
+		}
+
+		var cardAccount, settlementAccount *models.BankAccount
+		for i := range accounts {
+			switch accounts[i].ID {
+			case card.AccountID:
+				cardAccount = &accounts[i]
+			case authorization.SettlementAccountID:
+				settlementAccount = &accounts[i]
+			}
+		}
+		if cardAccount == nil || settlementAccount == nil {
+			return errors.New("банковский счет не найден")
+		}
+
+		if err := s.checkCardLimits(tx, card, authorization.Amount); err != nil {
+			return err
+		}
+		if err := checkAccountDebit(cardAccount); err != nil {
+			return err
+		}
+		if err := checkAccountCredit(settlementAccount); err != nil {
+			return errors.New("счет эквайера не может принимать зачисления")
+		}
+		if cardAccount.Available() < authorization.Amount {
+			return ErrInsufficientFunds
+		}
+
+		now := time.Now()
+		transactions := []models.Transaction{
+			{
+				AccountID:     cardAccount.ID,
+				Amount:        authorization.Amount,
+				Type:          string(TransactionTypeWithdraw),
+				BalanceBefore: cardAccount.Balance,
+				BalanceAfter:  cardAccount.Balance - authorization.Amount,
+				Description:   description,
+			},
+			{
+				AccountID:     settlementAccount.ID,
+				Amount:        authorization.Amount,
+				Type:          string(TransactionTypeDeposit),
+				BalanceBefore: settlementAccount.Balance,
+				BalanceAfter:  settlementAccount.Balance + authorization.Amount,
+				Description:   "Card acquiring " + card.NumberMasked,
+			},
+		}
+		if err := tx.Model(cardAccount).Updates(map[string]interface{}{
+			"balance":    cardAccount.Balance - authorization.Amount,
+			"updated_at": now,
+		}).Error; err != nil {
+			return errors.New("ошибка при обновлении баланса")
+		}
+		if err := tx.Model(settlementAccount).Updates(map[string]interface{}{
+			"balance":    settlementAccount.Balance + authorization.Amount,
+			"updated_at": now,
+		}).Error; err != nil {
+			return errors.New("ошибка при обновлении баланса")
+		}
+		if err := tx.Create(&transactions).Error; err != nil {
+			return errors.New("ошибка при сохранении транзакции")
+		}
+
+		authorization.Status = models.CardAuthorizationApproved
+		authorization.DeclineReason = ""
+		if err := tx.Save(authorization).Error; err != nil {
+			return errors.New("ошибка при сохранении авторизации")
+		}
+		return nil
+	})
+	if err != nil {
+		s.declineAuthorization(authorization, err.Error())
+		return err
+	}
+	return nil
+}
+
+// declineAuthorization фиксирует отклонение авторизации
+func (s *CardService) declineAuthorization(authorization *models.CardAuthorization, reason string) {
+	authorization.Status = models.CardAuthorizationDeclined
+	authorization.DeclineReason = truncate(reason, 255)
+	if err := s.db.Save(authorization).Error; err != nil {
+		log.Printf("Ошибка сохранения отклоненной авторизации по карте %d: %v", authorization.CardID, err)
+	}
+}
+
+// authorizationToResponseDTO конвертирует авторизацию в DTO
+func (s *CardService) authorizationToResponseDTO(authorization *models.CardAuthorization, card *models.Card) *CardAuthorizationResponseDTO {
+	response := &CardAuthorizationResponseDTO{
+		ID:            authorization.ID,
+		Status:        string(authorization.Status),
+		CardNumber:    card.NumberMasked,
+		Amount:        authorization.Amount,
+		Channel:       authorization.Channel,
+		DeclineReason: authorization.DeclineReason,
+		CreatedAt:     authorization.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if authorization.Status == models.CardAuthorizationPendingChallenge && authorization.ChallengeExpiresAt != nil {
+		response.ChallengeExpiresAt = authorization.ChallengeExpiresAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// checkCardCredentials проверяет статус карты, срок действия и данные держателя
+func (s *CardService) checkCardCredentials(card *models.Card, request CardAuthorizationRequest) error {
+	if card.Status == models.CardStatusBlocked {
+		return ErrCardBlocked
+	}
+	if card.Status != models.CardStatusActive {
+		return errors.New("операция отклонена")
+	}
+	if err := checkAccountDebit(&card.Account); err != nil {
+		return err
+	}
+
+	// Срок действия сверяем по HMAC, не расшифровывая данные карты
+	expirationHMAC, err := s.keys.HMAC(card.KeyVersion, request.Expiration)
+	if err != nil {
+		return errors.New("операция отклонена")
+	}
+	if expirationHMAC != card.ExpirationHMAC {
+		return s.registerCredentialFailure(card.ID)
+	}
+	expiresAt, err := cardExpiryTime(request.Expiration)
+	if err != nil || !time.Now().Before(expiresAt) {
+		return errors.New("срок действия карты истек")
+	}
+
+	switch request.Channel {
+	case CardChannelATM, CardChannelPOS:
+		if request.PIN == "" {
+			return errors.New("требуется PIN-код")
+		}
+		return s.VerifyPIN(card.ID, request.PIN)
+	case CardChannelECOM:
+		if request.CVV == "" {
+			return errors.New("требуется CVV")
+		}
+		if !s.verifyCVV(card, request.CVV) {
+			return s.registerCredentialFailure(card.ID)
+		}
+	}
+
+	if card.CVVAttempts > 0 {
+		if err := s.db.Model(&models.Card{}).Where("id = ?", card.ID).Update("cvv_attempts", 0).Error; err != nil {
+			log.Printf("Ошибка сброса счетчика проверок CVV карты %d: %v", card.ID, err)
+		}
+	}
+	return nil
+}
+
+// registerCredentialFailure учитывает неверный CVV или срок действия карты.
+// По достижении лимита карта блокируется, как после неверных вводов PIN-кода.
+func (s *CardService) registerCredentialFailure(cardID uint) error {
+	declineErr := errors.New("операция отклонена")
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем строку, чтобы параллельные попытки не обошли счетчик
+		var card models.Card
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, cardID).Error; err != nil {
+			return errors.New("карта не найдена")
+		}
+
+		updates := map[string]interface{}{"cvv_attempts": card.CVVAttempts + 1}
+		if card.CVVAttempts+1 >= s.config.CardCVVMaxAttempts {
+			updates["status"] = models.CardStatusBlocked
+			declineErr = errors.New("превышено количество неверных вводов данных карты, карта заблокирована")
+		}
+		return tx.Model(&card).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return declineErr
+}
+
+// verifyCVV проверяет CVV карты. Принимается действующий динамический CVV,
+// выданный при показе реквизитов, либо статический CVV карты.
+func (s *CardService) verifyCVV(card *models.Card, cvv string) bool {
+	if card.DynamicCVVHash != "" && card.DynamicCVVExpiresAt != nil && time.Now().Before(*card.DynamicCVVExpiresAt) {
+		if bcrypt.CompareHashAndPassword([]byte(card.DynamicCVVHash), []byte(cvv)) == nil {
+			return true
+		}
+	}
+	return bcrypt.CompareHashAndPassword([]byte(card.CVV), []byte(cvv)) == nil
+}
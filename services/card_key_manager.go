@@ -0,0 +1,286 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cardKey представляет разобранную версию ключей карт
+type cardKey struct {
+	version    int
+	publicKey  string
+	privateKey string
+	hmacKey    []byte
+
+	// Разобранные связки ключей кэшируются при первом обращении
+	publicRing  openpgp.EntityList
+	privateRing openpgp.EntityList
+}
+
+// CardKeyManager управляет версиями PGP и HMAC ключей карт
+type CardKeyManager struct {
+	mu            sync.RWMutex
+	keys          map[int]*cardKey
+	activeVersion int
+	pinKey        []byte
+}
+
+// NewCardKeyManager создает менеджер ключей на основе конфигурации
+func NewCardKeyManager(cfg *config.Config) (*CardKeyManager, error) {
+	m := &CardKeyManager{
+		keys:   make(map[int]*cardKey),
+		pinKey: []byte(cfg.CardPINKey),
+	}
+
+	for _, keyCfg := range cfg.CardKeys {
+		if err := m.addKey(keyCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := m.keys[cfg.CardActiveKeyVersion]; !ok {
+		return nil, fmt.Errorf("активная версия ключа карт %d не найдена", cfg.CardActiveKeyVersion)
+	}
+	m.activeVersion = cfg.CardActiveKeyVersion
+
+	return m, nil
+}
+
+// addKey регистрирует версию ключей без блокировки
+func (m *CardKeyManager) addKey(keyCfg config.CardKeyConfig) error {
+	if keyCfg.Version <= 0 {
+		return errors.New("версия ключа карт должна быть больше 0")
+	}
+	if keyCfg.HMACKey == "" {
+		return fmt.Errorf("не задан HMAC-ключ карт версии %d", keyCfg.Version)
+	}
+	if _, exists := m.keys[keyCfg.Version]; exists {
+		return fmt.Errorf("версия ключа карт %d уже зарегистрирована", keyCfg.Version)
+	}
+
+	m.keys[keyCfg.Version] = &cardKey{
+		version:    keyCfg.Version,
+		publicKey:  keyCfg.PublicKey,
+		privateKey: keyCfg.PrivateKey,
+		hmacKey:    []byte(keyCfg.HMACKey),
+	}
+	return nil
+}
+
+// Rotate добавляет новую версию ключей и делает ее активной.
+// Старые версии остаются доступными для расшифровки и поиска по HMAC,
+// пока фоновое перешифрование не переведет все карты на новую версию.
+func (m *CardKeyManager) Rotate(keyCfg config.CardKeyConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.addKey(keyCfg); err != nil {
+		return err
+	}
+	m.activeVersion = keyCfg.Version
+	return nil
+}
+
+// ActiveVersion возвращает версию ключа для шифрования новых данных
+func (m *CardKeyManager) ActiveVersion() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.activeVersion
+}
+
+// Versions возвращает все зарегистрированные версии ключей по возрастанию
+func (m *CardKeyManager) Versions() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := make([]int, 0, len(m.keys))
+	for version := range m.keys {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// getKey возвращает версию ключей по номеру
+func (m *CardKeyManager) getKey(version int) (*cardKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("ключ карт версии %d не найден", version)
+	}
+	return key, nil
+}
+
+// publicEntities возвращает разобранный публичный ключ версии (с кэшированием)
+func (m *CardKeyManager) publicEntities(key *cardKey) (openpgp.EntityList, error) {
+	m.mu.RLock()
+	ring := key.publicRing
+	m.mu.RUnlock()
+	if ring != nil {
+		return ring, nil
+	}
+
+	ring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать публичный ключ карт версии %d: %v", key.version, err)
+	}
+
+	m.mu.Lock()
+	key.publicRing = ring
+	m.mu.Unlock()
+	return ring, nil
+}
+
+// privateEntities возвращает разобранный приватный ключ версии (с кэшированием)
+func (m *CardKeyManager) privateEntities(key *cardKey) (openpgp.EntityList, error) {
+	m.mu.RLock()
+	ring := key.privateRing
+	m.mu.RUnlock()
+	if ring != nil {
+		return ring, nil
+	}
+
+	ring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать приватный ключ карт версии %d: %v", key.version, err)
+	}
+
+	m.mu.Lock()
+	key.privateRing = ring
+	m.mu.Unlock()
+	return ring, nil
+}
+
+// Encrypt шифрует данные активной версией ключа и возвращает номер версии
+func (m *CardKeyManager) Encrypt(data string) (string, int, error) {
+	version := m.ActiveVersion()
+	encrypted, err := m.EncryptWithVersion(version, data)
+	if err != nil {
+		return "", 0, err
+	}
+	return encrypted, version, nil
+}
+
+// EncryptWithVersion шифрует данные указанной версией ключа
+func (m *CardKeyManager) EncryptWithVersion(version int, data string) (string, error) {
+	key, err := m.getKey(version)
+	if err != nil {
+		return "", err
+	}
+
+	entityList, err := m.publicEntities(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Шифртекст храним в armored-виде, чтобы он корректно помещался в текстовую колонку
+	var buf strings.Builder
+	armoredWriter, err := armor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", err
+	}
+
+	w, err := openpgp.Encrypt(armoredWriter, entityList, nil, nil, &packet.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write([]byte(data)); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	if err := armoredWriter.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Decrypt расшифровывает данные указанной версией ключа
+func (m *CardKeyManager) Decrypt(version int, encryptedData string) (string, error) {
+	key, err := m.getKey(version)
+	if err != nil {
+		return "", err
+	}
+
+	entityList, err := m.privateEntities(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Поддерживаем как armored-шифртекст, так и бинарный из ранних версий
+	var reader io.Reader = strings.NewReader(encryptedData)
+	if strings.HasPrefix(encryptedData, "-----BEGIN") {
+		block, err := armor.Decode(reader)
+		if err != nil {
+			return "", err
+		}
+		reader = block.Body
+	}
+
+	md, err := openpgp.ReadMessage(reader, entityList, nil, &packet.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	decrypted, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return "", err
+	}
+
+	return string(decrypted), nil
+}
+
+// HMAC вычисляет HMAC данных указанной версией ключа
+func (m *CardKeyManager) HMAC(version int, data string) (string, error) {
+	key, err := m.getKey(version)
+	if err != nil {
+		return "", err
+	}
+
+	h := hmac.New(sha256.New, key.hmacKey)
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LookupHMACs возвращает HMAC данных во всех зарегистрированных версиях ключа.
+// Используется для поиска карт во время ротации, когда часть записей еще
+// подписана старыми ключами.
+func (m *CardKeyManager) LookupHMACs(data string) []string {
+	var hmacs []string
+	for _, version := range m.Versions() {
+		value, err := m.HMAC(version, data)
+		if err != nil {
+			continue
+		}
+		hmacs = append(hmacs, value)
+	}
+	return hmacs
+}
+
+// PINHash вычисляет ключевой хэш PIN-кода карты.
+// Идентификатор карты выступает солью, поэтому одинаковые PIN разных карт
+// дают разные хэши.
+func (m *CardKeyManager) PINHash(cardID uint, pin string) string {
+	h := hmac.New(sha256.New, m.pinKey)
+	h.Write([]byte(strconv.FormatUint(uint64(cardID), 10) + ":" + pin))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"log"
+	"time"
+)
+
+// Таблицы с данными, зашифрованными ключами карт
+const (
+	rotationTableCards      = "cards"
+	rotationTableTwoFactor  = "user_two_factors"
+	rotationTableSavedPayee = "saved_payees"
+)
+
+// rotationCursor возвращает последний просмотренный ID таблицы
+func (s *CardService) rotationCursor(table string) uint {
+	s.rotationMu.Lock()
+	defer s.rotationMu.Unlock()
+	return s.rotationCursors[table]
+}
+
+// advanceRotationCursor сдвигает курсор за последнюю запись пачки. Неполная пачка
+// означает конец таблицы: следующий проход начнется сначала и повторит записи,
+// которые не удалось перешифровать, не мешая остальным.
+func (s *CardService) advanceRotationCursor(table string, lastID uint, count, batchSize int) {
+	s.rotationMu.Lock()
+	defer s.rotationMu.Unlock()
+	if count < batchSize {
+		s.rotationCursors[table] = 0
+		return
+	}
+	s.rotationCursors[table] = lastID
+}
+
+// ReencryptCards перешифровывает пачку карт, зашифрованных неактивной версией ключа.
+// Возвращает количество перешифрованных карт.
+func (s *CardService) ReencryptCards(batchSize int) (int, error) {
+	activeVersion := s.keys.ActiveVersion()
+
+	var cards []models.Card
+	if err := s.db.Where("key_version <> ? AND id > ?", activeVersion, s.rotationCursor(rotationTableCards)).
+		Order("id ASC").
+		Limit(batchSize).
+		Find(&cards).Error; err != nil {
+		return 0, errors.New("ошибка при получении карт для перешифрования")
+	}
+	if len(cards) > 0 {
+		s.advanceRotationCursor(rotationTableCards, cards[len(cards)-1].ID, len(cards), batchSize)
+	} else {
+		s.advanceRotationCursor(rotationTableCards, 0, 0, batchSize)
+	}
+
+	reencrypted := 0
+	for _, card := range cards {
+		number, err := s.keys.Decrypt(card.KeyVersion, card.NumberEncrypted)
+		if err != nil {
+			log.Printf("Не удалось расшифровать номер карты %d: %v", card.ID, err)
+			continue
+		}
+
+		expiration, err := s.keys.Decrypt(card.KeyVersion, card.ExpirationEncrypted)
+		if err != nil {
+			log.Printf("Не удалось расшифровать срок действия карты %d: %v", card.ID, err)
+			continue
+		}
+
+		previousVersion := card.KeyVersion
+		if err := s.sealCard(&card, number, expiration); err != nil {
+			log.Printf("Не удалось перешифровать карту %d: %v", card.ID, err)
+			continue
+		}
+
+		// Условие по старой версии защищает от гонки с параллельным перешифрованием
+		result := s.db.Model(&models.Card{}).
+			Where("id = ? AND key_version = ?", card.ID, previousVersion).
+			Updates(map[string]interface{}{
+				"number_encrypted":     card.NumberEncrypted,
+				"number_hmac":          card.NumberHMAC,
+				"expiration_encrypted": card.ExpirationEncrypted,
+				"expiration_hmac":      card.ExpirationHMAC,
+				"expires_at":           card.ExpiresAt,
+				"key_version":          card.KeyVersion,
+			})
+		if result.Error != nil {
+			log.Printf("Не удалось сохранить перешифрованную карту %d: %v", card.ID, result.Error)
+			continue
+		}
+		reencrypted += int(result.RowsAffected)
+	}
+
+	return reencrypted, nil
+}
+
+// ReencryptTwoFactorSecrets перешифровывает пачку секретов TOTP, зашифрованных
+// неактивной версией ключа карт
+func (s *CardService) ReencryptTwoFactorSecrets(batchSize int) (int, error) {
+	activeVersion := s.keys.ActiveVersion()
+
+	var settings []models.UserTwoFactor
+	if err := s.db.Where("key_version <> ? AND id > ?", activeVersion, s.rotationCursor(rotationTableTwoFactor)).
+		Order("id ASC").
+		Limit(batchSize).
+		Find(&settings).Error; err != nil {
+		return 0, errors.New("ошибка при получении секретов 2FA для перешифрования")
+	}
+	if len(settings) > 0 {
+		s.advanceRotationCursor(rotationTableTwoFactor, settings[len(settings)-1].ID, len(settings), batchSize)
+	} else {
+		s.advanceRotationCursor(rotationTableTwoFactor, 0, 0, batchSize)
+	}
+
+	reencrypted := 0
+	for _, item := range settings {
+		secret, err := s.keys.Decrypt(item.KeyVersion, item.SecretEncrypted)
+		if err != nil {
+			log.Printf("Не удалось расшифровать секрет 2FA %d: %v", item.ID, err)
+			continue
+		}
+		encrypted, version, err := s.keys.Encrypt(secret)
+		if err != nil {
+			log.Printf("Не удалось перешифровать секрет 2FA %d: %v", item.ID, err)
+			continue
+		}
+
+		result := s.db.Model(&models.UserTwoFactor{}).
+			Where("id = ? AND key_version = ?", item.ID, item.KeyVersion).
+			Updates(map[string]interface{}{
+				"secret_encrypted": encrypted,
+				"key_version":      version,
+			})
+		if result.Error != nil {
+			log.Printf("Не удалось сохранить перешифрованный секрет 2FA %d: %v", item.ID, result.Error)
+			continue
+		}
+		reencrypted += int(result.RowsAffected)
+	}
+
+	return reencrypted, nil
+}
+
+// ReencryptSavedPayees перешифровывает пачку номеров карт сохраненных получателей,
+// зашифрованных неактивной версией ключа
+func (s *CardService) ReencryptSavedPayees(batchSize int) (int, error) {
+	activeVersion := s.keys.ActiveVersion()
+
+	var payees []models.SavedPayee
+	if err := s.db.Where("kind = ? AND card_key_version <> ? AND id > ?",
+		models.PayeeKindCard, activeVersion, s.rotationCursor(rotationTableSavedPayee)).
+		Order("id ASC").
+		Limit(batchSize).
+		Find(&payees).Error; err != nil {
+		return 0, errors.New("ошибка при получении получателей для перешифрования")
+	}
+	if len(payees) > 0 {
+		s.advanceRotationCursor(rotationTableSavedPayee, payees[len(payees)-1].ID, len(payees), batchSize)
+	} else {
+		s.advanceRotationCursor(rotationTableSavedPayee, 0, 0, batchSize)
+	}
+
+	reencrypted := 0
+	for _, payee := range payees {
+		number, err := s.keys.Decrypt(payee.CardKeyVersion, payee.CardEncrypted)
+		if err != nil {
+			log.Printf("Не удалось расшифровать карту получателя %d: %v", payee.ID, err)
+			continue
+		}
+		encrypted, version, err := s.keys.Encrypt(number)
+		if err != nil {
+			log.Printf("Не удалось перешифровать карту получателя %d: %v", payee.ID, err)
+			continue
+		}
+
+		result := s.db.Model(&models.SavedPayee{}).
+			Where("id = ? AND card_key_version = ?", payee.ID, payee.CardKeyVersion).
+			Updates(map[string]interface{}{
+				"card_encrypted":   encrypted,
+				"card_key_version": version,
+			})
+		if result.Error != nil {
+			log.Printf("Не удалось сохранить перешифрованную карту получателя %d: %v", payee.ID, result.Error)
+			continue
+		}
+		reencrypted += int(result.RowsAffected)
+	}
+
+	return reencrypted, nil
+}
+
+// StartKeyRotation запускает фоновое перешифрование карт, секретов 2FA и карт
+// сохраненных получателей на активную версию ключа
+func (s *CardService) StartKeyRotation(interval time.Duration, batchSize int) {
+	steps := []struct {
+		name      string
+		reencrypt func(int) (int, error)
+	}{
+		{"карт", s.ReencryptCards},
+		{"секретов 2FA", s.ReencryptTwoFactorSecrets},
+		{"карт сохраненных получателей", s.ReencryptSavedPayees},
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			for _, step := range steps {
+				count, err := step.reencrypt(batchSize)
+				if err != nil {
+					log.Printf("Ошибка при перешифровании %s: %v", step.name, err)
+					continue
+				}
+				if count > 0 {
+					log.Printf("Перешифровано %s: %d", step.name, count)
+				}
+			}
+		}
+	}()
+}
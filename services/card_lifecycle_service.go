@@ -0,0 +1,170 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// ProcessCardExpiry помечает карты с истекшим сроком действия как EXPIRED
+// и заранее перевыпускает карты, срок действия которых скоро истечет.
+// Карты выбираются по открытому сроку expires_at; срок расшифровывается только
+// у карт, выпущенных до появления этой колонки, и сохраняется для следующих запусков.
+// Возвращает количество карт с истекшим сроком и количество перевыпущенных карт.
+func (s *CardService) ProcessCardExpiry() (int, int, error) {
+	now := time.Now()
+	reissueBefore := now.AddDate(0, 0, s.config.CardReissueDaysBefore)
+
+	var cards []models.Card
+	if err := s.db.Preload("Account.Holder").
+		Where("status IN ?", []models.CardStatus{
+			models.CardStatusActive,
+			models.CardStatusBlocked,
+			models.CardStatusIssued,
+		}).
+		Where("expires_at IS NULL OR expires_at < ?", reissueBefore).
+		Find(&cards).Error; err != nil {
+		return 0, 0, errors.New("ошибка при получении карт")
+	}
+
+	expired, reissued := 0, 0
+
+	for i := range cards {
+		card := &cards[i]
+
+		expiresAt, err := s.cardExpiresAt(card)
+		if err != nil {
+			log.Printf("Не удалось определить срок действия карты %d: %v", card.ID, err)
+			continue
+		}
+
+		if !now.Before(expiresAt) {
+			if err := s.expireCard(card); err != nil {
+				log.Printf("Не удалось завершить срок действия карты %d: %v", card.ID, err)
+				continue
+			}
+			expired++
+			continue
+		}
+
+		// Перевыпускаем только действующие карты, которые еще не перевыпускались
+		if card.Status == models.CardStatusActive && card.ReplacedByCardID == nil && expiresAt.Before(reissueBefore) {
+			if err := s.reissueCard(card, expiresAt); err != nil {
+				log.Printf("Не удалось перевыпустить карту %d: %v", card.ID, err)
+				continue
+			}
+			reissued++
+		}
+	}
+
+	return expired, reissued, nil
+}
+
+// cardExpiresAt возвращает окончание срока действия карты. Для карт без expires_at
+// срок расшифровывается и сохраняется.
+func (s *CardService) cardExpiresAt(card *models.Card) (time.Time, error) {
+	if card.ExpiresAt != nil {
+		return *card.ExpiresAt, nil
+	}
+
+	expiration, err := s.keys.Decrypt(card.KeyVersion, card.ExpirationEncrypted)
+	if err != nil {
+		return time.Time{}, err
+	}
+	expiresAt, err := cardExpiryTime(expiration)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.db.Model(&models.Card{}).Where("id = ?", card.ID).Update("expires_at", expiresAt).Error; err != nil {
+		log.Printf("Не удалось сохранить срок действия карты %d: %v", card.ID, err)
+	}
+	card.ExpiresAt = &expiresAt
+	return expiresAt, nil
+}
+
+// expireCard переводит карту в статус EXPIRED и уведомляет владельца
+func (s *CardService) expireCard(card *models.Card) error {
+	if err := s.db.Model(card).Update("status", models.CardStatusExpired).Error; err != nil {
+		return err
+	}
+
+	if err := s.email.SendCardExpiredNotification(card.Account.Holder.Email, card.NumberMasked); err != nil {
+		log.Printf("Ошибка отправки уведомления об истечении срока карты: %v", err)
+	}
+	return nil
+}
+
+// reissueCard выпускает новую карту к тому же счету с теми же лимитами.
+// Старая карта продолжает работать, пока новая не будет активирована.
+func (s *CardService) reissueCard(card *models.Card, expiresAt time.Time) error {
+	newCard, err := s.newCard(card.AccountID, card.SingleLimit, card.DailyLimit, models.CardStatusIssued)
+	if err != nil {
+		return err
+	}
+	newCard.ReplacesCardID = &card.ID
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newCard).Error; err != nil {
+			return err
+		}
+		// Условие защищает от повторного перевыпуска при параллельном запуске задачи
+		result := tx.Model(&models.Card{}).
+			Where("id = ? AND replaced_by_card_id IS NULL", card.ID).
+			Update("replaced_by_card_id", newCard.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("карта уже перевыпущена")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.email.SendCardReissuedNotification(card.Account.Holder.Email, card.NumberMasked, newCard.NumberMasked, expiresAt); err != nil {
+		log.Printf("Ошибка отправки уведомления о перевыпуске карты: %v", err)
+	}
+	return nil
+}
+
+// ActivateCard активирует перевыпущенную карту. Заменяемая карта при этом
+// перестает действовать.
+func (s *CardService) ActivateCard(userID, cardID uint) (*CardResponseDTO, error) {
+	card, err := s.GetUserCard(userID, cardID, models.AccountActionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status != models.CardStatusIssued {
+		return nil, errors.New("карта не ожидает активации")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(card).Update("status", models.CardStatusActive).Error; err != nil {
+			return errors.New("не удалось активировать карту")
+		}
+
+		if card.ReplacesCardID != nil {
+			if err := tx.Model(&models.Card{}).
+				Where("id = ? AND status IN ?", *card.ReplacesCardID, []models.CardStatus{
+					models.CardStatusActive,
+					models.CardStatusBlocked,
+				}).
+				Update("status", models.CardStatusReplaced).Error; err != nil {
+				return errors.New("не удалось закрыть заменяемую карту")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	card.Status = models.CardStatusActive
+	return s.cardToResponseDTO(card)
+}
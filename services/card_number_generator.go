@@ -0,0 +1,131 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// cardNumberLength длина номера карты
+const cardNumberLength = 16
+
+// CardNumberGenerator генерирует номера карт в диапазонах BIN платежной системы
+type CardNumberGenerator struct {
+	ranges []config.BINRange
+	random io.Reader
+}
+
+// NewCardNumberGenerator создает генератор для указанной платежной системы
+func NewCardNumberGenerator(cfg *config.Config) (*CardNumberGenerator, error) {
+	ranges, ok := cfg.CardBINRanges[cfg.CardPaymentSystem]
+	if !ok || len(ranges) == 0 {
+		return nil, fmt.Errorf("для платежной системы %s не заданы диапазоны BIN", cfg.CardPaymentSystem)
+	}
+	return newCardNumberGenerator(ranges, rand.Reader), nil
+}
+
+// newCardNumberGenerator создает генератор с заданным источником случайности
+func newCardNumberGenerator(ranges []config.BINRange, random io.Reader) *CardNumberGenerator {
+	return &CardNumberGenerator{
+		ranges: ranges,
+		random: random,
+	}
+}
+
+// Generate генерирует номер карты с корректной контрольной цифрой по алгоритму Луна
+func (g *CardNumberGenerator) Generate() (string, error) {
+	prefix, err := g.randomPrefix()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := randomDigits(g.random, cardNumberLength-1-len(prefix))
+	if err != nil {
+		return "", err
+	}
+
+	payload := prefix + body
+	return payload + strconv.Itoa(luhnCheckDigit(payload)), nil
+}
+
+// randomPrefix выбирает случайный префикс из диапазонов BIN.
+// Вероятность выбора диапазона пропорциональна его размеру.
+func (g *CardNumberGenerator) randomPrefix() (string, error) {
+	total := big.NewInt(0)
+	sizes := make([]*big.Int, len(g.ranges))
+	for i, r := range g.ranges {
+		from, okFrom := new(big.Int).SetString(r.From, 10)
+		to, okTo := new(big.Int).SetString(r.To, 10)
+		if !okFrom || !okTo || len(r.From) != len(r.To) || from.Cmp(to) > 0 {
+			return "", fmt.Errorf("некорректный диапазон BIN %s-%s", r.From, r.To)
+		}
+		sizes[i] = new(big.Int).Add(new(big.Int).Sub(to, from), big.NewInt(1))
+		total.Add(total, sizes[i])
+	}
+
+	n, err := rand.Int(g.random, total)
+	if err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать префикс карты: %v", err)
+	}
+
+	for i, r := range g.ranges {
+		if n.Cmp(sizes[i]) < 0 {
+			from, _ := new(big.Int).SetString(r.From, 10)
+			prefix := new(big.Int).Add(from, n).String()
+			// Сохраняем ведущие нули префикса
+			return strings.Repeat("0", len(r.From)-len(prefix)) + prefix, nil
+		}
+		n.Sub(n, sizes[i])
+	}
+
+	return "", errors.New("не удалось выбрать диапазон BIN")
+}
+
+// randomDigits генерирует строку из случайных цифр заданной длины
+func randomDigits(random io.Reader, length int) (string, error) {
+	var number strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(random, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("не удалось сгенерировать случайную цифру: %v", err)
+		}
+		number.WriteString(digit.String())
+	}
+	return number.String(), nil
+}
+
+// luhnCheckDigit вычисляет контрольную цифру по алгоритму Луна для номера без нее
+func luhnCheckDigit(payload string) int {
+	sum := 0
+	// Удваиваются цифры на четных позициях справа, считая контрольную цифру первой
+	for i := len(payload) - 1; i >= 0; i-- {
+		digit := int(payload[i] - '0')
+		if (len(payload)-i)%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return (10 - sum%10) % 10
+}
+
+// validateLuhn проверяет номер карты по алгоритму Луна
+func validateLuhn(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	payload := number[:len(number)-1]
+	return int(number[len(number)-1]-'0') == luhnCheckDigit(payload)
+}
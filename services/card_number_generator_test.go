@@ -0,0 +1,126 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"crypto/rand"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+// digitsFromSeed превращает произвольные байты в строку цифр заданной длины
+func digitsFromSeed(seed []byte, length int) string {
+	digits := make([]byte, length)
+	for i := range digits {
+		var b byte
+		if len(seed) > 0 {
+			b = seed[i%len(seed)] + byte(i)
+		}
+		digits[i] = '0' + b%10
+	}
+	return string(digits)
+}
+
+func TestValidateLuhnKnownNumbers(t *testing.T) {
+	valid := []string{"79927398713", "4111111111111111", "5555555555554444", "2200000000000004"}
+	for _, number := range valid {
+		if !validateLuhn(number) {
+			t.Errorf("validateLuhn(%s) = false, want true", number)
+		}
+	}
+
+	invalid := []string{"79927398710", "4111111111111112", "", "7", "4111-1111-1111-1111"}
+	for _, number := range invalid {
+		if validateLuhn(number) {
+			t.Errorf("validateLuhn(%q) = true, want false", number)
+		}
+	}
+}
+
+func TestLuhnCheckDigitProperty(t *testing.T) {
+	// Любой номер с дописанной контрольной цифрой проходит проверку
+	property := func(seed []byte) bool {
+		payload := digitsFromSeed(seed, cardNumberLength-1)
+		return validateLuhn(payload + strconv.Itoa(luhnCheckDigit(payload)))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLuhnDetectsSingleDigitErrors(t *testing.T) {
+	// Замена любой одной цифры на другую ломает контрольную сумму
+	property := func(seed []byte, position uint8, delta uint8) bool {
+		payload := digitsFromSeed(seed, cardNumberLength-1)
+		number := []byte(payload + strconv.Itoa(luhnCheckDigit(payload)))
+
+		i := int(position) % len(number)
+		shift := byte(delta%9) + 1
+		number[i] = '0' + (number[i]-'0'+shift)%10
+
+		return !validateLuhn(string(number))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCardNumberGeneratorProperty(t *testing.T) {
+	ranges := []config.BINRange{{From: "2200", To: "2204"}}
+	generator := newCardNumberGenerator(ranges, rand.Reader)
+
+	property := func() bool {
+		number, err := generator.Generate()
+		if err != nil {
+			return false
+		}
+		if len(number) != cardNumberLength || !validateLuhn(number) {
+			return false
+		}
+		prefix := number[:4]
+		return prefix >= "2200" && prefix <= "2204"
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCardNumberGeneratorCoversAllRanges(t *testing.T) {
+	ranges := []config.BINRange{{From: "4", To: "4"}, {From: "51", To: "55"}}
+	generator := newCardNumberGenerator(ranges, rand.Reader)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 2000; i++ {
+		number, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if number[0] == '4' {
+			seen["4"] = true
+		} else {
+			seen[number[:2]] = true
+		}
+	}
+
+	for _, prefix := range []string{"4", "51", "52", "53", "54", "55"} {
+		if !seen[prefix] {
+			t.Errorf("prefix %s was never generated", prefix)
+		}
+	}
+}
+
+func TestCardNumbersAreNotRepeated(t *testing.T) {
+	generator := newCardNumberGenerator([]config.BINRange{{From: "2200", To: "2204"}}, rand.Reader)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		number, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[number] {
+			t.Fatalf("number %s generated twice", number)
+		}
+		seen[number] = true
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"crypto/hmac"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetPINDTO представляет данные для установки PIN-кода
+type SetPINDTO struct {
+	PIN string `json:"pin" validate:"required,len=4,numeric"`
+}
+
+// ChangePINDTO представляет данные для смены PIN-кода
+type ChangePINDTO struct {
+	OldPIN string `json:"old_pin" validate:"required,len=4,numeric"`
+	NewPIN string `json:"new_pin" validate:"required,len=4,numeric"`
+}
+
+// ErrInvalidPIN возвращается при неверном PIN-коде
+var ErrInvalidPIN = errors.New("неверный PIN-код")
+
+// ErrCardBlocked возвращается при операциях с заблокированной картой
+var ErrCardBlocked = errors.New("карта заблокирована")
+
+// GetUserCard возвращает карту, если пользователю доступно действие action по ее счету.
+// Показ реквизитов, PIN-код и активация требуют права управления счетом,
+// переводы с карты — права расходовать средства.
+func (s *CardService) GetUserCard(userID, cardID uint, action models.AccountAction) (*models.Card, error) {
+	var card models.Card
+	if err := s.db.Preload("Account.Holder").First(&card, cardID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("карта не найдена")
+		}
+		return nil, errors.New("ошибка при поиске карты")
+	}
+
+	if _, err := AuthorizeAccount(s.db, userID, card.AccountID, action, 0); err != nil {
+		if errors.Is(err, ErrAccountAccessDenied) {
+			return nil, errors.New("нет доступа к данной карте")
+		}
+		return nil, err
+	}
+
+	return &card, nil
+}
+
+// SetPIN устанавливает PIN-код карты, если он еще не задан
+func (s *CardService) SetPIN(userID, cardID uint, dto SetPINDTO) error {
+	card, err := s.GetUserCard(userID, cardID, models.AccountActionManage)
+	if err != nil {
+		return err
+	}
+
+	if card.PINHash != "" {
+		return errors.New("PIN-код уже установлен, используйте смену PIN-кода")
+	}
+
+	if !isAcceptablePIN(dto.PIN) {
+		return errors.New("PIN-код слишком простой")
+	}
+
+	return s.db.Model(card).Updates(map[string]interface{}{
+		"pin_hash":     s.keys.PINHash(card.ID, dto.PIN),
+		"pin_attempts": 0,
+	}).Error
+}
+
+// ChangePIN меняет PIN-код карты после проверки текущего
+func (s *CardService) ChangePIN(userID, cardID uint, dto ChangePINDTO) error {
+	card, err := s.GetUserCard(userID, cardID, models.AccountActionManage)
+	if err != nil {
+		return err
+	}
+
+	if err := s.VerifyPIN(card.ID, dto.OldPIN); err != nil {
+		return err
+	}
+
+	if !isAcceptablePIN(dto.NewPIN) {
+		return errors.New("PIN-код слишком простой")
+	}
+
+	return s.db.Model(&models.Card{}).Where("id = ?", card.ID).
+		Update("pin_hash", s.keys.PINHash(card.ID, dto.NewPIN)).Error
+}
+
+// VerifyPIN проверяет PIN-код карты.
+// Каждая неудачная попытка увеличивает счетчик, по достижении лимита карта блокируется.
+// Успешная проверка сбрасывает счетчик.
+func (s *CardService) VerifyPIN(cardID uint, pin string) error {
+	var verifyErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем строку, чтобы параллельные попытки не обошли счетчик
+		var card models.Card
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, cardID).Error; err != nil {
+			return errors.New("карта не найдена")
+		}
+
+		if card.Status == models.CardStatusBlocked {
+			verifyErr = ErrCardBlocked
+			return nil
+		}
+
+		if card.PINHash == "" {
+			verifyErr = errors.New("PIN-код не установлен")
+			return nil
+		}
+
+		if hmac.Equal([]byte(card.PINHash), []byte(s.keys.PINHash(card.ID, pin))) {
+			if card.PINAttempts == 0 {
+				return nil
+			}
+			return tx.Model(&card).Update("pin_attempts", 0).Error
+		}
+
+		updates := map[string]interface{}{"pin_attempts": card.PINAttempts + 1}
+		verifyErr = ErrInvalidPIN
+		if card.PINAttempts+1 >= s.config.CardPINMaxAttempts {
+			updates["status"] = models.CardStatusBlocked
+			verifyErr = errors.New("превышено количество попыток ввода PIN-кода, карта заблокирована")
+		}
+		return tx.Model(&card).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return verifyErr
+}
+
+// isAcceptablePIN отклоняет PIN-коды из одинаковых или последовательных цифр
+func isAcceptablePIN(pin string) bool {
+	same, ascending, descending := true, true, true
+	for i := 1; i < len(pin); i++ {
+		same = same && pin[i] == pin[0]
+		ascending = ascending && pin[i] == pin[i-1]+1
+		descending = descending && pin[i] == pin[i-1]-1
+	}
+	return !same && !ascending && !descending
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// revealWrongPassword причина отказа в журнале показа реквизитов, по которой считаются
+// неудачные попытки ввода пароля
+const revealWrongPassword = "неверный пароль"
+
+// ErrRevealLocked возвращается, когда показ реквизитов заблокирован после неверных паролей
+var ErrRevealLocked = errors.New("слишком много неверных попыток, показ реквизитов временно заблокирован")
+
+// RevealCardDTO представляет данные для подтверждения показа реквизитов
+type RevealCardDTO struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// RequestMeta представляет сведения о клиенте, выполнившем запрос
+type RequestMeta struct {
+	IP        string
+	UserAgent string
+	DeviceID  string // Идентификатор устройства из заголовка X-Device-ID, если клиент его передает
+}
+
+// CardRevealResponseDTO представляет полные реквизиты карты
+type CardRevealResponseDTO struct {
+	ID           uint   `json:"id"`
+	Number       string `json:"number"`
+	Expiration   string `json:"expiration"`
+	CVV          string `json:"cvv"`
+	CVVExpiresAt string `json:"cvv_expires_at"`
+}
+
+// RevealCard показывает полные реквизиты карты после повторной проверки пароля.
+// Вместо статического CVV выдается динамический, действующий несколько минут.
+// Каждая попытка, успешная или нет, записывается в журнал. После CardRevealMaxAttempts
+// неверных паролей подряд показ реквизитов блокируется на CardRevealLockMinutes.
+func (s *CardService) RevealCard(userID, cardID uint, dto RevealCardDTO, meta RequestMeta) (*CardRevealResponseDTO, error) {
+	card, err := s.GetUserCard(userID, cardID, models.AccountActionManage)
+	if err != nil {
+		s.auditReveal(cardID, userID, meta, false, err.Error())
+		return nil, err
+	}
+
+	if err := s.checkRevealPassword(card.ID, userID, dto.Password, meta); err != nil {
+		return nil, err
+	}
+
+	if card.Status != models.CardStatusActive {
+		s.auditReveal(card.ID, userID, meta, false, "карта неактивна")
+		return nil, errors.New("карта неактивна")
+	}
+
+	number, err := s.keys.Decrypt(card.KeyVersion, card.NumberEncrypted)
+	if err != nil {
+		return nil, errors.New("не удалось расшифровать номер карты")
+	}
+
+	expiration, err := s.keys.Decrypt(card.KeyVersion, card.ExpirationEncrypted)
+	if err != nil {
+		return nil, errors.New("не удалось расшифровать дату истечения")
+	}
+
+	cvv, err := s.generateCVV()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedCVV, err := s.hashCVV(cvv)
+	if err != nil {
+		return nil, err
+	}
+
+	cvvExpiresAt := time.Now().Add(time.Duration(s.config.CardDynamicCVVTTL) * time.Minute)
+	if err := s.db.Model(card).Updates(map[string]interface{}{
+		"dynamic_cvv_hash":       hashedCVV,
+		"dynamic_cvv_expires_at": cvvExpiresAt,
+	}).Error; err != nil {
+		return nil, errors.New("не удалось выпустить динамический CVV")
+	}
+
+	s.auditReveal(card.ID, userID, meta, true, "")
+
+	return &CardRevealResponseDTO{
+		ID:           card.ID,
+		Number:       number,
+		Expiration:   expiration,
+		CVV:          cvv,
+		CVVExpiresAt: cvvExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// checkRevealPassword проверяет пароль пользователя с учетом неудачных попыток.
+// Строка пользователя блокируется, чтобы параллельные запросы не обошли счетчик.
+func (s *CardService) checkRevealPassword(cardID, userID uint, password string, meta RequestMeta) error {
+	var checkErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("пользователь не найден")
+		}
+
+		// Считаем неверные пароли после последнего успешного показа в пределах окна блокировки
+		since := time.Now().Add(-time.Duration(s.config.CardRevealLockMinutes) * time.Minute)
+		var lastSuccess models.CardRevealAudit
+		if err := tx.Where("user_id = ? AND success = ? AND created_at > ?", userID, true, since).
+			Order("created_at DESC").
+			Limit(1).
+			Find(&lastSuccess).Error; err != nil {
+			return errors.New("ошибка при проверке попыток показа реквизитов")
+		}
+		if lastSuccess.ID != 0 {
+			since = lastSuccess.CreatedAt
+		}
+
+		var failures int64
+		if err := tx.Model(&models.CardRevealAudit{}).
+			Where("user_id = ? AND success = ? AND reason = ? AND created_at > ?", userID, false, revealWrongPassword, since).
+			Count(&failures).Error; err != nil {
+			return errors.New("ошибка при проверке попыток показа реквизитов")
+		}
+		if failures >= int64(s.config.CardRevealMaxAttempts) {
+			s.auditRevealWith(tx, cardID, userID, meta, false, "показ реквизитов заблокирован")
+			checkErr = ErrRevealLocked
+			return nil
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+			s.auditRevealWith(tx, cardID, userID, meta, false, revealWrongPassword)
+			checkErr = errors.New("не удалось подтвердить личность")
+			if failures+1 >= int64(s.config.CardRevealMaxAttempts) {
+				checkErr = ErrRevealLocked
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return checkErr
+}
+
+// auditReveal записывает попытку показа реквизитов в журнал
+func (s *CardService) auditReveal(cardID, userID uint, meta RequestMeta, success bool, reason string) {
+	s.auditRevealWith(s.db, cardID, userID, meta, success, reason)
+}
+
+// auditRevealWith записывает попытку показа реквизитов в журнал в переданной транзакции
+func (s *CardService) auditRevealWith(db *gorm.DB, cardID, userID uint, meta RequestMeta, success bool, reason string) {
+	audit := &models.CardRevealAudit{
+		CardID:    cardID,
+		UserID:    userID,
+		IP:        meta.IP,
+		UserAgent: truncate(meta.UserAgent, 255),
+		Success:   success,
+		Reason:    reason,
+	}
+	if err := db.Create(audit).Error; err != nil {
+		log.Printf("Ошибка записи в журнал показа реквизитов карты %d: %v", cardID, err)
+	}
+}
+
+// truncate обрезает строку до заданной длины в байтах
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// CardSchedulerService предоставляет фоновые задачи обслуживания карт
+type CardSchedulerService struct {
+	cardService *CardService
+}
+
+// NewCardSchedulerService создает новый экземпляр CardSchedulerService
+func NewCardSchedulerService(cardService *CardService) *CardSchedulerService {
+	return &CardSchedulerService{
+		cardService: cardService,
+	}
+}
+
+// Start запускает планировщик задач по картам
+func (s *CardSchedulerService) Start() {
+	// Проверяем сроки действия карт при запуске и затем раз в сутки
+	expiryTicker := time.NewTicker(24 * time.Hour)
+	go func() {
+		s.processExpiry()
+		for range expiryTicker.C {
+			s.processExpiry()
+		}
+	}()
+}
+
+// processExpiry обрабатывает сроки действия карт
+func (s *CardSchedulerService) processExpiry() {
+	expired, reissued, err := s.cardService.ProcessCardExpiry()
+	if err != nil {
+		log.Printf("Ошибка при обработке сроков действия карт: %v", err)
+		return
+	}
+	log.Printf("Карт с истекшим сроком: %d, перевыпущено карт: %d", expired, reissued)
+}
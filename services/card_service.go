@@ -3,17 +3,11 @@ package services
 import (
 	"awesomeProject/config"
 	"awesomeProject/models"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/rand"
 	"errors"
 	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/packet"
 	"gorm.io/gorm"
-	"io"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,83 +27,104 @@ type CardResponseDTO struct {
 	CVV        string `json:"cvv"`
 	Holder     string `json:"holder"`
 	Expiration string `json:"expiration"`
+	Status     string `json:"status"`
 	AccountID  uint   `json:"account_id"`
 	CreatedAt  string `json:"created_at"`
 	UpdatedAt  string `json:"updated_at"`
 }
 
+// maxCardNumberAttempts количество попыток сгенерировать уникальный номер карты
+const maxCardNumberAttempts = 10
+
 // CardService предоставляет методы для работы с картами
 type CardService struct {
 	db          *gorm.DB
 	config      *config.Config
+	keys        *CardKeyManager
+	numbers     *CardNumberGenerator
 	bankService *BankService
 	userService *UserService
+	email       *EmailService
+
+	// Канал доставки одноразовых кодов (по умолчанию email)
+	challengeNotifier ChallengeNotifier
+
+	// Последний просмотренный ID перешифрования по таблицам
+	rotationMu      sync.Mutex
+	rotationCursors map[string]uint
 }
 
 // NewCardService создает новый экземпляр CardService
-func NewCardService(db *gorm.DB, bankService *BankService, userService *UserService) (*CardService, error) {
-	cfg, err := config.NewConfig()
-	if err != nil {
-		return nil, err
-	}
-
+func NewCardService(db *gorm.DB, cfg *config.Config, keys *CardKeyManager, numbers *CardNumberGenerator, bankService *BankService, userService *UserService, email *EmailService) *CardService {
 	return &CardService{
-		db:          db,
-		config:      cfg,
-		bankService: bankService,
-		userService: userService,
-	}, nil
+		db:                db,
+		config:            cfg,
+		keys:              keys,
+		numbers:           numbers,
+		bankService:       bankService,
+		userService:       userService,
+		email:             email,
+		challengeNotifier: email,
+		rotationCursors:   make(map[string]uint),
+	}
 }
 
 // CreateCard создает новую карту
 func (s *CardService) CreateCard(dto CardDTO) (*CardResponseDTO, error) {
-	account, err := s.bankService.GetById(dto.AccountID)
+	// Выпускать карты к счету может владелец или совладелец
+	account, err := AuthorizeAccount(s.db, dto.UserID, dto.AccountID, models.AccountActionManage, 0)
 	if err != nil {
 		return nil, err
 	}
-
-	if account.HolderID != dto.UserID {
-		return nil, errors.New("банковский счет не принадлежит пользователю")
+	if account.Status != models.AccountStatusActive {
+		return nil, errors.New("карту можно выпустить только к действующему счету")
 	}
 
-	cardNumber := s.generateCardNumber()
+	card, err := s.newCard(dto.AccountID, s.config.CardSingleLimit, s.config.CardDailyLimit, models.CardStatusActive)
+	if err != nil {
+		return nil, err
+	}
 
-	if s.validateLuhn(cardNumber) {
-		return nil, errors.New("Номер карты не проходит проверку по алгоритму Луна")
+	if err := s.db.Create(card).Error; err != nil {
+		return nil, errors.New("не удалось создать карту")
 	}
 
-	expirationDate := calculateExpirationDate()
-	expirationStr := expirationDate.Format("01/06")
+	return s.cardToResponseDTO(card)
+}
 
-	hashedCVV, error := s.hashCVV(s.generateCVV())
-	if error != nil {
+// newCard генерирует реквизиты новой карты к счету. Карта не сохраняется в базе.
+func (s *CardService) newCard(accountID uint, singleLimit, dailyLimit float64, status models.CardStatus) (*models.Card, error) {
+	cardNumber, err := s.generateUniqueCardNumber()
+	if err != nil {
 		return nil, err
 	}
 
-	encryptedNumber, err := s.encryptData(cardNumber)
+	expirationDate := calculateExpirationDate()
+	expirationStr := expirationDate.Format("01/06")
+
+	cvv, err := s.generateCVV()
 	if err != nil {
-		return nil, errors.New("не удалось зашифровать номер карты")
+		return nil, err
 	}
 
-	encryptedExpiration, err := s.encryptData(expirationStr)
+	hashedCVV, err := s.hashCVV(cvv)
 	if err != nil {
-		return nil, errors.New("не удалось зашифровать дату истечения")
+		return nil, err
 	}
 
 	card := &models.Card{
-		NumberEncrypted:     encryptedNumber,
-		NumberHMAC:          s.calculateHMAC(cardNumber),
-		ExpirationEncrypted: encryptedExpiration,
-		ExpirationHMAC:      s.calculateHMAC(expirationStr),
-		CVV:                 hashedCVV,
-		AccountID:           dto.AccountID,
+		CVV:         hashedCVV,
+		AccountID:   accountID,
+		Status:      status,
+		SingleLimit: singleLimit,
+		DailyLimit:  dailyLimit,
 	}
 
-	if err := s.db.Create(card).Error; err != nil {
-		return nil, errors.New("не удалось создать карту")
+	if err := s.sealCard(card, cardNumber, expirationStr); err != nil {
+		return nil, err
 	}
 
-	return s.cardToResponseDTO(card)
+	return card, nil
 }
 
 // GetAllByUserID возвращает все карты пользователя
@@ -129,7 +144,7 @@ func (s *CardService) GetAllByUserID(userID uint) ([]CardResponseDTO, error) {
 	}
 
 	var cards []models.Card
-	if err := s.db.Where("account_id IN ?", accountIDs).Find(&cards).Error; err != nil {
+	if err := s.db.Preload("Account.Holder").Where("account_id IN ?", accountIDs).Find(&cards).Error; err != nil {
 		return nil, errors.New("не удалось получить карты")
 	}
 
@@ -148,49 +163,29 @@ func (s *CardService) GetAllByUserID(userID uint) ([]CardResponseDTO, error) {
 // Вспомогательные методы
 
 func (s *CardService) cardToResponseDTO(card *models.Card) (*CardResponseDTO, error) {
-	number, err := s.decryptData(card.NumberEncrypted)
-	if err != nil {
-		return nil, errors.New("не удалось расшифровать номер карты")
-	}
-
-	expiration, err := s.decryptData(card.ExpirationEncrypted)
-	if err != nil {
-		return nil, errors.New("не удалось расшифровать дату истечения")
+	// Для списка достаточно маскированного номера, полные реквизиты выдаются только через показ
+	number := card.NumberMasked
+	if number == "" {
+		decrypted, err := s.keys.Decrypt(card.KeyVersion, card.NumberEncrypted)
+		if err != nil {
+			return nil, errors.New("не удалось расшифровать номер карты")
+		}
+		number = maskCardNumber(decrypted)
 	}
 
 	return &CardResponseDTO{
 		ID:         card.ID,
-		Number:     maskCardNumber(number),
+		Number:     number,
 		CVV:        "***",
 		Holder:     card.Account.Holder.LastName + " " + card.Account.Holder.FirstName,
-		Expiration: expiration,
+		Expiration: "**/**",
+		Status:     string(card.Status),
 		AccountID:  card.AccountID,
 		CreatedAt:  card.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:  card.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}, nil
 }
 
-// decryptData расшифровывает данные с помощью PGP
-func (s *CardService) decryptData(encryptedData string) (string, error) {
-	entityList, err := openpgp.ReadArmoredKeyRing(strings.NewReader(s.config.CardPrivateKey))
-	if err != nil {
-		return "", err
-	}
-
-	buf := strings.NewReader(encryptedData)
-	md, err := openpgp.ReadMessage(buf, entityList, nil, &packet.Config{})
-	if err != nil {
-		return "", err
-	}
-
-	decrypted, err := io.ReadAll(md.UnverifiedBody)
-	if err != nil {
-		return "", err
-	}
-
-	return string(decrypted), nil
-}
-
 // maskCardNumber маскирует номер карты
 func maskCardNumber(number string) string {
 	if len(number) != 16 {
@@ -209,41 +204,46 @@ func calculateExpirationDate() time.Time {
 	return time.Date(expiration.Year(), expiration.Month()+1, 0, 0, 0, 0, 0, time.UTC)
 }
 
-// generateCardNumber генерирует номер карты
-func (s *CardService) generateCardNumber() string {
-	// Генерируем первые 15 цифр
-	number := ""
-	for i := 0; i < 15; i++ {
-		number += strconv.Itoa(int(time.Now().UnixNano() % 10))
+// cardExpiryTime возвращает момент окончания срока действия карты по строке "MM/YY".
+// Карта действует до конца указанного месяца включительно.
+func cardExpiryTime(expiration string) (time.Time, error) {
+	month, err := time.Parse("01/06", expiration)
+	if err != nil {
+		return time.Time{}, err
 	}
+	return month.AddDate(0, 1, 0), nil
+}
 
-	// Вычисляем контрольную сумму
-	sum := 0
-	for i := 0; i < len(number); i++ {
-		digit := int(number[i] - '0')
-		if i%2 == 0 {
-			digit *= 2
-			if digit > 9 {
-				digit -= 9
-			}
+// generateUniqueCardNumber генерирует номер карты, которого еще нет в базе.
+// Уникальность проверяется по HMAC-индексу во всех версиях ключа.
+func (s *CardService) generateUniqueCardNumber() (string, error) {
+	for attempt := 0; attempt < maxCardNumberAttempts; attempt++ {
+		cardNumber, err := s.numbers.Generate()
+		if err != nil {
+			return "", err
 		}
-		sum += digit
-	}
 
-	// Добавляем контрольную цифру
-	checkDigit := (10 - (sum % 10)) % 10
-	return number + strconv.Itoa(checkDigit)
-}
+		if !validateLuhn(cardNumber) {
+			return "", errors.New("номер карты не проходит проверку по алгоритму Луна")
+		}
 
-// generateCVV генерирует номер карты
-func (s *CardService) generateCVV() string {
-	// Генерируем первые 3 цифр
-	number := ""
-	for i := 0; i < 3; i++ {
-		number += strconv.Itoa(int(time.Now().UnixNano() % 10))
+		var count int64
+		if err := s.db.Unscoped().Model(&models.Card{}).
+			Where("number_hmac IN ?", s.keys.LookupHMACs(cardNumber)).
+			Count(&count).Error; err != nil {
+			return "", errors.New("ошибка при проверке уникальности номера карты")
+		}
+		if count == 0 {
+			return cardNumber, nil
+		}
 	}
 
-	return number
+	return "", errors.New("не удалось сгенерировать уникальный номер карты")
+}
+
+// generateCVV генерирует CVV код
+func (s *CardService) generateCVV() (string, error) {
+	return randomDigits(rand.Reader, 3)
 }
 
 // hashCVV хэширует CVV код
@@ -255,53 +255,51 @@ func (s *CardService) hashCVV(cvv string) (string, error) {
 	return string(hashedCVV), nil
 }
 
-// encryptData шифрует данные с помощью PGP
-func (s *CardService) encryptData(data string) (string, error) {
-	// Загружаем публичный ключ
-	entityList, err := openpgp.ReadArmoredKeyRing(strings.NewReader(s.config.CardPublicKey))
+// sealCard шифрует номер и срок действия карты активной версией ключа
+// и заполняет HMAC-индексы той же версией
+func (s *CardService) sealCard(card *models.Card, number, expiration string) error {
+	encryptedNumber, version, err := s.keys.Encrypt(number)
 	if err != nil {
-		return "", err
+		return errors.New("не удалось зашифровать номер карты")
 	}
 
-	// Создаем буфер для зашифрованных данных
-	var buf strings.Builder
-	w, err := openpgp.Encrypt(&buf, entityList, nil, nil, &packet.Config{})
+	encryptedExpiration, err := s.keys.EncryptWithVersion(version, expiration)
 	if err != nil {
-		return "", err
+		return errors.New("не удалось зашифровать дату истечения")
 	}
 
-	// Записываем данные
-	if _, err := w.Write([]byte(data)); err != nil {
-		return "", err
+	numberHMAC, err := s.keys.HMAC(version, number)
+	if err != nil {
+		return err
 	}
 
-	// Закрываем writer
-	if err := w.Close(); err != nil {
-		return "", err
+	expirationHMAC, err := s.keys.HMAC(version, expiration)
+	if err != nil {
+		return err
 	}
 
-	return buf.String(), nil
-}
-
-// calculateHMAC вычисляет HMAC для данных
-func (s *CardService) calculateHMAC(data string) string {
-	h := hmac.New(sha256.New, []byte(s.config.CardHMACKey))
-	h.Write([]byte(data))
-	return hex.EncodeToString(h.Sum(nil))
+	card.NumberEncrypted = encryptedNumber
+	card.NumberHMAC = numberHMAC
+	card.NumberMasked = maskCardNumber(number)
+	card.ExpirationEncrypted = encryptedExpiration
+	card.ExpirationHMAC = expirationHMAC
+	card.KeyVersion = version
+	if expiresAt, err := cardExpiryTime(expiration); err == nil {
+		card.ExpiresAt = &expiresAt
+	}
+	return nil
 }
 
-// validateLuhn проверяет номер карты по алгоритму Луна
-func (s *CardService) validateLuhn(number string) bool {
-	sum := 0
-	for i := 0; i < len(number); i++ {
-		digit := int(number[i] - '0')
-		if i%2 == 0 {
-			digit *= 2
-			if digit > 9 {
-				digit -= 9
-			}
+// FindByNumber ищет карту по номеру с учетом всех активных версий HMAC-ключа
+func (s *CardService) FindByNumber(number string) (*models.Card, error) {
+	var card models.Card
+	if err := s.db.Preload("Account.Holder").
+		Where("number_hmac IN ?", s.keys.LookupHMACs(number)).
+		First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("карта не найдена")
 		}
-		sum += digit
+		return nil, errors.New("ошибка при поиске карты")
 	}
-	return sum%10 == 0
+	return &card, nil
 }
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"time"
+)
+
+// CardTransferDTO представляет данные для перевода с карты на карту
+type CardTransferDTO struct {
+	SourceCardID      uint    `json:"source_card_id" validate:"required"`
+	DestinationNumber string  `json:"destination_number" validate:"required,len=16,numeric"`
+	Amount            float64 `json:"amount" validate:"required,gt=0"`
+	TOTPCode          string  `json:"totp_code" validate:"omitempty,min=6,max=11"` // Код 2FA для переводов выше порога
+}
+
+// CardTransferReceiptDTO представляет квитанцию о переводе с карты на карту
+type CardTransferReceiptDTO struct {
+	ID              uint    `json:"id"`
+	SourceCard      string  `json:"source_card"`
+	DestinationCard string  `json:"destination_card"`
+	Amount          float64 `json:"amount"`
+	Fee             float64 `json:"fee"`
+	Total           float64 `json:"total"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+// TransferByCard переводит средства с карты пользователя на любую карту банка по номеру.
+// Списание со счета отправителя, комиссия и зачисление на счет получателя
+// выполняются в одной транзакции базы данных.
+func (s *CardService) TransferByCard(userID uint, dto CardTransferDTO) (*CardTransferReceiptDTO, error) {
+	source, err := s.GetUserCard(userID, dto.SourceCardID, models.AccountActionSpend)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validateLuhn(dto.DestinationNumber) {
+		return nil, errors.New("неверный номер карты получателя")
+	}
+
+	// Неизвестная и недоступная карта дают одинаковый ответ, чтобы по нему нельзя было
+	// перебором узнать номера выпущенных карт банка
+	destination, err := s.FindByNumber(dto.DestinationNumber)
+	if err != nil {
+		return nil, ErrRecipientNotFound
+	}
+
+	if source.ID == destination.ID {
+		return nil, errors.New("нельзя перевести средства на ту же карту")
+	}
+
+	if source.Status != models.CardStatusActive {
+		return nil, errors.New("карта отправителя неактивна")
+	}
+	if destination.Status != models.CardStatusActive {
+		return nil, ErrRecipientNotFound
+	}
+
+	fee := s.calculateTransferFee(source, destination, dto.Amount)
+	transfer := &models.CardTransfer{
+		SourceCardID:      source.ID,
+		DestinationCardID: destination.ID,
+		Amount:            dto.Amount,
+		Fee:               fee,
+	}
+
+	sourceMasked := source.NumberMasked
+	destinationMasked := maskCardNumber(dto.DestinationNumber)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем оба счета в порядке возрастания ID, чтобы избежать взаимоблокировок
+		var accounts []models.BankAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{source.AccountID, destination.AccountID}).
+			Order("id ASC").
+			Find(&accounts).Error; err != nil {
+			return errors.New("ошибка при поиске банковских счетов")
+		}
+
+		var sourceAccount, destinationAccount *models.BankAccount
+		for i := range accounts {
+			if accounts[i].ID == source.AccountID {
+				sourceAccount = &accounts[i]
+			}
+			if accounts[i].ID == destination.AccountID {
+				destinationAccount = &accounts[i]
+			}
+		}
+		if sourceAccount == nil || destinationAccount == nil {
+			return errors.New("банковский счет не найден")
+		}
+
+		total := dto.Amount + fee
+
+		// Лимиты проверяются под блокировкой счета, чтобы параллельные переводы
+		// по карте не превысили суточный лимит вместе
+		if err := s.checkCardLimits(tx, source, dto.Amount); err != nil {
+			return err
+		}
+
+		// Доверенное лицо с ролью SPENDER ограничено дневным лимитом списаний
+		if _, err := AuthorizeAccount(tx, userID, sourceAccount.ID, models.AccountActionSpend, total); err != nil {
+			return err
+		}
+
+		if err := checkAccountDebit(sourceAccount); err != nil {
+			return err
+		}
+		if err := chargeSpendLimit(tx, userID, sourceAccount, total); err != nil {
+			return err
+		}
+		if err := checkAccountCredit(destinationAccount); err != nil {
+			return ErrRecipientNotFound
+		}
+
+		if sourceAccount.Available() < total {
+			return ErrInsufficientFunds
+		}
+
+		// Перевод другому клиенту не должен превышать лимит остатка получателя
+		if sourceAccount.HolderID != destinationAccount.HolderID {
+			if err := checkBalanceLimit(tx, destinationAccount.HolderID, dto.Amount); err != nil {
+				if errors.Is(err, ErrKYCLimitExceeded) {
+					return errors.New("перевод превышает лимит остатка получателя")
+				}
+				return err
+			}
+		}
+
+		now := time.Now()
+		sourceBalanceBefore := sourceAccount.Balance
+		destinationBalanceBefore := destinationAccount.Balance
+		feeBalanceBefore := sourceBalanceBefore - dto.Amount
+
+		// Перевод между картами одного счета не меняет его баланс, кроме комиссии:
+		// зачисление и комиссия отражаются от баланса после предыдущей проводки
+		if destinationAccount.ID == sourceAccount.ID {
+			destinationAccount = sourceAccount
+			destinationBalanceBefore = sourceBalanceBefore - dto.Amount
+			feeBalanceBefore = sourceBalanceBefore
+		}
+
+		sourceAccount.Balance -= total
+		sourceAccount.UpdatedAt = now
+		destinationAccount.Balance += dto.Amount
+		destinationAccount.UpdatedAt = now
+
+		if err := tx.Save(sourceAccount).Error; err != nil {
+			return errors.New("ошибка при обновлении баланса")
+		}
+		if destinationAccount != sourceAccount {
+			if err := tx.Save(destinationAccount).Error; err != nil {
+				return errors.New("ошибка при обновлении баланса")
+			}
+		}
+
+		transactions := []models.Transaction{
+			{
+				AccountID:     sourceAccount.ID,
+				Amount:        dto.Amount,
+				Type:          string(TransactionTypeTransfer),
+				BalanceBefore: sourceBalanceBefore,
+				BalanceAfter:  sourceBalanceBefore - dto.Amount,
+				Description:   "Card transfer to " + destinationMasked,
+			},
+			{
+				AccountID:     destinationAccount.ID,
+				Amount:        dto.Amount,
+				Type:          string(TransactionTypeTransfer),
+				BalanceBefore: destinationBalanceBefore,
+				BalanceAfter:  destinationBalanceBefore + dto.Amount,
+				Description:   "Card transfer from " + sourceMasked,
+			},
+		}
+		if fee > 0 {
+			transactions = append(transactions, models.Transaction{
+				AccountID:     sourceAccount.ID,
+				Amount:        fee,
+				Type:          string(TransactionTypeFee),
+				BalanceBefore: feeBalanceBefore,
+				BalanceAfter:  feeBalanceBefore - fee,
+				Description:   "Card transfer fee",
+			})
+		}
+		if err := tx.Create(&transactions).Error; err != nil {
+			return errors.New("ошибка при сохранении транзакции")
+		}
+
+		if err := tx.Create(transfer).Error; err != nil {
+			return errors.New("ошибка при сохранении перевода")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordPayeeUsage(s.db, s.keys, userID, payeeDetails{Kind: models.PayeeKindCard, CardNumber: dto.DestinationNumber})
+
+	return &CardTransferReceiptDTO{
+		ID:              transfer.ID,
+		SourceCard:      sourceMasked,
+		DestinationCard: destinationMasked,
+		Amount:          dto.Amount,
+		Fee:             fee,
+		Total:           dto.Amount + fee,
+		CreatedAt:       transfer.CreatedAt.Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// checkCardLimits проверяет разовый и суточный лимиты карты. В суточный лимит входят
+// переводы с карты и одобренные авторизации по ней.
+func (s *CardService) checkCardLimits(db *gorm.DB, card *models.Card, amount float64) error {
+	singleLimit := card.SingleLimit
+	if singleLimit <= 0 {
+		singleLimit = s.config.CardSingleLimit
+	}
+	if amount > singleLimit {
+		return errors.New("превышен лимит карты на одну операцию")
+	}
+
+	dailyLimit := card.DailyLimit
+	if dailyLimit <= 0 {
+		dailyLimit = s.config.CardDailyLimit
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var transferred, authorized float64
+	if err := db.Model(&models.CardTransfer{}).
+		Where("source_card_id = ? AND created_at >= ?", card.ID, dayStart).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&transferred).Error; err != nil {
+		return errors.New("ошибка при проверке лимитов карты")
+	}
+	if err := db.Model(&models.CardAuthorization{}).
+		Where("card_id = ? AND status = ? AND created_at >= ?", card.ID, models.CardAuthorizationApproved, dayStart).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&authorized).Error; err != nil {
+		return errors.New("ошибка при проверке лимитов карты")
+	}
+
+	if transferred+authorized+amount > dailyLimit {
+		return errors.New("превышен суточный лимит карты")
+	}
+
+	return nil
+}
+
+// calculateTransferFee рассчитывает комиссию за перевод.
+// Переводы между картами одного владельца бесплатны, на чужие карты —
+// процент от суммы в пределах минимальной и максимальной комиссии.
+func (s *CardService) calculateTransferFee(source, destination *models.Card, amount float64) float64 {
+	if source.Account.HolderID == destination.Account.HolderID {
+		return 0
+	}
+
+	fee := amount * s.config.CardTransferFeePercent / 100
+	fee = math.Max(fee, s.config.CardTransferMinFee)
+	fee = math.Min(fee, s.config.CardTransferMaxFee)
+	return math.Round(fee*100) / 100
+}
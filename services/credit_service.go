@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
 	"math"
 	"strings"
@@ -55,6 +56,7 @@ type PayCreditDTO struct {
 	Amount    float64 `json:"amount" validate:"required,gt=0"`
 	AccountID uint    `json:"account_id" validate:"required"`
 	CreditID  uint    `json:"-"`
+	UserID    uint    `json:"-"` // Плательщик, для учета дневного лимита участника SPENDER
 }
 
 // CreditService предоставляет методы для работы с кредитами
@@ -73,6 +75,11 @@ func NewCreditService(db *gorm.DB, email *EmailService) *CreditService {
 	}
 }
 
+// GetDB возвращает экземпляр базы данных
+func (s *CreditService) GetDB() *gorm.DB {
+	return s.db
+}
+
 // calculateAnnuityPayment рассчитывает размер аннуитетного платежа
 func (s *CreditService) calculateAnnuityPayment(amount float64, rate float64, months int) float64 {
 	// Конвертируем годовую ставку в месячную (в долях)
@@ -165,18 +172,30 @@ func (s *CreditService) Create(dto CreateCreditDTO) (*CreditResponseDTO, error)
 		return nil, errors.New("ошибка при начале транзакции")
 	}
 
-	// Проверяем существование счета
+	// Оформить кредит на счет может владелец или совладелец
+	if _, err := AuthorizeAccount(tx, dto.UserID, dto.AccountID, models.AccountActionManage, 0); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	var account models.BankAccount
-	if err := tx.Preload("Holder").First(&account, dto.AccountID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Holder").First(&account, dto.AccountID).Error; err != nil {
 		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("банковский счет не найден")
-		}
 		return nil, errors.New("ошибка при поиске банковского счета")
 	}
+	if account.Status != models.AccountStatusActive {
+		tx.Rollback()
+		return nil, errors.New("кредит можно оформить только на действующий счет")
+	}
 
-	if account.HolderID != dto.UserID {
-		return nil, errors.New("Access denied")
+	// Кредиты выдаются только клиентам с полной идентификацией
+	var borrower models.User
+	if err := tx.Select("id", "kyc_level").First(&borrower, dto.UserID).Error; err != nil {
+		tx.Rollback()
+		return nil, errors.New("пользователь не найден")
+	}
+	if !borrower.KYCLevel.CanTakeCredit() {
+		tx.Rollback()
+		return nil, ErrKYCCreditNotAllowed
 	}
 
 	// Проверяем, нет ли уже активного кредита
@@ -280,10 +299,10 @@ func (s *CreditService) Create(dto CreateCreditDTO) (*CreditResponseDTO, error)
 	return response, nil
 }
 
-// GetCreditsByUserID возвращает все кредиты пользователя
+// GetCreditsByUserID возвращает кредиты по всем счетам, доступным пользователю
 func (s *CreditService) GetCreditsByUserID(userID uint) ([]models.Credit, error) {
 	var credits []models.Credit
-	if err := s.db.Where("user_id = ?", userID).
+	if err := s.db.Where("account_id IN (?)", accessibleAccountIDs(s.db, userID)).
 		Preload("Account.Holder").
 		Preload("Payments", func(db *gorm.DB) *gorm.DB {
 			return db.Order("payments.created_at DESC")
@@ -412,15 +431,23 @@ func (s *CreditService) PayCredit(dto PayCreditDTO) (*PaymentDTO, error) {
 
 	// Получаем счет
 	var account models.BankAccount
-	if err := tx.Preload("Holder").First(&account, dto.AccountID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Holder").First(&account, dto.AccountID).Error; err != nil {
 		tx.Rollback()
 		return nil, errors.New("счет не найден")
 	}
+	if err := checkAccountDebit(&account); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := chargeSpendLimit(tx, dto.UserID, &account, dto.Amount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	// Проверяем достаточность средств
-	if account.Balance < dto.Amount {
+	if account.Available() < dto.Amount {
 		tx.Rollback()
-		return nil, errors.New("недостаточно средств на счете")
+		return nil, ErrInsufficientFunds
 	}
 
 	// Находим следующий платеж
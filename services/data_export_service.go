@@ -0,0 +1,509 @@
+package services
+
+import (
+	"archive/zip"
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// dataExportCooldown как часто пользователь может запрашивать выгрузку
+const dataExportCooldown = 24 * time.Hour
+
+// ErrDataExportNotFound возвращается при неизвестной или просроченной ссылке на выгрузку
+var ErrDataExportNotFound = errors.New("ссылка на выгрузку недействительна или срок ее действия истек")
+
+// ErrDataExportTooSoon возвращается, если с предыдущей выгрузки не прошли сутки
+var ErrDataExportTooSoon = errors.New("выгрузку можно запрашивать не чаще раза в сутки, ссылка на предыдущую отправлена на email")
+
+// DataExportDTO представляет запрос на выгрузку персональных данных
+type DataExportDTO struct {
+	ID        uint       `json:"id"`
+	Status    string     `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DataExportFile представляет готовый архив для скачивания
+type DataExportFile struct {
+	Path     string
+	FileName string
+}
+
+// exportSection представляет один раздел выгрузки: он записывается в архив
+// дважды, как JSON для машинной обработки и как CSV для просмотра в таблицах
+type exportSection struct {
+	name    string
+	rows    interface{}
+	header  []string
+	records [][]string
+}
+
+// DataExportService собирает архив со всеми данными пользователя по его запросу
+// и отправляет на email ссылку для скачивания с ограниченным сроком действия
+type DataExportService struct {
+	db     *gorm.DB
+	config *config.Config
+	email  *EmailService
+}
+
+// NewDataExportService создает новый экземпляр DataExportService
+func NewDataExportService(db *gorm.DB, cfg *config.Config, email *EmailService) *DataExportService {
+	return &DataExportService{
+		db:     db,
+		config: cfg,
+		email:  email,
+	}
+}
+
+// Request создает запрос на выгрузку и запускает сборку архива в фоне.
+// Новую выгрузку можно запросить не чаще раза в сутки.
+func (s *DataExportService) Request(userID uint) (*DataExportDTO, error) {
+	var recent int64
+	if err := s.db.Model(&models.DataExport{}).
+		Where("user_id = ? AND status <> ? AND created_at > ?", userID, models.DataExportStatusFailed, time.Now().Add(-dataExportCooldown)).
+		Count(&recent).Error; err != nil {
+		return nil, errors.New("ошибка при проверке выгрузок")
+	}
+	if recent > 0 {
+		return nil, ErrDataExportTooSoon
+	}
+
+	export := &models.DataExport{
+		UserID: userID,
+		Status: models.DataExportStatusPending,
+	}
+	if err := s.db.Create(export).Error; err != nil {
+		return nil, errors.New("не удалось создать запрос на выгрузку")
+	}
+
+	go s.build(export.ID)
+
+	return toDataExportDTO(*export), nil
+}
+
+// Download проверяет токен ссылки и возвращает готовый архив
+func (s *DataExportService) Download(token string) (*DataExportFile, error) {
+	if token == "" {
+		return nil, ErrDataExportNotFound
+	}
+
+	var export models.DataExport
+	if err := s.db.Where("token_hash = ? AND status = ?", utils.HashToken(token), models.DataExportStatusReady).
+		First(&export).Error; err != nil {
+		return nil, ErrDataExportNotFound
+	}
+	if export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, ErrDataExportNotFound
+	}
+
+	// После закрытия профиля выгрузка больше не выдается
+	var user models.User
+	if err := s.db.Select("id", "closed_at").First(&user, export.UserID).Error; err != nil || user.ClosedAt != nil {
+		return nil, ErrDataExportNotFound
+	}
+
+	return &DataExportFile{
+		Path:     export.FilePath,
+		FileName: fmt.Sprintf("data-export-%s.zip", export.CreatedAt.Format("2006-01-02")),
+	}, nil
+}
+
+// CleanupExpired удаляет архивы с истекшим сроком ссылки
+func (s *DataExportService) CleanupExpired() (int, error) {
+	var exports []models.DataExport
+	if err := s.db.Where("status = ? AND expires_at < ?", models.DataExportStatusReady, time.Now()).
+		Find(&exports).Error; err != nil {
+		return 0, err
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Ошибка удаления архива выгрузки %d: %v", export.ID, err)
+			continue
+		}
+		if err := s.db.Model(&export).Updates(map[string]interface{}{
+			"status":     models.DataExportStatusExpired,
+			"token_hash": "",
+			"file_path":  "",
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(exports), nil
+}
+
+// ResumePending заново запускает сборку выгрузок, оставшихся в статусе PENDING
+// после остановки сервиса. Без этого такие запросы висели бы до конца суток
+// и не давали запросить новую выгрузку.
+func (s *DataExportService) ResumePending() (int, error) {
+	var exports []models.DataExport
+	if err := s.db.Select("id").Where("status = ?", models.DataExportStatusPending).
+		Find(&exports).Error; err != nil {
+		return 0, errors.New("ошибка при получении незавершенных выгрузок")
+	}
+
+	for _, export := range exports {
+		go s.build(export.ID)
+	}
+	return len(exports), nil
+}
+
+// StartCleanup запускает фоновое удаление просроченных архивов
+func (s *DataExportService) StartCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				count, err := s.CleanupExpired()
+				if err != nil {
+					log.Printf("Ошибка при удалении просроченных выгрузок: %v", err)
+					continue
+				}
+				if count > 0 {
+					log.Printf("Удалено просроченных выгрузок: %d", count)
+				}
+			}
+		}
+	}()
+}
+
+// build собирает архив, сохраняет его и отправляет пользователю ссылку
+func (s *DataExportService) build(exportID uint) {
+	var export models.DataExport
+	if err := s.db.First(&export, exportID).Error; err != nil {
+		log.Printf("Выгрузка %d не найдена: %v", exportID, err)
+		return
+	}
+
+	var user models.User
+	if err := s.db.First(&user, export.UserID).Error; err != nil {
+		s.fail(&export, err)
+		return
+	}
+
+	sections, err := s.collect(user)
+	if err != nil {
+		s.fail(&export, err)
+		return
+	}
+
+	if err := os.MkdirAll(s.config.ExportDir, 0o700); err != nil {
+		s.fail(&export, err)
+		return
+	}
+	path := filepath.Join(s.config.ExportDir, fmt.Sprintf("export-%d.zip", export.ID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		s.fail(&export, err)
+		return
+	}
+	if err := writeExportArchive(file, sections); err != nil {
+		file.Close()
+		os.Remove(path)
+		s.fail(&export, err)
+		return
+	}
+	info, statErr := file.Stat()
+	if err := file.Close(); err != nil || statErr != nil {
+		os.Remove(path)
+		s.fail(&export, errors.Join(statErr, err))
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		os.Remove(path)
+		s.fail(&export, err)
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(s.config.ExportLinkTTL) * time.Hour)
+	if err := s.db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.DataExportStatusReady,
+		"token_hash":   utils.HashToken(token),
+		"file_path":    path,
+		"size":         info.Size(),
+		"expires_at":   expiresAt,
+		"completed_at": now,
+	}).Error; err != nil {
+		os.Remove(path)
+		s.fail(&export, err)
+		return
+	}
+
+	link := s.config.Server.BaseURL + "/api/me/export/download?token=" + url.QueryEscape(token)
+	if err := s.email.SendDataExportReady(user.Email, link, expiresAt); err != nil {
+		log.Printf("Ошибка отправки ссылки на выгрузку %d: %v", export.ID, err)
+	}
+}
+
+// fail помечает выгрузку неудачной
+func (s *DataExportService) fail(export *models.DataExport, cause error) {
+	log.Printf("Ошибка сборки выгрузки %d: %v", export.ID, cause)
+	if err := s.db.Model(export).Update("status", models.DataExportStatusFailed).Error; err != nil {
+		log.Printf("Ошибка обновления статуса выгрузки %d: %v", export.ID, err)
+	}
+}
+
+// collect собирает все разделы выгрузки пользователя
+func (s *DataExportService) collect(user models.User) ([]exportSection, error) {
+	profile := toProfileDTO(user)
+
+	var accounts []models.BankAccount
+	if err := s.db.Where("holder_id = ?", user.ID).Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	accountIDs := make([]uint, len(accounts))
+	for i, account := range accounts {
+		accountIDs[i] = account.ID
+	}
+
+	var transactions []models.Transaction
+	if err := s.db.Where("account_id IN ?", accountIDs).Order("created_at, id").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	var cards []models.Card
+	if err := s.db.Where("account_id IN ?", accountIDs).Order("id").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+
+	var credits []models.Credit
+	if err := s.db.Where("account_id IN ?", accountIDs).Order("id").Find(&credits).Error; err != nil {
+		return nil, err
+	}
+	creditIDs := make([]uint, len(credits))
+	for i, credit := range credits {
+		creditIDs[i] = credit.ID
+	}
+
+	var payments []models.Payment
+	if err := s.db.Where("credit_id IN ?", creditIDs).Order("credit_id, pay_date").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	var notifications []models.Notification
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	var signIns []models.SignInEvent
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&signIns).Error; err != nil {
+		return nil, err
+	}
+
+	return buildExportSections(profile, accounts, transactions, cards, credits, payments, notifications, signIns), nil
+}
+
+// buildExportSections раскладывает данные пользователя по разделам выгрузки.
+// Номера карт выгружаются только в маскированном виде, CVV и PIN не выгружаются.
+func buildExportSections(
+	profile *ProfileDTO,
+	accounts []models.BankAccount,
+	transactions []models.Transaction,
+	cards []models.Card,
+	credits []models.Credit,
+	payments []models.Payment,
+	notifications []models.Notification,
+	signIns []models.SignInEvent,
+) []exportSection {
+	type accountRow struct {
+		ID        uint      `json:"id"`
+		Number    string    `json:"number"`
+		Bank      string    `json:"bank"`
+		Title     string    `json:"title"`
+		Balance   float64   `json:"balance"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	type transactionRow struct {
+		ID          uint      `json:"id"`
+		AccountID   uint      `json:"account_id"`
+		Type        string    `json:"type"`
+		Amount      float64   `json:"amount"`
+		Description string    `json:"description"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	type cardRow struct {
+		ID          uint      `json:"id"`
+		AccountID   uint      `json:"account_id"`
+		Number      string    `json:"number"`
+		Status      string    `json:"status"`
+		SingleLimit float64   `json:"single_limit"`
+		DailyLimit  float64   `json:"daily_limit"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	type creditRow struct {
+		ID        uint      `json:"id"`
+		AccountID uint      `json:"account_id"`
+		Amount    float64   `json:"amount"`
+		Rate      float64   `json:"rate"`
+		Status    string    `json:"status"`
+		StartDate time.Time `json:"start_date"`
+		EndDate   time.Time `json:"end_date"`
+	}
+	type paymentRow struct {
+		ID          uint       `json:"id"`
+		CreditID    uint       `json:"credit_id"`
+		PayDate     time.Time  `json:"pay_date"`
+		Amount      float64    `json:"amount"`
+		Status      string     `json:"status"`
+		RealPayDate *time.Time `json:"real_pay_date,omitempty"`
+	}
+	type notificationRow struct {
+		Recipient string    `json:"recipient"`
+		Subject   string    `json:"subject"`
+		Status    string    `json:"status"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	money := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+	id := func(value uint) string {
+		return strconv.FormatUint(uint64(value), 10)
+	}
+	timestamp := func(value time.Time) string {
+		return value.Format(time.RFC3339)
+	}
+
+	profileSection := exportSection{
+		name:   "profile",
+		rows:   profile,
+		header: []string{"id", "first_name", "last_name", "email", "phone", "email_verified", "created_at"},
+		records: [][]string{{
+			id(profile.ID), profile.FirstName, profile.LastName, profile.Email, profile.Phone,
+			strconv.FormatBool(profile.EmailVerified), timestamp(profile.CreatedAt),
+		}},
+	}
+
+	accountRows := make([]accountRow, len(accounts))
+	accountRecords := make([][]string, len(accounts))
+	for i, account := range accounts {
+		accountRows[i] = accountRow{account.ID, account.Number, account.Bank, account.Title, account.Balance, account.CreatedAt}
+		accountRecords[i] = []string{id(account.ID), account.Number, account.Bank, account.Title, money(account.Balance), timestamp(account.CreatedAt)}
+	}
+
+	transactionRows := make([]transactionRow, len(transactions))
+	transactionRecords := make([][]string, len(transactions))
+	for i, transaction := range transactions {
+		transactionRows[i] = transactionRow{transaction.ID, transaction.AccountID, transaction.Type, transaction.Amount, transaction.Description, transaction.CreatedAt}
+		transactionRecords[i] = []string{
+			id(transaction.ID), id(transaction.AccountID), transaction.Type, money(transaction.Amount),
+			transaction.Description, timestamp(transaction.CreatedAt),
+		}
+	}
+
+	cardRows := make([]cardRow, len(cards))
+	cardRecords := make([][]string, len(cards))
+	for i, card := range cards {
+		cardRows[i] = cardRow{card.ID, card.AccountID, card.NumberMasked, string(card.Status), card.SingleLimit, card.DailyLimit, card.CreatedAt}
+		cardRecords[i] = []string{
+			id(card.ID), id(card.AccountID), card.NumberMasked, string(card.Status),
+			money(card.SingleLimit), money(card.DailyLimit), timestamp(card.CreatedAt),
+		}
+	}
+
+	creditRows := make([]creditRow, len(credits))
+	creditRecords := make([][]string, len(credits))
+	for i, credit := range credits {
+		creditRows[i] = creditRow{credit.ID, credit.AccountID, credit.Amount, credit.Rate, string(credit.Status), credit.StartDate, credit.EndDate}
+		creditRecords[i] = []string{
+			id(credit.ID), id(credit.AccountID), money(credit.Amount), strconv.FormatFloat(credit.Rate, 'f', -1, 64),
+			string(credit.Status), timestamp(credit.StartDate), timestamp(credit.EndDate),
+		}
+	}
+
+	paymentRows := make([]paymentRow, len(payments))
+	paymentRecords := make([][]string, len(payments))
+	for i, payment := range payments {
+		paymentRows[i] = paymentRow{payment.ID, payment.CreditID, payment.PayDate, payment.Amount, string(payment.Status), payment.RealPayDate}
+		realPayDate := ""
+		if payment.RealPayDate != nil {
+			realPayDate = timestamp(*payment.RealPayDate)
+		}
+		paymentRecords[i] = []string{
+			id(payment.ID), id(payment.CreditID), timestamp(payment.PayDate), money(payment.Amount),
+			string(payment.Status), realPayDate,
+		}
+	}
+
+	notificationRows := make([]notificationRow, len(notifications))
+	notificationRecords := make([][]string, len(notifications))
+	for i, notification := range notifications {
+		notificationRows[i] = notificationRow{notification.Recipient, notification.Subject, string(notification.Status), notification.CreatedAt}
+		notificationRecords[i] = []string{notification.Recipient, notification.Subject, string(notification.Status), timestamp(notification.CreatedAt)}
+	}
+
+	signInRows := make([]SignInEventDTO, len(signIns))
+	signInRecords := make([][]string, len(signIns))
+	for i, event := range signIns {
+		signInRows[i] = SignInEventDTO{event.ID, event.IP, event.UserAgent, string(event.Outcome), event.CreatedAt}
+		signInRecords[i] = []string{id(event.ID), event.IP, event.UserAgent, string(event.Outcome), timestamp(event.CreatedAt)}
+	}
+
+	return []exportSection{
+		profileSection,
+		{"accounts", accountRows, []string{"id", "number", "bank", "title", "balance", "created_at"}, accountRecords},
+		{"transactions", transactionRows, []string{"id", "account_id", "type", "amount", "description", "created_at"}, transactionRecords},
+		{"cards", cardRows, []string{"id", "account_id", "number", "status", "single_limit", "daily_limit", "created_at"}, cardRecords},
+		{"credits", creditRows, []string{"id", "account_id", "amount", "rate", "status", "start_date", "end_date"}, creditRecords},
+		{"payments", paymentRows, []string{"id", "credit_id", "pay_date", "amount", "status", "real_pay_date"}, paymentRecords},
+		{"notifications", notificationRows, []string{"recipient", "subject", "status", "created_at"}, notificationRecords},
+		{"sign_ins", signInRows, []string{"id", "ip", "user_agent", "outcome", "created_at"}, signInRecords},
+	}
+}
+
+// writeExportArchive записывает разделы в ZIP-архив: каждый раздел в файлы <раздел>.json и <раздел>.csv
+func writeExportArchive(w io.Writer, sections []exportSection) error {
+	archive := zip.NewWriter(w)
+
+	for _, section := range sections {
+		jsonFile, err := archive.Create(section.name + ".json")
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(jsonFile)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.rows); err != nil {
+			return err
+		}
+
+		csvFile, err := archive.Create(section.name + ".csv")
+		if err != nil {
+			return err
+		}
+		writer := csv.NewWriter(csvFile)
+		if err := writer.Write(section.header); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(section.records); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func toDataExportDTO(export models.DataExport) *DataExportDTO {
+	return &DataExportDTO{
+		ID:        export.ID,
+		Status:    string(export.Status),
+		ExpiresAt: export.ExpiresAt,
+		CreatedAt: export.CreatedAt,
+	}
+}
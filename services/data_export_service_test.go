@@ -0,0 +1,91 @@
+package services
+
+import (
+	"archive/zip"
+	"awesomeProject/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteExportArchive(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	profile := &ProfileDTO{ID: 7, FirstName: "Иван", LastName: "Петров", Email: "ivan@example.com", CreatedAt: createdAt}
+	accounts := []models.BankAccount{{ID: 3, Number: "40817810000000000001", Bank: "Go Bank", Title: "Основной", Balance: 1500.5, CreatedAt: createdAt}}
+	cards := []models.Card{{
+		NumberEncrypted: "зашифрованный номер",
+		NumberMasked:    "2200 **** **** 0004",
+		CVV:             "хэш CVV",
+		PINHash:         "хэш PIN",
+		Status:          models.CardStatusActive,
+		AccountID:       3,
+	}}
+	transactions := []models.Transaction{{ID: 1, AccountID: 3, Type: "DEPOSIT", Amount: 1500.5, Description: "ATM, \"касса\"", CreatedAt: createdAt}}
+
+	sections := buildExportSections(profile, accounts, transactions, cards, nil, nil, nil, nil)
+
+	var buffer bytes.Buffer
+	if err := writeExportArchive(&buffer, sections); err != nil {
+		t.Fatalf("writeExportArchive() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		files[file.Name] = string(content)
+	}
+
+	for _, name := range []string{"profile", "accounts", "transactions", "cards", "credits", "payments", "notifications", "sign_ins"} {
+		for _, ext := range []string{".json", ".csv"} {
+			if _, ok := files[name+ext]; !ok {
+				t.Errorf("archive is missing %s%s", name, ext)
+			}
+		}
+	}
+
+	// Пустые разделы выгружаются как пустой массив, а не null
+	if strings.TrimSpace(files["credits.json"]) != "[]" {
+		t.Errorf("credits.json = %q, want []", files["credits.json"])
+	}
+
+	var accountRows []map[string]interface{}
+	if err := json.Unmarshal([]byte(files["accounts.json"]), &accountRows); err != nil {
+		t.Fatalf("accounts.json: %v", err)
+	}
+	if len(accountRows) != 1 || accountRows[0]["number"] != "40817810000000000001" {
+		t.Errorf("accounts.json = %v", accountRows)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(files["transactions.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("transactions.csv: %v", err)
+	}
+	if len(records) != 2 || records[1][3] != "1500.50" || records[1][4] != "ATM, \"касса\"" {
+		t.Errorf("transactions.csv = %v", records)
+	}
+
+	// Номер карты выгружается только маскированным, секреты карты не попадают в архив
+	for _, name := range []string{"cards.json", "cards.csv"} {
+		if !strings.Contains(files[name], "2200 **** **** 0004") {
+			t.Errorf("%s does not contain masked number", name)
+		}
+		for _, secret := range []string{"зашифрованный номер", "хэш CVV", "хэш PIN"} {
+			if strings.Contains(files[name], secret) {
+				t.Errorf("%s contains %q", name, secret)
+			}
+		}
+	}
+}
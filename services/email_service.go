@@ -2,8 +2,12 @@ package services
 
 import (
 	"awesomeProject/config"
+	"awesomeProject/models"
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+	"html"
+	"log"
 	"time"
 )
 
@@ -12,10 +16,12 @@ type EmailService struct {
 	dialer *gomail.Dialer
 	from   string
 	config *config.Config
+	db     *gorm.DB
 }
 
-// NewEmailService создает новый экземпляр EmailService
-func NewEmailService(cfg *config.Config) *EmailService {
+// NewEmailService создает новый экземпляр EmailService.
+// Отправленные письма записываются в историю уведомлений пользователя.
+func NewEmailService(cfg *config.Config, db *gorm.DB) *EmailService {
 	dialer := gomail.NewDialer(
 		cfg.SMTP.Host,
 		cfg.SMTP.Port,
@@ -27,11 +33,19 @@ func NewEmailService(cfg *config.Config) *EmailService {
 		dialer: dialer,
 		from:   cfg.SMTP.From,
 		config: cfg,
+		db:     db,
 	}
 }
 
-// SendEmail отправляет email
+// SendEmail отправляет email. В историю уведомлений письмо попадает к пользователю
+// с этим email.
 func (s *EmailService) SendEmail(to, subject, body string) error {
+	return s.send(nil, to, subject, body)
+}
+
+// send отправляет email и записывает его в историю уведомлений пользователя userID,
+// а если он не указан — пользователя с адресом to
+func (s *EmailService) send(userID *uint, to, subject, body string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", s.from)
 	m.SetHeader("To", to)
@@ -39,12 +53,40 @@ func (s *EmailService) SendEmail(to, subject, body string) error {
 	m.SetBody("text/html", body)
 
 	if err := s.dialer.DialAndSend(m); err != nil {
+		s.recordNotification(userID, to, subject, models.NotificationStatusFailed)
 		return fmt.Errorf("ошибка отправки email: %v", err)
 	}
 
+	s.recordNotification(userID, to, subject, models.NotificationStatusSent)
 	return nil
 }
 
+// recordNotification записывает письмо в историю уведомлений. Без userID письмо
+// относится к пользователю с текущим email to: адрес в pending_email может ожидать
+// подтверждения сразу у нескольких пользователей.
+func (s *EmailService) recordNotification(userID *uint, to, subject string, status models.NotificationStatus) {
+	if s.db == nil {
+		return
+	}
+
+	notification := &models.Notification{
+		Recipient: to,
+		Subject:   subject,
+		Status:    status,
+		UserID:    userID,
+	}
+	if userID == nil {
+		var user models.User
+		if err := s.db.Select("id").Where("email = ?", to).First(&user).Error; err == nil {
+			notification.UserID = &user.ID
+		}
+	}
+
+	if err := s.db.Create(notification).Error; err != nil {
+		log.Printf("Ошибка записи в историю уведомлений: %v", err)
+	}
+}
+
 // SendTransactionNotification отправляет уведомление о транзакции
 func (s *EmailService) SendTransactionNotification(to, accountNumber string, amount float64, transactionType string) error {
 	subject := "Уведомление о транзакции"
@@ -101,3 +143,218 @@ func (s *EmailService) SendCreditPaidNotification(email string, creditID uint) e
 
 	return nil
 }
+
+// SendCardReissuedNotification отправляет уведомление о перевыпуске карты
+func (s *EmailService) SendCardReissuedNotification(to, oldCardNumber, newCardNumber string, expiresAt time.Time) error {
+	subject := "Ваша карта перевыпущена"
+	body := fmt.Sprintf(`
+		<h2>Ваша карта перевыпущена</h2>
+		<p>Срок действия карты %s истекает %s.</p>
+		<p>Мы выпустили новую карту %s к тому же счету с теми же лимитами.</p>
+		<p>Старая карта продолжит работать до активации новой.</p>
+	`, oldCardNumber, expiresAt.Format("02.01.2006"), newCardNumber)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendCardExpiredNotification отправляет уведомление об истечении срока действия карты
+func (s *EmailService) SendCardExpiredNotification(to, cardNumber string) error {
+	subject := "Срок действия карты истек"
+	body := fmt.Sprintf(`
+		<h2>Срок действия карты истек</h2>
+		<p>Карта %s больше не обслуживается.</p>
+		<p>Если вам была перевыпущена карта, активируйте ее в приложении банка.</p>
+	`, cardNumber)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendChallengeCode отправляет одноразовый код подтверждения операции
+func (s *EmailService) SendChallengeCode(to, code, description string) error {
+	subject := "Код подтверждения операции"
+	body := fmt.Sprintf(`
+		<h2>Код подтверждения</h2>
+		<p>%s</p>
+		<p>Ваш код: <b>%s</b></p>
+		<p>Никому не сообщайте этот код. Если вы не совершали операцию, обратитесь в банк.</p>
+	`, html.EscapeString(description), code)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendEmailVerification отправляет ссылку для подтверждения email
+func (s *EmailService) SendEmailVerification(to, link string) error {
+	subject := "Подтвердите адрес электронной почты"
+	body := fmt.Sprintf(`
+		<h2>Подтверждение email</h2>
+		<p>Чтобы начать пользоваться счетами и картами, подтвердите адрес электронной почты:</p>
+		<p><a href="%s">Подтвердить email</a></p>
+		<p>Ссылка действует 24 часа. Если вы не регистрировались в нашем банке, просто проигнорируйте это письмо.</p>
+	`, link)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendPasswordReset отправляет ссылку для сброса пароля
+func (s *EmailService) SendPasswordReset(to, link string) error {
+	subject := "Сброс пароля"
+	body := fmt.Sprintf(`
+		<h2>Сброс пароля</h2>
+		<p>Мы получили запрос на сброс пароля. Чтобы задать новый пароль, перейдите по ссылке:</p>
+		<p><a href="%s">Сбросить пароль</a></p>
+		<p>Ссылка действует 1 час. Если вы не запрашивали сброс пароля, проигнорируйте это письмо — пароль останется прежним.</p>
+	`, link)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendAccountLockedNotification уведомляет владельца о блокировке входа после неудачных попыток
+func (s *EmailService) SendAccountLockedNotification(to string, attempts int, lockedUntil time.Time, ip string) error {
+	subject := "Вход в учетную запись временно заблокирован"
+	body := fmt.Sprintf(`
+		<h2>Вход временно заблокирован</h2>
+		<p>Зафиксировано %d неудачных попыток входа подряд, последняя — с IP-адреса %s.</p>
+		<p>Вход заблокирован до %s.</p>
+		<p>Если это были не вы, смените пароль после разблокировки и обратитесь в банк.</p>
+	`, attempts, ip, lockedUntil.Format("02.01.2006 15:04"))
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendNewDeviceSignInNotification отправляет уведомление о входе с нового устройства
+func (s *EmailService) SendNewDeviceSignInNotification(to, ip, userAgent string, signedInAt time.Time) error {
+	subject := "Вход с нового устройства"
+	body := fmt.Sprintf(`
+		<h2>Вход с нового устройства</h2>
+		<p>%s выполнен вход в вашу учетную запись с устройства, с которого вы раньше не входили.</p>
+		<p>IP-адрес: %s<br>Устройство: %s</p>
+		<p>Если это были не вы, завершите сеанс в разделе «Устройства» и смените пароль.</p>
+	`, signedInAt.Format("02.01.2006 15:04"), ip, html.EscapeString(userAgent))
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendEmailChangeVerification отправляет на новый адрес ссылку для подтверждения смены email
+func (s *EmailService) SendEmailChangeVerification(userID uint, to, link string) error {
+	subject := "Подтвердите новый email"
+	body := fmt.Sprintf(`
+		<h2>Смена email</h2>
+		<p>Чтобы использовать этот адрес для входа, перейдите по ссылке:</p>
+		<p><a href="%s">%s</a></p>
+		<p>Ссылка действует 24 часа. Если вы не меняли email, просто проигнорируйте это письмо.</p>
+	`, link, link)
+
+	return s.send(&userID, to, subject, body)
+}
+
+// SendEmailChangeRequestedNotification предупреждает о запросе смены email на прежний адрес
+func (s *EmailService) SendEmailChangeRequestedNotification(to, newEmail string) error {
+	subject := "Запрошена смена email"
+	body := fmt.Sprintf(`
+		<h2>Запрошена смена email</h2>
+		<p>Для вашей учетной записи запрошена смена email на %s.</p>
+		<p>Если это были не вы, смените пароль и обратитесь в банк.</p>
+	`, html.EscapeString(newEmail))
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendPasswordChangedNotification уведомляет о смене пароля
+func (s *EmailService) SendPasswordChangedNotification(to string) error {
+	subject := "Пароль изменен"
+	body := `
+		<h2>Пароль изменен</h2>
+		<p>Пароль от вашей учетной записи был изменен. Остальные сеансы завершены.</p>
+		<p>Если это были не вы, восстановите доступ через «Забыли пароль» и обратитесь в банк.</p>
+	`
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendKYCApprovedNotification уведомляет о подтверждении анкеты
+func (s *EmailService) SendKYCApprovedNotification(to, level string) error {
+	subject := "Анкета подтверждена"
+	body := fmt.Sprintf(`
+		<h2>Анкета подтверждена</h2>
+		<p>Ваши данные проверены, присвоен уровень верификации %s.</p>
+		<p>Новые лимиты уже действуют.</p>
+	`, level)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendKYCRejectedNotification сообщает об отклонении анкеты и причине
+func (s *EmailService) SendKYCRejectedNotification(to, reason string) error {
+	subject := "Анкета отклонена"
+	body := fmt.Sprintf(`
+		<h2>Анкета отклонена</h2>
+		<p>Мы не смогли подтвердить ваши данные.</p>
+		<p>Причина: %s</p>
+		<p>Исправьте анкету или загрузите документы повторно и отправьте ее на проверку.</p>
+	`, html.EscapeString(reason))
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendDataExportReady отправляет ссылку на скачивание выгрузки персональных данных
+func (s *EmailService) SendDataExportReady(to, link string, expiresAt time.Time) error {
+	subject := "Выгрузка ваших данных готова"
+	body := fmt.Sprintf(`
+		<h2>Выгрузка данных</h2>
+		<p>Архив со всеми данными, которые банк хранит о вас, готов к скачиванию:</p>
+		<p><a href="%s">Скачать архив</a></p>
+		<p>Ссылка действует до %s. Не пересылайте ее: архив содержит персональные данные.</p>
+		<p>Если вы не запрашивали выгрузку, смените пароль и обратитесь в банк.</p>
+	`, link, expiresAt.Format("02.01.2006 15:04"))
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendAccountInvitation отправляет приглашение к совместному счету
+func (s *EmailService) SendAccountInvitation(to, inviterName, accountTitle, role, link string) error {
+	subject := "Приглашение к счету"
+	body := fmt.Sprintf(`
+		<h2>Приглашение к счету</h2>
+		<p>%s приглашает вас к счету «%s» с ролью %s.</p>
+		<p>Чтобы принять приглашение, войдите в учетную запись с этим email и перейдите по ссылке:</p>
+		<p><a href="%s">%s</a></p>
+		<p>Ссылка действует 7 дней. Если вы не ждали приглашения, просто проигнорируйте это письмо.</p>
+	`, html.EscapeString(inviterName), html.EscapeString(accountTitle), role, link, link)
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendInterbankFailedNotification сообщает, что перевод в другой банк отклонен или возвращен
+func (s *EmailService) SendInterbankFailedNotification(to, accountNumber string, amount float64, status, reason string) error {
+	subject := "Перевод в другой банк не выполнен"
+	body := fmt.Sprintf(`
+		<h2>Перевод в другой банк не выполнен</h2>
+		<p>Счет: %s</p>
+		<p>Сумма: %.2f</p>
+		<p>Статус: %s</p>
+		<p>Причина: %s</p>
+		<p>Средства возвращены на счет.</p>
+	`, accountNumber, amount, status, html.EscapeString(reason))
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendStandingOrderFailedNotification сообщает о пропущенном переводе по постоянному поручению
+// или об остановке поручения
+func (s *EmailService) SendStandingOrderFailedNotification(to string, orderID uint, amount float64, reason string, stopped bool) error {
+	subject := "Перевод по постоянному поручению не выполнен"
+	action := "Следующий перевод будет выполнен по расписанию."
+	if stopped {
+		subject = "Постоянное поручение остановлено"
+		action = "Поручение остановлено. Устраните причину и возобновите его в приложении."
+	}
+	body := fmt.Sprintf(`
+		<h2>%s</h2>
+		<p>Поручение: №%d</p>
+		<p>Сумма: %.2f</p>
+		<p>Причина: %s</p>
+		<p>%s</p>
+	`, subject, orderID, amount, html.EscapeString(reason), action)
+
+	return s.SendEmail(to, subject, body)
+}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// interbankReferencePrefix префикс нашего идентификатора исходящего платежа
+const interbankReferencePrefix = "OUT-"
+
+// interbankResendAfter время, после которого не принятый платежной системой платеж отправляется повторно
+const interbankResendAfter = time.Minute
+
+// ErrInterbankPaymentNotFound возвращается, если уведомление относится к неизвестному платежу
+var ErrInterbankPaymentNotFound = errors.New("межбанковский платеж не найден")
+
+// OutgoingPaymentDTO представляет перевод клиенту другого банка по номеру счета или телефону
+type OutgoingPaymentDTO struct {
+	AccountID    uint    `json:"account_id" validate:"required"`
+	PayeeBIK     string  `json:"payee_bik" validate:"required,len=9,numeric"`
+	PayeeAccount string  `json:"payee_account" validate:"required_without=PayeePhone,omitempty,len=20,numeric"`
+	PayeePhone   string  `json:"payee_phone" validate:"required_without=PayeeAccount,omitempty,max=20"`
+	PayeeName    string  `json:"payee_name" validate:"omitempty,max=100"`
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	Purpose      string  `json:"purpose" validate:"omitempty,max=210"`
+	TOTPCode     string  `json:"totp_code" validate:"omitempty,min=6,max=11"` // Код 2FA для переводов выше порога
+}
+
+// InterbankPaymentDTO представляет межбанковский платеж в ответах API
+type InterbankPaymentDTO struct {
+	ID                  uint       `json:"id"`
+	Direction           string     `json:"direction"`
+	Rail                string     `json:"rail"`
+	AccountID           uint       `json:"account_id"`
+	Amount              float64    `json:"amount"`
+	Status              string     `json:"status"`
+	CounterpartyBIK     string     `json:"counterparty_bik"`
+	CounterpartyAccount string     `json:"counterparty_account,omitempty"`
+	CounterpartyPhone   string     `json:"counterparty_phone,omitempty"`
+	CounterpartyName    string     `json:"counterparty_name,omitempty"`
+	Purpose             string     `json:"purpose,omitempty"`
+	RejectReason        string     `json:"reject_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	SettledAt           *time.Time `json:"settled_at,omitempty"`
+}
+
+// InterbankService выполняет переводы в другие банки через платежную систему.
+// Сумма исходящего платежа удерживается на счете до расчета и списывается
+// только после подтверждения платежной системой.
+type InterbankService struct {
+	db        *gorm.DB
+	rail      PaymentRail
+	bik       string
+	twoFactor *TwoFactorService
+	email     *EmailService
+}
+
+// NewInterbankService создает новый экземпляр InterbankService
+func NewInterbankService(db *gorm.DB, rail PaymentRail, numbers *AccountNumberGenerator, twoFactor *TwoFactorService, email *EmailService) *InterbankService {
+	return &InterbankService{
+		db:        db,
+		rail:      rail,
+		bik:       numbers.BIK(),
+		twoFactor: twoFactor,
+		email:     email,
+	}
+}
+
+// Send удерживает сумму на счете клиента и передает платеж в платежную систему.
+// Если платежная система недоступна, платеж остается в статусе CREATED и
+// отправляется повторно фоновой задачей.
+func (s *InterbankService) Send(userID uint, dto OutgoingPaymentDTO) (*InterbankPaymentDTO, error) {
+	if dto.PayeeBIK == s.bik {
+		return nil, errors.New("для перевода клиенту нашего банка используйте внутренний перевод")
+	}
+	var phone string
+	if dto.PayeeAccount != "" {
+		if err := utils.ValidateAccountNumber(dto.PayeeBIK, dto.PayeeAccount); err != nil {
+			return nil, err
+		}
+	} else {
+		normalized, err := normalizePhone(dto.PayeePhone)
+		if err != nil {
+			return nil, err
+		}
+		phone = normalized
+	}
+
+	if _, err := AuthorizeAccount(s.db, userID, dto.AccountID, models.AccountActionSpend, dto.Amount); err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.RequireForTransfer(userID, dto.Amount, dto.TOTPCode); err != nil {
+		return nil, err
+	}
+
+	payment := &models.InterbankPayment{
+		Direction:           models.InterbankOutgoing,
+		Rail:                s.rail.Name(),
+		AccountID:           dto.AccountID,
+		UserID:              &userID,
+		Amount:              dto.Amount,
+		Status:              models.InterbankStatusCreated,
+		CounterpartyBIK:     dto.PayeeBIK,
+		CounterpartyAccount: dto.PayeeAccount,
+		CounterpartyPhone:   phone,
+		CounterpartyName:    strings.TrimSpace(dto.PayeeName),
+		Purpose:             strings.TrimSpace(dto.Purpose),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		account, err := lockBankAccount(tx, dto.AccountID)
+		if err != nil {
+			return err
+		}
+		if err := checkAccountDebit(account); err != nil {
+			return err
+		}
+		if err := chargeSpendLimit(tx, userID, account, dto.Amount); err != nil {
+			return err
+		}
+		if account.Available() < dto.Amount {
+			return ErrInsufficientFunds
+		}
+
+		if err := tx.Model(account).Updates(map[string]interface{}{
+			"held_amount": account.HeldAmount + dto.Amount,
+			"updated_at":  time.Now(),
+		}).Error; err != nil {
+			return errors.New("не удалось удержать средства")
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return errors.New("не удалось создать платеж")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordPayeeUsage(s.db, nil, userID, payeeDetails{
+		Kind:          models.PayeeKindExternal,
+		AccountNumber: dto.PayeeAccount,
+		BIK:           dto.PayeeBIK,
+		Phone:         phone,
+	})
+
+	s.submit(payment)
+	return s.get(payment.ID)
+}
+
+// List возвращает межбанковские платежи по счетам, доступным пользователю
+func (s *InterbankService) List(userID uint) ([]InterbankPaymentDTO, error) {
+	var payments []models.InterbankPayment
+	if err := s.db.Where("account_id IN (?)", accessibleAccountIDs(s.db, userID)).
+		Order("id DESC").
+		Limit(100).
+		Find(&payments).Error; err != nil {
+		return nil, errors.New("ошибка при получении платежей")
+	}
+
+	result := make([]InterbankPaymentDTO, 0, len(payments))
+	for _, payment := range payments {
+		result = append(result, toInterbankPaymentDTO(payment))
+	}
+	return result, nil
+}
+
+// HandleNotification обрабатывает подписанное уведомление платежной системы:
+// зачисляет входящий платеж или обновляет статус исходящего.
+// Повторное уведомление о том же платеже не меняет баланс.
+func (s *InterbankService) HandleNotification(body []byte, signature string) error {
+	notification, err := s.rail.ParseNotification(body, signature)
+	if err != nil {
+		return err
+	}
+
+	if notification.Type == RailNotificationIncoming {
+		return s.receive(notification)
+	}
+
+	var payment models.InterbankPayment
+	query := s.db.Where("direction = ?", models.InterbankOutgoing)
+	if id, ok := parseInterbankReference(notification.Reference); ok {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("external_id = ?", notification.ExternalID)
+	}
+	if err := query.First(&payment).Error; err != nil {
+		return ErrInterbankPaymentNotFound
+	}
+
+	return s.applyStatus(payment.ID, &RailStatus{
+		ExternalID: notification.ExternalID,
+		Status:     notification.Status,
+		Reason:     notification.Reason,
+	})
+}
+
+// PollPending повторно отправляет платежи, не принятые платежной системой,
+// и запрашивает статус отправленных. Возвращает число обработанных платежей.
+func (s *InterbankService) PollPending() (int, error) {
+	var payments []models.InterbankPayment
+	if err := s.db.Where("direction = ?", models.InterbankOutgoing).
+		Where("(status = ? AND created_at < ?) OR status = ?",
+			models.InterbankStatusCreated, time.Now().Add(-interbankResendAfter), models.InterbankStatusSent).
+		Order("id").
+		Limit(100).
+		Find(&payments).Error; err != nil {
+		return 0, errors.New("ошибка при поиске незавершенных платежей")
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		if payment.Status == models.InterbankStatusCreated || payment.ExternalID == nil {
+			s.submit(payment)
+			continue
+		}
+
+		status, err := s.rail.Status(context.Background(), *payment.ExternalID)
+		if err != nil {
+			log.Printf("Ошибка запроса статуса платежа %d: %v", payment.ID, err)
+			continue
+		}
+		if err := s.applyStatus(payment.ID, status); err != nil {
+			log.Printf("Ошибка обновления статуса платежа %d: %v", payment.ID, err)
+		}
+	}
+	return len(payments), nil
+}
+
+// StartPolling периодически опрашивает платежную систему о незавершенных платежах
+func (s *InterbankService) StartPolling(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.PollPending(); err != nil {
+					log.Printf("Ошибка при опросе межбанковских платежей: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// submit передает исходящий платеж в платежную систему и применяет полученный статус.
+// Ошибка отправки только записывается в журнал: платеж будет отправлен повторно.
+func (s *InterbankService) submit(payment *models.InterbankPayment) {
+	var account models.BankAccount
+	if err := s.db.Preload("Holder").First(&account, payment.AccountID).Error; err != nil {
+		log.Printf("Счет платежа %d не найден: %v", payment.ID, err)
+		return
+	}
+
+	status, err := s.rail.Send(context.Background(), RailTransfer{
+		Reference:    interbankReferencePrefix + strconv.FormatUint(uint64(payment.ID), 10),
+		PayerBIK:     s.bik,
+		PayerAccount: account.Number,
+		PayerName:    strings.TrimSpace(account.Holder.FirstName + " " + account.Holder.LastName),
+		PayeeBIK:     payment.CounterpartyBIK,
+		PayeeAccount: payment.CounterpartyAccount,
+		PayeePhone:   payment.CounterpartyPhone,
+		PayeeName:    payment.CounterpartyName,
+		Amount:       payment.Amount,
+		Purpose:      payment.Purpose,
+	})
+	if err != nil {
+		log.Printf("Ошибка отправки платежа %d в %s: %v", payment.ID, s.rail.Name(), err)
+		return
+	}
+	if err := s.applyStatus(payment.ID, status); err != nil {
+		log.Printf("Ошибка обновления статуса платежа %d: %v", payment.ID, err)
+	}
+}
+
+// applyStatus переводит исходящий платеж в новый статус и изменяет баланс счета:
+// при расчете списывает удержанную сумму, при отклонении снимает удержание,
+// при возврате зачисляет сумму обратно.
+func (s *InterbankService) applyStatus(paymentID uint, status *RailStatus) error {
+	var notify *models.InterbankPayment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var payment models.InterbankPayment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+			return ErrInterbankPaymentNotFound
+		}
+
+		updates := map[string]interface{}{"updated_at": time.Now()}
+		if payment.ExternalID == nil && status.ExternalID != "" {
+			updates["external_id"] = status.ExternalID
+		}
+		if payment.Status == status.Status {
+			return tx.Model(&payment).Updates(updates).Error
+		}
+		if !canChangeInterbankStatus(payment.Status, status.Status) {
+			return fmt.Errorf("недопустимый переход платежа из %s в %s", payment.Status, status.Status)
+		}
+
+		account, err := lockBankAccount(tx, payment.AccountID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch status.Status {
+		case models.InterbankStatusSent:
+			updates["sent_at"] = now
+		case models.InterbankStatusSettled:
+			updates["settled_at"] = now
+			if payment.SentAt == nil {
+				updates["sent_at"] = now
+			}
+			err = s.changeBalance(tx, account, -payment.Amount, -payment.Amount,
+				"SBP transfer to "+interbankCounterparty(payment))
+		case models.InterbankStatusRejected:
+			updates["reject_reason"] = status.Reason
+			err = s.changeBalance(tx, account, 0, -payment.Amount, "")
+		case models.InterbankStatusReturned:
+			// Возврат зачисляется независимо от статуса счета: деньги клиента должны вернуться
+			updates["reject_reason"] = status.Reason
+			err = s.changeBalance(tx, account, payment.Amount, 0,
+				"SBP transfer returned by "+interbankCounterparty(payment))
+		}
+		if err != nil {
+			return err
+		}
+
+		updates["status"] = status.Status
+		if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+			return errors.New("ошибка при обновлении платежа")
+		}
+		if status.Status.IsFinal() {
+			payment.Status = status.Status
+			payment.RejectReason = status.Reason
+			notify = &payment
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if notify != nil {
+		s.notifyFailed(notify)
+	}
+	return nil
+}
+
+// receive зачисляет входящий платеж на счет клиента. Платеж на недоступный счет
+// записывается отклоненным, и платежная система возвращает его отправителю.
+func (s *InterbankService) receive(notification *RailNotification) error {
+	var existing models.InterbankPayment
+	if err := s.db.Where("external_id = ?", notification.ExternalID).First(&existing).Error; err == nil {
+		if existing.Status == models.InterbankStatusRejected {
+			return errors.New(existing.RejectReason)
+		}
+		return nil
+	}
+	if notification.Amount <= 0 {
+		return errors.New("неверная сумма платежа")
+	}
+
+	accountID, err := s.resolvePayee(notification)
+	if err != nil {
+		return err
+	}
+
+	var rejected error
+	var account *models.BankAccount
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		account, err = lockBankAccount(tx, accountID)
+		if err != nil {
+			return err
+		}
+
+		payment := &models.InterbankPayment{
+			Direction:           models.InterbankIncoming,
+			Rail:                s.rail.Name(),
+			AccountID:           account.ID,
+			Amount:              notification.Amount,
+			Status:              models.InterbankStatusSettled,
+			ExternalID:          &notification.ExternalID,
+			CounterpartyBIK:     notification.PayerBIK,
+			CounterpartyAccount: notification.PayerAccount,
+			CounterpartyName:    notification.PayerName,
+			Purpose:             notification.Purpose,
+		}
+
+		rejected = checkAccountCredit(account)
+		if rejected == nil {
+			if err := checkBalanceLimit(tx, account.HolderID, notification.Amount); err != nil {
+				if !errors.Is(err, ErrKYCLimitExceeded) {
+					return err
+				}
+				rejected = errors.New("зачисление превышает лимит остатка получателя")
+			}
+		}
+
+		now := time.Now()
+		if rejected != nil {
+			payment.Status = models.InterbankStatusRejected
+			payment.RejectReason = rejected.Error()
+		} else {
+			payment.SentAt = &now
+			payment.SettledAt = &now
+			if err := s.changeBalance(tx, account, notification.Amount, 0,
+				"SBP transfer from "+interbankCounterparty(*payment)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return errors.New("ошибка при сохранении платежа")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if rejected != nil {
+		return rejected
+	}
+
+	var holder models.User
+	if err := s.db.First(&holder, account.HolderID).Error; err == nil {
+		if err := s.email.SendTransactionNotification(holder.Email, account.Number, notification.Amount, "Перевод из другого банка"); err != nil {
+			log.Printf("Ошибка отправки уведомления: %v", err)
+		}
+	}
+	return nil
+}
+
+// resolvePayee находит счет получателя входящего платежа по номеру счета
+// или по телефону клиента (первый открытый счет, как при внутренних переводах)
+func (s *InterbankService) resolvePayee(notification *RailNotification) (uint, error) {
+	var account models.BankAccount
+	if notification.PayeeAccount != "" {
+		if err := s.db.Where("number = ?", notification.PayeeAccount).First(&account).Error; err != nil {
+			return 0, ErrRecipientNotFound
+		}
+		return account.ID, nil
+	}
+
+	phone, err := normalizePhone(notification.PayeePhone)
+	if err != nil {
+		return 0, ErrRecipientNotFound
+	}
+	var user models.User
+	if err := s.db.Where("phone = ? AND phone_verified_at IS NOT NULL AND closed_at IS NULL", phone).First(&user).Error; err != nil {
+		return 0, ErrRecipientNotFound
+	}
+	if err := s.db.Where("holder_id = ? AND status = ?", user.ID, models.AccountStatusActive).
+		Order("id").
+		First(&account).Error; err != nil {
+		return 0, ErrRecipientNotFound
+	}
+	return account.ID, nil
+}
+
+// changeBalance изменяет баланс и удержание заблокированного счета и записывает
+// транзакцию, если меняется баланс
+func (s *InterbankService) changeBalance(tx *gorm.DB, account *models.BankAccount, balanceDelta, heldDelta float64, description string) error {
+	balanceBefore := account.Balance
+	account.Balance += balanceDelta
+	account.HeldAmount += heldDelta
+	if account.HeldAmount < 0 {
+		account.HeldAmount = 0
+	}
+	if err := tx.Model(account).Updates(map[string]interface{}{
+		"balance":     account.Balance,
+		"held_amount": account.HeldAmount,
+		"updated_at":  time.Now(),
+	}).Error; err != nil {
+		return errors.New("ошибка при обновлении баланса")
+	}
+	if balanceDelta == 0 {
+		return nil
+	}
+
+	amount := balanceDelta
+	if amount < 0 {
+		amount = -amount
+	}
+	if err := tx.Create(&models.Transaction{
+		AccountID:     account.ID,
+		Amount:        amount,
+		Type:          string(TransactionTypeTransfer),
+		BalanceBefore: balanceBefore,
+		BalanceAfter:  account.Balance,
+		Description:   description,
+	}).Error; err != nil {
+		return errors.New("ошибка при сохранении транзакции")
+	}
+	return nil
+}
+
+// notifyFailed сообщает инициатору, что перевод отклонен или возвращен
+func (s *InterbankService) notifyFailed(payment *models.InterbankPayment) {
+	if payment.UserID == nil {
+		return
+	}
+	var account models.BankAccount
+	var user models.User
+	if err := s.db.First(&account, payment.AccountID).Error; err != nil {
+		return
+	}
+	if err := s.db.First(&user, *payment.UserID).Error; err != nil {
+		return
+	}
+	if err := s.email.SendInterbankFailedNotification(user.Email, account.Number, payment.Amount, string(payment.Status), payment.RejectReason); err != nil {
+		log.Printf("Ошибка отправки уведомления: %v", err)
+	}
+}
+
+// get возвращает платеж по ID
+func (s *InterbankService) get(id uint) (*InterbankPaymentDTO, error) {
+	var payment models.InterbankPayment
+	if err := s.db.First(&payment, id).Error; err != nil {
+		return nil, ErrInterbankPaymentNotFound
+	}
+	dto := toInterbankPaymentDTO(payment)
+	return &dto, nil
+}
+
+// lockBankAccount загружает счет с блокировкой строки
+func lockBankAccount(tx *gorm.DB, id uint) (*models.BankAccount, error) {
+	var account models.BankAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, errors.New("ошибка при поиске банковского счета")
+	}
+	return &account, nil
+}
+
+// canChangeInterbankStatus проверяет, что исходящий платеж может перейти из статуса from в статус to.
+// Отклонить можно только платеж до расчета, вернуть — только рассчитанный.
+func canChangeInterbankStatus(from, to models.InterbankStatus) bool {
+	switch from {
+	case models.InterbankStatusCreated:
+		return to == models.InterbankStatusSent || to == models.InterbankStatusSettled || to == models.InterbankStatusRejected
+	case models.InterbankStatusSent:
+		return to == models.InterbankStatusSettled || to == models.InterbankStatusRejected
+	case models.InterbankStatusSettled:
+		return to == models.InterbankStatusReturned
+	}
+	return false
+}
+
+// parseInterbankReference извлекает ID платежа из нашего идентификатора OUT-<id>
+func parseInterbankReference(reference string) (uint, bool) {
+	if !strings.HasPrefix(reference, interbankReferencePrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(reference, interbankReferencePrefix), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// interbankCounterparty описывает контрагента платежа для выписки
+func interbankCounterparty(payment models.InterbankPayment) string {
+	if payment.CounterpartyAccount != "" {
+		return payment.CounterpartyBIK + "/" + payment.CounterpartyAccount
+	}
+	return payment.CounterpartyBIK + "/" + payment.CounterpartyPhone
+}
+
+func toInterbankPaymentDTO(payment models.InterbankPayment) InterbankPaymentDTO {
+	return InterbankPaymentDTO{
+		ID:                  payment.ID,
+		Direction:           string(payment.Direction),
+		Rail:                payment.Rail,
+		AccountID:           payment.AccountID,
+		Amount:              payment.Amount,
+		Status:              string(payment.Status),
+		CounterpartyBIK:     payment.CounterpartyBIK,
+		CounterpartyAccount: payment.CounterpartyAccount,
+		CounterpartyPhone:   payment.CounterpartyPhone,
+		CounterpartyName:    payment.CounterpartyName,
+		Purpose:             payment.Purpose,
+		RejectReason:        payment.RejectReason,
+		CreatedAt:           payment.CreatedAt,
+		SettledAt:           payment.SettledAt,
+	}
+}
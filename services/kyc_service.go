@@ -0,0 +1,533 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// kycMaxDocumentSize максимальный размер загружаемого документа
+	kycMaxDocumentSize = 5 << 20
+	// kycMaxDocuments максимальное количество документов в анкете
+	kycMaxDocuments = 10
+	// kycMinAge минимальный возраст клиента
+	kycMinAge = 18
+)
+
+// kycDocumentContentTypes допустимые форматы документов
+var kycDocumentContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+// ErrKYCLimitExceeded возвращается, если операция превышает лимит уровня верификации
+var ErrKYCLimitExceeded = errors.New("операция превышает лимит остатка для вашего уровня верификации, пройдите идентификацию")
+
+// ErrKYCCreditNotAllowed возвращается при попытке взять кредит без полной идентификации
+var ErrKYCCreditNotAllowed = errors.New("кредиты доступны только после полной идентификации")
+
+// KYCSubmissionDTO представляет анкету клиента
+type KYCSubmissionDTO struct {
+	PassportNumber string `json:"passport_number" validate:"required"` // Серия и номер паспорта, 10 цифр
+	BirthDate      string `json:"birth_date" validate:"required"`      // Дата рождения в формате ГГГГ-ММ-ДД
+	INN            string `json:"inn" validate:"required"`
+	Address        string `json:"address" validate:"required,min=10,max=300"`
+}
+
+// KYCDocumentDTO представляет загруженный документ без содержимого
+type KYCDocumentDTO struct {
+	ID          uint      `json:"id"`
+	Type        string    `json:"type"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// KYCStatusDTO представляет состояние идентификации клиента
+type KYCStatusDTO struct {
+	Level           string           `json:"level"`
+	Status          string           `json:"status,omitempty"`
+	RejectionReason string           `json:"rejection_reason,omitempty"`
+	BalanceLimit    float64          `json:"balance_limit,omitempty"` // Не указывается, если ограничения нет
+	CreditsAllowed  bool             `json:"credits_allowed"`
+	Documents       []KYCDocumentDTO `json:"documents"`
+}
+
+// KYCReviewDTO представляет анкету для проверки сотрудником
+type KYCReviewDTO struct {
+	UserID      uint              `json:"user_id"`
+	Email       string            `json:"email"`
+	FirstName   string            `json:"first_name"`
+	LastName    string            `json:"last_name"`
+	Level       string            `json:"level"`
+	Status      string            `json:"status"`
+	SubmittedAt time.Time         `json:"submitted_at"`
+	Data        *KYCSubmissionDTO `json:"data,omitempty"` // Расшифрованная анкета, только в карточке
+	Documents   []KYCDocumentDTO  `json:"documents,omitempty"`
+}
+
+// KYCDocumentContent представляет расшифрованный документ
+type KYCDocumentContent struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// KYCService принимает анкеты клиентов и документы, хранит их зашифрованными
+// и позволяет сотрудникам банка подтверждать уровень верификации
+type KYCService struct {
+	db     *gorm.DB
+	config *config.Config
+	email  *EmailService
+}
+
+// NewKYCService создает новый экземпляр KYCService
+func NewKYCService(db *gorm.DB, cfg *config.Config, email *EmailService) *KYCService {
+	return &KYCService{
+		db:     db,
+		config: cfg,
+		email:  email,
+	}
+}
+
+// Submit сохраняет анкету и отправляет ее на проверку.
+// Подтвержденную полную анкету клиент изменить не может.
+func (s *KYCService) Submit(userID uint, dto KYCSubmissionDTO) (*KYCStatusDTO, error) {
+	normalized, err := normalizeKYCSubmission(dto, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.PGPEncrypt(string(data), s.config.KYCPublicKey)
+	if err != nil {
+		log.Printf("Ошибка шифрования анкеты пользователя %d: %v", userID, err)
+		return nil, errors.New("не удалось сохранить анкету")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("пользователь не найден")
+		}
+		if user.KYCLevel == models.KYCLevelFull {
+			return errors.New("идентификация уже пройдена, для изменения данных обратитесь в банк")
+		}
+
+		profile := models.KYCProfile{UserID: userID}
+		if err := tx.Where("user_id = ?", userID).FirstOrInit(&profile).Error; err != nil {
+			return err
+		}
+		profile.DataEncrypted = encrypted
+		profile.Status = models.KYCStatusPending
+		profile.RejectionReason = ""
+		profile.SubmittedAt = time.Now()
+		profile.ReviewedBy = nil
+		profile.ReviewedAt = nil
+		return tx.Save(&profile).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Status(userID)
+}
+
+// UploadDocument сохраняет документ анкеты в зашифрованном виде
+func (s *KYCService) UploadDocument(userID uint, docType models.KYCDocumentType, fileName, contentType string, data []byte) (*KYCDocumentDTO, error) {
+	switch docType {
+	case models.KYCDocumentPassportMain, models.KYCDocumentPassportRegistration, models.KYCDocumentSelfie, models.KYCDocumentOther:
+	default:
+		return nil, errors.New("неизвестный тип документа")
+	}
+	if !kycDocumentContentTypes[contentType] {
+		return nil, errors.New("поддерживаются только документы JPEG, PNG и PDF")
+	}
+	if len(data) == 0 || len(data) > kycMaxDocumentSize {
+		return nil, fmt.Errorf("размер документа должен быть от 1 байта до %d МБ", kycMaxDocumentSize>>20)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.KYCDocument{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, errors.New("ошибка при проверке документов")
+	}
+	if count >= kycMaxDocuments {
+		return nil, errors.New("достигнуто максимальное количество документов")
+	}
+
+	encrypted, err := utils.PGPEncrypt(string(data), s.config.KYCPublicKey)
+	if err != nil {
+		log.Printf("Ошибка шифрования документа пользователя %d: %v", userID, err)
+		return nil, errors.New("не удалось сохранить документ")
+	}
+
+	document := &models.KYCDocument{
+		UserID:        userID,
+		Type:          docType,
+		FileName:      truncate(fileName, 255),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		DataEncrypted: encrypted,
+	}
+	if err := s.db.Create(document).Error; err != nil {
+		return nil, errors.New("не удалось сохранить документ")
+	}
+
+	dto := toKYCDocumentDTO(*document)
+	return &dto, nil
+}
+
+// Status возвращает уровень верификации, статус анкеты и действующие лимиты
+func (s *KYCService) Status(userID uint) (*KYCStatusDTO, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+
+	status := &KYCStatusDTO{
+		Level:          string(user.KYCLevel),
+		BalanceLimit:   user.KYCLevel.BalanceLimit(),
+		CreditsAllowed: user.KYCLevel.CanTakeCredit(),
+	}
+
+	var profile models.KYCProfile
+	if err := s.db.Where("user_id = ?", userID).First(&profile).Error; err == nil {
+		status.Status = string(profile.Status)
+		status.RejectionReason = profile.RejectionReason
+	}
+
+	documents, err := s.documents(userID)
+	if err != nil {
+		return nil, err
+	}
+	status.Documents = documents
+	return status, nil
+}
+
+// ListPending возвращает анкеты, ожидающие проверки, начиная с самых старых
+func (s *KYCService) ListPending() ([]KYCReviewDTO, error) {
+	var profiles []models.KYCProfile
+	if err := s.db.Where("status = ?", models.KYCStatusPending).
+		Order("submitted_at ASC").
+		Limit(100).
+		Find(&profiles).Error; err != nil {
+		return nil, errors.New("ошибка при получении анкет")
+	}
+
+	result := make([]KYCReviewDTO, 0, len(profiles))
+	for _, profile := range profiles {
+		var user models.User
+		if err := s.db.First(&user, profile.UserID).Error; err != nil {
+			continue
+		}
+		result = append(result, toKYCReviewDTO(user, profile))
+	}
+	return result, nil
+}
+
+// GetForReview возвращает расшифрованную анкету и список документов для сотрудника
+func (s *KYCService) GetForReview(userID uint) (*KYCReviewDTO, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	var profile models.KYCProfile
+	if err := s.db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+
+	decrypted, err := utils.PGPDecrypt(profile.DataEncrypted, s.config.KYCPrivateKey)
+	if err != nil {
+		log.Printf("Ошибка расшифровки анкеты пользователя %d: %v", userID, err)
+		return nil, errors.New("не удалось расшифровать анкету")
+	}
+	var data KYCSubmissionDTO
+	if err := json.Unmarshal([]byte(decrypted), &data); err != nil {
+		return nil, errors.New("не удалось прочитать анкету")
+	}
+
+	documents, err := s.documents(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	review := toKYCReviewDTO(user, profile)
+	review.Data = &data
+	review.Documents = documents
+	return &review, nil
+}
+
+// GetDocument возвращает расшифрованный документ для сотрудника
+func (s *KYCService) GetDocument(userID, documentID uint) (*KYCDocumentContent, error) {
+	var document models.KYCDocument
+	if err := s.db.Where("id = ? AND user_id = ?", documentID, userID).First(&document).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+
+	decrypted, err := utils.PGPDecrypt(document.DataEncrypted, s.config.KYCPrivateKey)
+	if err != nil {
+		log.Printf("Ошибка расшифровки документа %d: %v", document.ID, err)
+		return nil, errors.New("не удалось расшифровать документ")
+	}
+
+	return &KYCDocumentContent{
+		FileName:    document.FileName,
+		ContentType: document.ContentType,
+		Data:        []byte(decrypted),
+	}, nil
+}
+
+// Approve подтверждает анкету и присваивает клиенту уровень верификации.
+// Полный уровень требует хотя бы одного загруженного документа.
+func (s *KYCService) Approve(adminID, userID uint, level models.KYCLevel, reason string) error {
+	if level != models.KYCLevelBasic && level != models.KYCLevelFull {
+		return errors.New("можно присвоить только уровень BASIC или FULL")
+	}
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) < adminReasonMinLength {
+		return ErrAdminReasonRequired
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		profile, err := s.lockPendingProfile(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if level == models.KYCLevelFull {
+			var documents int64
+			if err := tx.Model(&models.KYCDocument{}).Where("user_id = ?", userID).Count(&documents).Error; err != nil {
+				return err
+			}
+			if documents == 0 {
+				return errors.New("для полного уровня нужен хотя бы один документ")
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(profile).Updates(map[string]interface{}{
+			"status":           models.KYCStatusApproved,
+			"rejection_reason": "",
+			"reviewed_by":      adminID,
+			"reviewed_at":      now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("kyc_level", level).Error; err != nil {
+			return err
+		}
+
+		return recordAdminAction(tx, adminID, adminActionKYCApprove, "user", userID, reason, string(level))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.email.SendKYCApprovedNotification(user.Email, string(level)); err != nil {
+		log.Printf("Ошибка отправки уведомления о подтверждении анкеты: %v", err)
+	}
+	return nil
+}
+
+// Reject отклоняет анкету. Причина сообщается клиенту, уровень верификации не меняется.
+func (s *KYCService) Reject(adminID, userID uint, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) < adminReasonMinLength {
+		return ErrAdminReasonRequired
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		profile, err := s.lockPendingProfile(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(profile).Updates(map[string]interface{}{
+			"status":           models.KYCStatusRejected,
+			"rejection_reason": reason,
+			"reviewed_by":      adminID,
+			"reviewed_at":      time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		return recordAdminAction(tx, adminID, adminActionKYCReject, "user", userID, reason, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.email.SendKYCRejectedNotification(user.Email, reason); err != nil {
+		log.Printf("Ошибка отправки уведомления об отклонении анкеты: %v", err)
+	}
+	return nil
+}
+
+// checkBalanceLimit проверяет, что после зачисления amount суммарный остаток
+// на счетах владельца не превысит лимит его уровня верификации
+func checkBalanceLimit(tx *gorm.DB, holderID uint, amount float64) error {
+	var user models.User
+	if err := tx.Select("id", "kyc_level").First(&user, holderID).Error; err != nil {
+		return errors.New("владелец счета не найден")
+	}
+
+	limit := user.KYCLevel.BalanceLimit()
+	if limit == 0 {
+		return nil
+	}
+
+	var total float64
+	if err := tx.Model(&models.BankAccount{}).
+		Where("holder_id = ?", holderID).
+		Select("COALESCE(SUM(balance), 0)").
+		Scan(&total).Error; err != nil {
+		return errors.New("ошибка при проверке лимита")
+	}
+	if total+amount > limit {
+		return ErrKYCLimitExceeded
+	}
+	return nil
+}
+
+// lockPendingProfile загружает анкету, ожидающую проверки, с блокировкой строки
+func (s *KYCService) lockPendingProfile(tx *gorm.DB, userID uint) (*models.KYCProfile, error) {
+	var profile models.KYCProfile
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminTargetNotFound
+		}
+		return nil, err
+	}
+	if profile.Status != models.KYCStatusPending {
+		return nil, errors.New("анкета не ожидает проверки")
+	}
+	return &profile, nil
+}
+
+// documents возвращает список документов клиента без содержимого
+func (s *KYCService) documents(userID uint) ([]KYCDocumentDTO, error) {
+	var documents []models.KYCDocument
+	if err := s.db.Select("id", "type", "file_name", "content_type", "size", "created_at").
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&documents).Error; err != nil {
+		return nil, errors.New("ошибка при получении документов")
+	}
+
+	result := make([]KYCDocumentDTO, len(documents))
+	for i, document := range documents {
+		result[i] = toKYCDocumentDTO(document)
+	}
+	return result, nil
+}
+
+// normalizeKYCSubmission проверяет анкету и приводит поля к единому виду
+func normalizeKYCSubmission(dto KYCSubmissionDTO, now time.Time) (*KYCSubmissionDTO, error) {
+	passport := strings.NewReplacer(" ", "", "-", "").Replace(dto.PassportNumber)
+	if len(passport) != 10 || !isDigits(passport) {
+		return nil, errors.New("серия и номер паспорта должны содержать 10 цифр")
+	}
+
+	birthDate, err := time.Parse("2006-01-02", strings.TrimSpace(dto.BirthDate))
+	if err != nil {
+		return nil, errors.New("дата рождения должна быть в формате ГГГГ-ММ-ДД")
+	}
+	if birthDate.AddDate(kycMinAge, 0, 0).After(now) {
+		return nil, fmt.Errorf("обслуживание доступно с %d лет", kycMinAge)
+	}
+	if birthDate.Before(now.AddDate(-120, 0, 0)) {
+		return nil, errors.New("некорректная дата рождения")
+	}
+
+	inn := strings.TrimSpace(dto.INN)
+	if !ValidatePersonalINN(inn) {
+		return nil, errors.New("некорректный ИНН")
+	}
+
+	return &KYCSubmissionDTO{
+		PassportNumber: passport,
+		BirthDate:      birthDate.Format("2006-01-02"),
+		INN:            inn,
+		Address:        strings.TrimSpace(dto.Address),
+	}, nil
+}
+
+// ValidatePersonalINN проверяет 12-значный ИНН физического лица по контрольным цифрам
+func ValidatePersonalINN(inn string) bool {
+	if len(inn) != 12 || !isDigits(inn) {
+		return false
+	}
+
+	digits := make([]int, 12)
+	for i, r := range inn {
+		digits[i] = int(r - '0')
+	}
+
+	checksum := func(weights []int) int {
+		sum := 0
+		for i, weight := range weights {
+			sum += digits[i] * weight
+		}
+		return sum % 11 % 10
+	}
+
+	return checksum([]int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[10] &&
+		checksum([]int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[11]
+}
+
+func toKYCDocumentDTO(document models.KYCDocument) KYCDocumentDTO {
+	return KYCDocumentDTO{
+		ID:          document.ID,
+		Type:        string(document.Type),
+		FileName:    document.FileName,
+		ContentType: document.ContentType,
+		Size:        document.Size,
+		CreatedAt:   document.CreatedAt,
+	}
+}
+
+func toKYCReviewDTO(user models.User, profile models.KYCProfile) KYCReviewDTO {
+	return KYCReviewDTO{
+		UserID:      user.ID,
+		Email:       user.Email,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Level:       string(user.KYCLevel),
+		Status:      string(profile.Status),
+		SubmittedAt: profile.SubmittedAt,
+	}
+}
+
+// isDigits проверяет, что строка состоит только из цифр
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatePersonalINN(t *testing.T) {
+	cases := map[string]bool{
+		"500100732259":  true,
+		"773370857141":  true,
+		"500100732258":  false, // неверная последняя контрольная цифра
+		"500100732269":  false, // неверная первая контрольная цифра
+		"7707083893":    false, // ИНН юридического лица
+		"50010073225a":  false,
+		"5001007322590": false,
+		"":              false,
+	}
+	for inn, want := range cases {
+		if got := ValidatePersonalINN(inn); got != want {
+			t.Errorf("ValidatePersonalINN(%q) = %v, want %v", inn, got, want)
+		}
+	}
+}
+
+func TestNormalizeKYCSubmission(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	valid := KYCSubmissionDTO{
+		PassportNumber: "45 06 123456",
+		BirthDate:      "1990-01-31",
+		INN:            "500100732259",
+		Address:        "  г. Москва, ул. Тверская, д. 1  ",
+	}
+
+	normalized, err := normalizeKYCSubmission(valid, now)
+	if err != nil {
+		t.Fatalf("normalizeKYCSubmission() error = %v", err)
+	}
+	if normalized.PassportNumber != "4506123456" {
+		t.Errorf("PassportNumber = %q, want 4506123456", normalized.PassportNumber)
+	}
+	if normalized.Address != "г. Москва, ул. Тверская, д. 1" {
+		t.Errorf("Address = %q, want trimmed address", normalized.Address)
+	}
+
+	invalid := map[string]func(dto *KYCSubmissionDTO){
+		"короткий паспорт":   func(dto *KYCSubmissionDTO) { dto.PassportNumber = "4506 12345" },
+		"формат даты":        func(dto *KYCSubmissionDTO) { dto.BirthDate = "31.01.1990" },
+		"младше 18 лет":      func(dto *KYCSubmissionDTO) { dto.BirthDate = "2008-06-16" },
+		"неверный ИНН":       func(dto *KYCSubmissionDTO) { dto.INN = "500100732258" },
+		"нереальный возраст": func(dto *KYCSubmissionDTO) { dto.BirthDate = "1890-01-01" },
+	}
+	for name, mutate := range invalid {
+		dto := valid
+		mutate(&dto)
+		if _, err := normalizeKYCSubmission(dto, now); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// Ровно 18 лет в день проверки уже достаточно
+	adult := valid
+	adult.BirthDate = "2008-06-15"
+	if _, err := normalizeKYCSubmission(adult, now); err != nil {
+		t.Errorf("18th birthday: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,678 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrPayeeNotFound возвращается для неизвестного или чужого получателя
+var ErrPayeeNotFound = errors.New("сохраненный получатель не найден")
+
+// ErrTemplateNotFound возвращается для неизвестного или чужого шаблона
+var ErrTemplateNotFound = errors.New("шаблон перевода не найден")
+
+// CreatePayeeDTO представляет сохранение получателя. Набор реквизитов зависит от вида:
+// ACCOUNT — account_number; CARD — card_number; EXTERNAL — bik и account_number или phone.
+type CreatePayeeDTO struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	Kind          string `json:"kind" validate:"required,oneof=ACCOUNT CARD EXTERNAL"`
+	AccountNumber string `json:"account_number" validate:"omitempty,len=20,numeric"`
+	CardNumber    string `json:"card_number" validate:"omitempty,len=16,numeric"`
+	BIK           string `json:"bik" validate:"omitempty,len=9,numeric"`
+	Phone         string `json:"phone" validate:"omitempty,max=20"`
+	RecipientName string `json:"recipient_name" validate:"omitempty,max=100"`
+}
+
+// UpdatePayeeDTO представляет переименование сохраненного получателя
+type UpdatePayeeDTO struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// PayeeDTO представляет сохраненного получателя в ответах API. Номер карты маскируется
+type PayeeDTO struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	AccountNumber string     `json:"account_number,omitempty"`
+	CardNumber    string     `json:"card_number,omitempty"`
+	BIK           string     `json:"bik,omitempty"`
+	Phone         string     `json:"phone,omitempty"`
+	RecipientName string     `json:"recipient_name,omitempty"`
+	UsageCount    int        `json:"usage_count"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CreateTemplateDTO представляет создание шаблона перевода. Для получателя-карты
+// указывается карта списания, для остальных — счет списания.
+type CreateTemplateDTO struct {
+	Name            string  `json:"name" validate:"required,max=100"`
+	PayeeID         uint    `json:"payee_id" validate:"required"`
+	SourceAccountID uint    `json:"source_account_id"`
+	SourceCardID    uint    `json:"source_card_id"`
+	Amount          float64 `json:"amount" validate:"omitempty,gt=0"`
+	Description     string  `json:"description" validate:"omitempty,max=140"`
+}
+
+// UpdateTemplateDTO представляет изменение шаблона. Незаполненные поля не меняются,
+// amount = 0 убирает сумму по умолчанию.
+type UpdateTemplateDTO struct {
+	Name            *string  `json:"name" validate:"omitempty,min=1,max=100"`
+	SourceAccountID *uint    `json:"source_account_id"`
+	SourceCardID    *uint    `json:"source_card_id"`
+	Amount          *float64 `json:"amount" validate:"omitempty,min=0"`
+	Description     *string  `json:"description" validate:"omitempty,max=140"`
+}
+
+// ExecuteTemplateDTO представляет перевод по шаблону. Сумма по умолчанию берется из шаблона
+type ExecuteTemplateDTO struct {
+	Amount   float64 `json:"amount" validate:"omitempty,gt=0"`
+	TOTPCode string  `json:"totp_code" validate:"omitempty,min=6,max=11"` // Код 2FA для переводов выше порога
+}
+
+// TemplateDTO представляет шаблон перевода в ответах API
+type TemplateDTO struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Payee           PayeeDTO   `json:"payee"`
+	SourceAccountID *uint      `json:"source_account_id,omitempty"`
+	SourceCardID    *uint      `json:"source_card_id,omitempty"`
+	Amount          float64    `json:"amount,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	UsageCount      int        `json:"usage_count"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+}
+
+// TemplateExecutionDTO представляет результат перевода по шаблону
+type TemplateExecutionDTO struct {
+	TemplateID       uint                    `json:"template_id"`
+	Kind             string                  `json:"kind"`
+	Amount           float64                 `json:"amount"`
+	CardTransfer     *CardTransferReceiptDTO `json:"card_transfer,omitempty"`
+	InterbankPayment *InterbankPaymentDTO    `json:"interbank_payment,omitempty"`
+}
+
+// payeeDetails описывает реквизиты получателя выполненного перевода. По ним перевод
+// без шаблона учитывается в частоте использования сохраненного получателя.
+type payeeDetails struct {
+	Kind          models.PayeeKind
+	AccountNumber string
+	CardNumber    string
+	BIK           string
+	Phone         string
+}
+
+// usageRank определяет место получателя или шаблона в списке избранного
+type usageRank struct {
+	count      int
+	lastUsedAt *time.Time
+	id         uint
+}
+
+// PayeeService управляет сохраненными получателями и шаблонами переводов
+// и выполняет переводы по шаблонам через сервисы счетов, карт и СБП
+type PayeeService struct {
+	db        *gorm.DB
+	bank      *BankService
+	cards     *CardService
+	interbank *InterbankService
+	twoFactor *TwoFactorService
+	keys      *CardKeyManager
+}
+
+// NewPayeeService создает новый экземпляр PayeeService
+func NewPayeeService(db *gorm.DB, bank *BankService, cards *CardService, interbank *InterbankService, twoFactor *TwoFactorService, keys *CardKeyManager) *PayeeService {
+	return &PayeeService{
+		db:        db,
+		bank:      bank,
+		cards:     cards,
+		interbank: interbank,
+		twoFactor: twoFactor,
+		keys:      keys,
+	}
+}
+
+// CreatePayee проверяет реквизиты и сохраняет получателя. Номер карты хранится
+// зашифрованным ключом карт, как и номера карт банка.
+func (s *PayeeService) CreatePayee(userID uint, dto CreatePayeeDTO) (*PayeeDTO, error) {
+	payee := &models.SavedPayee{
+		UserID:        userID,
+		Name:          strings.TrimSpace(dto.Name),
+		Kind:          models.PayeeKind(dto.Kind),
+		RecipientName: strings.TrimSpace(dto.RecipientName),
+	}
+	if payee.Name == "" {
+		return nil, errors.New("укажите название получателя")
+	}
+
+	switch payee.Kind {
+	case models.PayeeKindAccount:
+		if dto.AccountNumber == "" {
+			return nil, errors.New("укажите номер счета получателя")
+		}
+		if err := utils.ValidateAccountNumber(s.bank.numbers.BIK(), dto.AccountNumber); err != nil {
+			return nil, err
+		}
+		payee.AccountNumber = dto.AccountNumber
+	case models.PayeeKindCard:
+		if !validateLuhn(dto.CardNumber) {
+			return nil, errors.New("неверный номер карты получателя")
+		}
+		encrypted, version, err := s.keys.Encrypt(dto.CardNumber)
+		if err != nil {
+			return nil, errors.New("не удалось сохранить номер карты")
+		}
+		payee.CardEncrypted = encrypted
+		payee.CardKeyVersion = version
+		payee.CardMasked = maskCardNumber(dto.CardNumber)
+	case models.PayeeKindExternal:
+		if dto.BIK == "" {
+			return nil, errors.New("укажите БИК банка получателя")
+		}
+		if dto.BIK == s.bank.numbers.BIK() {
+			return nil, errors.New("для клиента нашего банка сохраните счет или карту")
+		}
+		payee.BIK = dto.BIK
+		switch {
+		case dto.AccountNumber != "":
+			if err := utils.ValidateAccountNumber(dto.BIK, dto.AccountNumber); err != nil {
+				return nil, err
+			}
+			payee.AccountNumber = dto.AccountNumber
+		case dto.Phone != "":
+			phone, err := normalizePhone(dto.Phone)
+			if err != nil {
+				return nil, err
+			}
+			payee.Phone = phone
+		default:
+			return nil, errors.New("укажите номер счета или телефон получателя")
+		}
+	}
+
+	if err := s.db.Create(payee).Error; err != nil {
+		return nil, errors.New("не удалось сохранить получателя")
+	}
+	return toPayeeDTO(*payee), nil
+}
+
+// ListPayees возвращает получателей пользователя: сначала те, кому переводят чаще
+func (s *PayeeService) ListPayees(userID uint) ([]PayeeDTO, error) {
+	var payees []models.SavedPayee
+	if err := s.db.Where("user_id = ?", userID).Find(&payees).Error; err != nil {
+		return nil, errors.New("ошибка при получении получателей")
+	}
+	sortPayees(payees)
+
+	result := make([]PayeeDTO, 0, len(payees))
+	for _, payee := range payees {
+		result = append(result, *toPayeeDTO(payee))
+	}
+	return result, nil
+}
+
+// UpdatePayee переименовывает получателя. Реквизиты не меняются: для новых
+// реквизитов сохраняется новый получатель.
+func (s *PayeeService) UpdatePayee(userID, payeeID uint, dto UpdatePayeeDTO) (*PayeeDTO, error) {
+	payee, err := s.findPayee(userID, payeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, errors.New("укажите название получателя")
+	}
+	if err := s.db.Model(payee).Updates(map[string]interface{}{
+		"name":       name,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return nil, errors.New("не удалось изменить получателя")
+	}
+	payee.Name = name
+	return toPayeeDTO(*payee), nil
+}
+
+// DeletePayee удаляет получателя вместе с его шаблонами
+func (s *PayeeService) DeletePayee(userID, payeeID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", payeeID, userID).Delete(&models.SavedPayee{})
+		if result.Error != nil {
+			return errors.New("не удалось удалить получателя")
+		}
+		if result.RowsAffected == 0 {
+			return ErrPayeeNotFound
+		}
+		if err := tx.Where("payee_id = ?", payeeID).Delete(&models.TransferTemplate{}).Error; err != nil {
+			return errors.New("не удалось удалить шаблоны получателя")
+		}
+		return nil
+	})
+}
+
+// CreateTemplate создает шаблон перевода сохраненному получателю
+func (s *PayeeService) CreateTemplate(userID uint, dto CreateTemplateDTO) (*TemplateDTO, error) {
+	payee, err := s.findPayee(userID, dto.PayeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.TransferTemplate{
+		UserID:      userID,
+		PayeeID:     payee.ID,
+		Payee:       *payee,
+		Name:        strings.TrimSpace(dto.Name),
+		Amount:      dto.Amount,
+		Description: strings.TrimSpace(dto.Description),
+	}
+	if template.Name == "" {
+		return nil, errors.New("укажите название шаблона")
+	}
+	if dto.SourceAccountID != 0 {
+		template.SourceAccountID = &dto.SourceAccountID
+	}
+	if dto.SourceCardID != 0 {
+		template.SourceCardID = &dto.SourceCardID
+	}
+	if err := s.checkTemplateSource(userID, template); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Omit("Payee").Create(template).Error; err != nil {
+		return nil, errors.New("не удалось создать шаблон")
+	}
+	return toTemplateDTO(*template), nil
+}
+
+// ListTemplates возвращает шаблоны пользователя: сначала самые используемые
+func (s *PayeeService) ListTemplates(userID uint) ([]TemplateDTO, error) {
+	var templates []models.TransferTemplate
+	if err := s.db.Preload("Payee").
+		Where("user_id = ?", userID).
+		Find(&templates).Error; err != nil {
+		return nil, errors.New("ошибка при получении шаблонов")
+	}
+	sortTemplates(templates)
+
+	result := make([]TemplateDTO, 0, len(templates))
+	for _, template := range templates {
+		result = append(result, *toTemplateDTO(template))
+	}
+	return result, nil
+}
+
+// UpdateTemplate изменяет название, источник списания, сумму или назначение шаблона
+func (s *PayeeService) UpdateTemplate(userID, templateID uint, dto UpdateTemplateDTO) (*TemplateDTO, error) {
+	template, err := s.findTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.Name != nil {
+		template.Name = strings.TrimSpace(*dto.Name)
+		if template.Name == "" {
+			return nil, errors.New("укажите название шаблона")
+		}
+	}
+	if dto.SourceAccountID != nil {
+		template.SourceAccountID = nil
+		if *dto.SourceAccountID != 0 {
+			template.SourceAccountID = dto.SourceAccountID
+		}
+	}
+	if dto.SourceCardID != nil {
+		template.SourceCardID = nil
+		if *dto.SourceCardID != 0 {
+			template.SourceCardID = dto.SourceCardID
+		}
+	}
+	if dto.Amount != nil {
+		template.Amount = *dto.Amount
+	}
+	if dto.Description != nil {
+		template.Description = strings.TrimSpace(*dto.Description)
+	}
+	if err := s.checkTemplateSource(userID, template); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(template).Updates(map[string]interface{}{
+		"name":              template.Name,
+		"source_account_id": template.SourceAccountID,
+		"source_card_id":    template.SourceCardID,
+		"amount":            template.Amount,
+		"description":       template.Description,
+		"updated_at":        time.Now(),
+	}).Error; err != nil {
+		return nil, errors.New("не удалось изменить шаблон")
+	}
+	return toTemplateDTO(*template), nil
+}
+
+// DeleteTemplate удаляет шаблон
+func (s *PayeeService) DeleteTemplate(userID, templateID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", templateID, userID).Delete(&models.TransferTemplate{})
+	if result.Error != nil {
+		return errors.New("не удалось удалить шаблон")
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// ExecuteTemplate выполняет перевод по шаблону: на счет банка — обычным переводом,
+// на карту — переводом с карты на карту, в другой банк — через СБП. Права на источник
+// списания и 2FA проверяются так же, как при переводе без шаблона.
+func (s *PayeeService) ExecuteTemplate(userID, templateID uint, dto ExecuteTemplateDTO) (*TemplateExecutionDTO, error) {
+	template, err := s.findTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := templateAmount(template, dto.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	payee := template.Payee
+	result := &TemplateExecutionDTO{
+		TemplateID: template.ID,
+		Kind:       string(payee.Kind),
+		Amount:     amount,
+	}
+
+	// Получатель учитывается в частоте использования самим переводом, как и перевод без шаблона
+	switch payee.Kind {
+	case models.PayeeKindAccount:
+		request, err := templateTransferRequest(template, userID, amount)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := AuthorizeAccount(s.db, userID, request.SourceID, models.AccountActionSpend, amount); err != nil {
+			return nil, err
+		}
+		var destination models.BankAccount
+		if err := s.db.Where("number = ?", payee.AccountNumber).First(&destination).Error; err != nil {
+			return nil, ErrRecipientNotFound
+		}
+		request.DestinationID = destination.ID
+		if err := s.twoFactor.RequireForTransfer(userID, amount, dto.TOTPCode); err != nil {
+			return nil, err
+		}
+		if err := s.bank.Transfer(request); err != nil {
+			return nil, err
+		}
+	case models.PayeeKindCard:
+		number, err := s.keys.Decrypt(payee.CardKeyVersion, payee.CardEncrypted)
+		if err != nil {
+			return nil, errors.New("не удалось прочитать номер карты получателя")
+		}
+		request, err := templateCardTransfer(template, number, amount)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.twoFactor.RequireForTransfer(userID, amount, dto.TOTPCode); err != nil {
+			return nil, err
+		}
+		receipt, err := s.cards.TransferByCard(userID, request)
+		if err != nil {
+			return nil, err
+		}
+		result.CardTransfer = receipt
+	case models.PayeeKindExternal:
+		request, err := templateInterbankPayment(template, amount, dto.TOTPCode)
+		if err != nil {
+			return nil, err
+		}
+		payment, err := s.interbank.Send(userID, request)
+		if err != nil {
+			return nil, err
+		}
+		result.InterbankPayment = payment
+	default:
+		return nil, errors.New("неизвестный вид получателя")
+	}
+
+	s.recordUsage(template)
+	return result, nil
+}
+
+// recordUsage учитывает перевод по шаблону для сортировки избранного
+func (s *PayeeService) recordUsage(template *models.TransferTemplate) {
+	s.db.Model(&models.TransferTemplate{}).Where("id = ?", template.ID).Updates(map[string]interface{}{
+		"usage_count":  gorm.Expr("usage_count + 1"),
+		"last_used_at": time.Now(),
+	})
+}
+
+// templateAmount возвращает сумму перевода по шаблону: указанную в запросе
+// или сумму по умолчанию из шаблона
+func templateAmount(template *models.TransferTemplate, requested float64) (float64, error) {
+	amount := requested
+	if amount == 0 {
+		amount = template.Amount
+	}
+	if amount <= 0 {
+		return 0, errors.New("в шаблоне нет суммы, укажите amount")
+	}
+	return amount, nil
+}
+
+// templateTransferRequest составляет перевод на счет банка по шаблону.
+// Счет получателя находит вызывающий по номеру счета из шаблона.
+func templateTransferRequest(template *models.TransferTemplate, userID uint, amount float64) (TransferRequest, error) {
+	if template.SourceAccountID == nil {
+		return TransferRequest{}, errors.New("в шаблоне не указан счет списания")
+	}
+	return TransferRequest{
+		SourceID:    *template.SourceAccountID,
+		Amount:      amount,
+		Description: template.Description,
+		SpenderID:   userID,
+	}, nil
+}
+
+// templateCardTransfer составляет перевод на карту по шаблону с расшифрованным номером карты получателя
+func templateCardTransfer(template *models.TransferTemplate, number string, amount float64) (CardTransferDTO, error) {
+	if template.SourceCardID == nil {
+		return CardTransferDTO{}, errors.New("в шаблоне не указана карта списания")
+	}
+	return CardTransferDTO{
+		SourceCardID:      *template.SourceCardID,
+		DestinationNumber: number,
+		Amount:            amount,
+	}, nil
+}
+
+// templateInterbankPayment составляет платеж через СБП по шаблону
+func templateInterbankPayment(template *models.TransferTemplate, amount float64, totpCode string) (OutgoingPaymentDTO, error) {
+	if template.SourceAccountID == nil {
+		return OutgoingPaymentDTO{}, errors.New("в шаблоне не указан счет списания")
+	}
+	payee := template.Payee
+	return OutgoingPaymentDTO{
+		AccountID:    *template.SourceAccountID,
+		PayeeBIK:     payee.BIK,
+		PayeeAccount: payee.AccountNumber,
+		PayeePhone:   payee.Phone,
+		PayeeName:    payee.RecipientName,
+		Amount:       amount,
+		Purpose:      template.Description,
+		TOTPCode:     totpCode,
+	}, nil
+}
+
+// recordPayeeUsage учитывает выполненный перевод в частоте использования сохраненных
+// получателей пользователя с теми же реквизитами. Номер карты сравнивается после
+// расшифровки, поэтому для переводов на карту нужен keys. Ошибки только журналируются:
+// перевод уже выполнен.
+func recordPayeeUsage(db *gorm.DB, keys *CardKeyManager, userID uint, details payeeDetails) {
+	var payees []models.SavedPayee
+	if err := db.Where("user_id = ? AND kind = ?", userID, details.Kind).Find(&payees).Error; err != nil {
+		log.Printf("Ошибка при поиске сохраненных получателей пользователя %d: %v", userID, err)
+		return
+	}
+
+	var ids []uint
+	for _, payee := range payees {
+		var cardNumber string
+		if payee.Kind == models.PayeeKindCard && keys != nil && payee.CardMasked == maskCardNumber(details.CardNumber) {
+			number, err := keys.Decrypt(payee.CardKeyVersion, payee.CardEncrypted)
+			if err != nil {
+				log.Printf("Ошибка расшифровки карты сохраненного получателя %d: %v", payee.ID, err)
+				continue
+			}
+			cardNumber = number
+		}
+		if payeeMatches(payee, details, cardNumber) {
+			ids = append(ids, payee.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	if err := db.Model(&models.SavedPayee{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"usage_count":  gorm.Expr("usage_count + 1"),
+		"last_used_at": time.Now(),
+	}).Error; err != nil {
+		log.Printf("Ошибка учета перевода сохраненным получателям %v: %v", ids, err)
+	}
+}
+
+// payeeMatches проверяет, что перевод выполнен по реквизитам сохраненного получателя.
+// cardNumber — расшифрованный номер карты получателя вида CARD.
+func payeeMatches(payee models.SavedPayee, details payeeDetails, cardNumber string) bool {
+	if payee.Kind != details.Kind {
+		return false
+	}
+	switch payee.Kind {
+	case models.PayeeKindAccount:
+		return details.AccountNumber != "" && payee.AccountNumber == details.AccountNumber
+	case models.PayeeKindCard:
+		return details.CardNumber != "" && cardNumber == details.CardNumber
+	case models.PayeeKindExternal:
+		if payee.BIK != details.BIK {
+			return false
+		}
+		if payee.AccountNumber != "" {
+			return payee.AccountNumber == details.AccountNumber
+		}
+		return payee.Phone != "" && payee.Phone == details.Phone
+	}
+	return false
+}
+
+// before проверяет, что в списке избранного r идет раньше other: сначала чаще
+// используемые, затем недавно использованные, затем сохраненные позже
+func (r usageRank) before(other usageRank) bool {
+	if r.count != other.count {
+		return r.count > other.count
+	}
+	if (r.lastUsedAt == nil) != (other.lastUsedAt == nil) {
+		return r.lastUsedAt != nil
+	}
+	if r.lastUsedAt != nil && !r.lastUsedAt.Equal(*other.lastUsedAt) {
+		return r.lastUsedAt.After(*other.lastUsedAt)
+	}
+	return r.id > other.id
+}
+
+// sortPayees упорядочивает получателей по частоте использования
+func sortPayees(payees []models.SavedPayee) {
+	sort.Slice(payees, func(i, j int) bool {
+		return usageRank{payees[i].UsageCount, payees[i].LastUsedAt, payees[i].ID}.
+			before(usageRank{payees[j].UsageCount, payees[j].LastUsedAt, payees[j].ID})
+	})
+}
+
+// sortTemplates упорядочивает шаблоны по частоте использования
+func sortTemplates(templates []models.TransferTemplate) {
+	sort.Slice(templates, func(i, j int) bool {
+		return usageRank{templates[i].UsageCount, templates[i].LastUsedAt, templates[i].ID}.
+			before(usageRank{templates[j].UsageCount, templates[j].LastUsedAt, templates[j].ID})
+	})
+}
+
+// checkTemplateSource проверяет, что источник списания подходит получателю
+// и пользователь может с него тратить
+func (s *PayeeService) checkTemplateSource(userID uint, template *models.TransferTemplate) error {
+	if template.Payee.Kind == models.PayeeKindCard {
+		if template.SourceCardID == nil {
+			return errors.New("для перевода на карту укажите source_card_id")
+		}
+		template.SourceAccountID = nil
+		if _, err := s.cards.GetUserCard(userID, *template.SourceCardID, models.AccountActionSpend); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if template.SourceAccountID == nil {
+		return errors.New("укажите source_account_id")
+	}
+	template.SourceCardID = nil
+	_, err := AuthorizeAccount(s.db, userID, *template.SourceAccountID, models.AccountActionSpend, template.Amount)
+	return err
+}
+
+// findPayee возвращает получателя пользователя
+func (s *PayeeService) findPayee(userID, payeeID uint) (*models.SavedPayee, error) {
+	var payee models.SavedPayee
+	if err := s.db.Where("id = ? AND user_id = ?", payeeID, userID).First(&payee).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPayeeNotFound
+		}
+		return nil, errors.New("ошибка при поиске получателя")
+	}
+	return &payee, nil
+}
+
+// findTemplate возвращает шаблон пользователя с получателем
+func (s *PayeeService) findTemplate(userID, templateID uint) (*models.TransferTemplate, error) {
+	var template models.TransferTemplate
+	if err := s.db.Preload("Payee").Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, errors.New("ошибка при поиске шаблона")
+	}
+	return &template, nil
+}
+
+func toPayeeDTO(payee models.SavedPayee) *PayeeDTO {
+	return &PayeeDTO{
+		ID:            payee.ID,
+		Name:          payee.Name,
+		Kind:          string(payee.Kind),
+		AccountNumber: payee.AccountNumber,
+		CardNumber:    payee.CardMasked,
+		BIK:           payee.BIK,
+		Phone:         payee.Phone,
+		RecipientName: payee.RecipientName,
+		UsageCount:    payee.UsageCount,
+		LastUsedAt:    payee.LastUsedAt,
+		CreatedAt:     payee.CreatedAt,
+	}
+}
+
+func toTemplateDTO(template models.TransferTemplate) *TemplateDTO {
+	return &TemplateDTO{
+		ID:              template.ID,
+		Name:            template.Name,
+		Payee:           *toPayeeDTO(template.Payee),
+		SourceAccountID: template.SourceAccountID,
+		SourceCardID:    template.SourceCardID,
+		Amount:          template.Amount,
+		Description:     template.Description,
+		UsageCount:      template.UsageCount,
+		LastUsedAt:      template.LastUsedAt,
+	}
+}
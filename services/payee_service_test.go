@@ -0,0 +1,233 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToPayeeDTOMasksCard(t *testing.T) {
+	payee := models.SavedPayee{
+		ID:             1,
+		Name:           "Карта мамы",
+		Kind:           models.PayeeKindCard,
+		CardEncrypted:  "ciphertext",
+		CardKeyVersion: 2,
+		CardMasked:     maskCardNumber("4276380012345678"),
+	}
+
+	body, err := json.Marshal(toPayeeDTO(payee))
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if strings.Contains(string(body), "4276380012345678") || strings.Contains(string(body), "ciphertext") {
+		t.Fatalf("номер карты попал в ответ: %s", body)
+	}
+	if !strings.Contains(string(body), `"card_number":"4276 **** **** 5678"`) {
+		t.Fatalf("нет маскированного номера карты: %s", body)
+	}
+}
+
+func TestSortPayeesByUsage(t *testing.T) {
+	earlier := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	later := earlier.Add(24 * time.Hour)
+
+	payees := []models.SavedPayee{
+		{ID: 1, UsageCount: 0},
+		{ID: 2, UsageCount: 3, LastUsedAt: &earlier},
+		{ID: 3, UsageCount: 3, LastUsedAt: &later},
+		{ID: 4, UsageCount: 0},
+		{ID: 5, UsageCount: 7, LastUsedAt: &earlier},
+		{ID: 6, UsageCount: 3},
+	}
+	sortPayees(payees)
+
+	want := []uint{5, 3, 2, 6, 4, 1}
+	for i, payee := range payees {
+		if payee.ID != want[i] {
+			t.Fatalf("порядок получателей = %v, want %v", payeeIDs(payees), want)
+		}
+	}
+}
+
+func TestSortTemplatesByUsage(t *testing.T) {
+	used := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	templates := []models.TransferTemplate{
+		{ID: 1, UsageCount: 1, LastUsedAt: &used},
+		{ID: 2},
+		{ID: 3, UsageCount: 2, LastUsedAt: &used},
+	}
+	sortTemplates(templates)
+
+	for i, id := range []uint{3, 1, 2} {
+		if templates[i].ID != id {
+			t.Fatalf("шаблон %d на месте %d, want %d", templates[i].ID, i, id)
+		}
+	}
+}
+
+func TestTemplateAmount(t *testing.T) {
+	template := &models.TransferTemplate{Amount: 1500}
+	if amount, err := templateAmount(template, 0); err != nil || amount != 1500 {
+		t.Errorf("templateAmount(0) = %v, %v, want сумму шаблона 1500", amount, err)
+	}
+	if amount, err := templateAmount(template, 200); err != nil || amount != 200 {
+		t.Errorf("templateAmount(200) = %v, %v, want 200", amount, err)
+	}
+	if _, err := templateAmount(&models.TransferTemplate{}, 0); err == nil {
+		t.Error("templateAmount без суммы: want ошибку")
+	}
+}
+
+func TestExecuteTemplateRequests(t *testing.T) {
+	sourceAccount := uint(10)
+	sourceCard := uint(20)
+
+	t.Run("ACCOUNT", func(t *testing.T) {
+		template := &models.TransferTemplate{
+			SourceAccountID: &sourceAccount,
+			Description:     "Аренда",
+			Payee:           models.SavedPayee{Kind: models.PayeeKindAccount, AccountNumber: "40817810000000000001"},
+		}
+		request, err := templateTransferRequest(template, 7, 500)
+		if err != nil {
+			t.Fatalf("templateTransferRequest: %v", err)
+		}
+		want := TransferRequest{SourceID: 10, Amount: 500, Description: "Аренда", SpenderID: 7}
+		if request != want {
+			t.Errorf("templateTransferRequest() = %+v, want %+v", request, want)
+		}
+
+		template.SourceAccountID = nil
+		if _, err := templateTransferRequest(template, 7, 500); err == nil {
+			t.Error("без счета списания: want ошибку")
+		}
+	})
+
+	t.Run("CARD", func(t *testing.T) {
+		template := &models.TransferTemplate{
+			SourceCardID: &sourceCard,
+			Payee:        models.SavedPayee{Kind: models.PayeeKindCard, CardMasked: "4276 **** **** 5678"},
+		}
+		request, err := templateCardTransfer(template, "4276380012345678", 300)
+		if err != nil {
+			t.Fatalf("templateCardTransfer: %v", err)
+		}
+		want := CardTransferDTO{SourceCardID: 20, DestinationNumber: "4276380012345678", Amount: 300}
+		if request != want {
+			t.Errorf("templateCardTransfer() = %+v, want %+v", request, want)
+		}
+
+		template.SourceCardID = nil
+		if _, err := templateCardTransfer(template, "4276380012345678", 300); err == nil {
+			t.Error("без карты списания: want ошибку")
+		}
+	})
+
+	t.Run("EXTERNAL", func(t *testing.T) {
+		template := &models.TransferTemplate{
+			SourceAccountID: &sourceAccount,
+			Description:     "Долг",
+			Payee: models.SavedPayee{
+				Kind:          models.PayeeKindExternal,
+				BIK:           "044525225",
+				Phone:         "+79123456789",
+				RecipientName: "Иванов Иван Иванович",
+			},
+		}
+		request, err := templateInterbankPayment(template, 1000, "123456")
+		if err != nil {
+			t.Fatalf("templateInterbankPayment: %v", err)
+		}
+		want := OutgoingPaymentDTO{
+			AccountID:  10,
+			PayeeBIK:   "044525225",
+			PayeePhone: "+79123456789",
+			PayeeName:  "Иванов Иван Иванович",
+			Amount:     1000,
+			Purpose:    "Долг",
+			TOTPCode:   "123456",
+		}
+		if request != want {
+			t.Errorf("templateInterbankPayment() = %+v, want %+v", request, want)
+		}
+
+		template.SourceAccountID = nil
+		if _, err := templateInterbankPayment(template, 1000, ""); err == nil {
+			t.Error("без счета списания: want ошибку")
+		}
+	})
+}
+
+func TestPayeeMatches(t *testing.T) {
+	cases := []struct {
+		name       string
+		payee      models.SavedPayee
+		details    payeeDetails
+		cardNumber string
+		want       bool
+	}{
+		{
+			name:    "счет банка",
+			payee:   models.SavedPayee{Kind: models.PayeeKindAccount, AccountNumber: "40817810000000000001"},
+			details: payeeDetails{Kind: models.PayeeKindAccount, AccountNumber: "40817810000000000001"},
+			want:    true,
+		},
+		{
+			name:    "другой счет",
+			payee:   models.SavedPayee{Kind: models.PayeeKindAccount, AccountNumber: "40817810000000000001"},
+			details: payeeDetails{Kind: models.PayeeKindAccount, AccountNumber: "40817810000000000002"},
+		},
+		{
+			name:       "карта",
+			payee:      models.SavedPayee{Kind: models.PayeeKindCard},
+			details:    payeeDetails{Kind: models.PayeeKindCard, CardNumber: "4276380012345678"},
+			cardNumber: "4276380012345678",
+			want:       true,
+		},
+		{
+			name:    "карта не расшифрована",
+			payee:   models.SavedPayee{Kind: models.PayeeKindCard},
+			details: payeeDetails{Kind: models.PayeeKindCard, CardNumber: "4276380012345678"},
+		},
+		{
+			name:    "телефон в другом банке",
+			payee:   models.SavedPayee{Kind: models.PayeeKindExternal, BIK: "044525225", Phone: "+79123456789"},
+			details: payeeDetails{Kind: models.PayeeKindExternal, BIK: "044525225", Phone: "+79123456789"},
+			want:    true,
+		},
+		{
+			name:    "тот же телефон в другом банке получателя",
+			payee:   models.SavedPayee{Kind: models.PayeeKindExternal, BIK: "044525225", Phone: "+79123456789"},
+			details: payeeDetails{Kind: models.PayeeKindExternal, BIK: "044525974", Phone: "+79123456789"},
+		},
+		{
+			name:    "счет в другом банке",
+			payee:   models.SavedPayee{Kind: models.PayeeKindExternal, BIK: "044525225", AccountNumber: "40817810000000000001"},
+			details: payeeDetails{Kind: models.PayeeKindExternal, BIK: "044525225", AccountNumber: "40817810000000000001"},
+			want:    true,
+		},
+		{
+			name:    "другой вид получателя",
+			payee:   models.SavedPayee{Kind: models.PayeeKindExternal, AccountNumber: "40817810000000000001"},
+			details: payeeDetails{Kind: models.PayeeKindAccount, AccountNumber: "40817810000000000001"},
+		},
+	}
+
+	for _, tc := range cases {
+		if got := payeeMatches(tc.payee, tc.details, tc.cardNumber); got != tc.want {
+			t.Errorf("%s: payeeMatches() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func payeeIDs(payees []models.SavedPayee) []uint {
+	ids := make([]uint, 0, len(payees))
+	for _, payee := range payees {
+		ids = append(ids, payee.ID)
+	}
+	return ids
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"context"
+	"errors"
+)
+
+// ErrRailSignatureInvalid возвращается для уведомления с неверной подписью
+var ErrRailSignatureInvalid = errors.New("неверная подпись уведомления")
+
+// RailTransfer представляет исходящий платеж, передаваемый в платежную систему
+type RailTransfer struct {
+	Reference    string // Наш идентификатор платежа, повторная отправка с ним не создает дубль
+	PayerBIK     string
+	PayerAccount string
+	PayerName    string
+	PayeeBIK     string
+	PayeeAccount string
+	PayeePhone   string
+	PayeeName    string
+	Amount       float64
+	Purpose      string
+}
+
+// RailStatus представляет состояние платежа в платежной системе
+type RailStatus struct {
+	ExternalID string
+	Status     models.InterbankStatus
+	Reason     string
+}
+
+// RailNotificationType представляет тип уведомления от платежной системы
+type RailNotificationType string
+
+const (
+	RailNotificationIncoming RailNotificationType = "INCOMING" // Входящий платеж клиенту банка
+	RailNotificationStatus   RailNotificationType = "STATUS"   // Изменение статуса исходящего платежа
+)
+
+// RailNotification представляет уведомление платежной системы
+type RailNotification struct {
+	Type         RailNotificationType
+	ExternalID   string
+	Reference    string // Наш идентификатор для уведомлений о статусе исходящего платежа
+	Status       models.InterbankStatus
+	Reason       string
+	Amount       float64
+	PayerBIK     string
+	PayerAccount string
+	PayerName    string
+	PayeeAccount string
+	PayeePhone   string
+	Purpose      string
+}
+
+// PaymentRail отправляет платежи в другие банки через платежную систему
+// и разбирает ее уведомления
+type PaymentRail interface {
+	// Name возвращает название платежной системы для журнала платежей
+	Name() string
+	// Send передает исходящий платеж. Ошибка означает, что результат неизвестен
+	// и отправку можно повторить с тем же Reference.
+	Send(ctx context.Context, transfer RailTransfer) (*RailStatus, error)
+	// Status запрашивает текущий статус платежа по идентификатору платежной системы
+	Status(ctx context.Context, externalID string) (*RailStatus, error)
+	// ParseNotification проверяет подпись уведомления и разбирает его
+	ParseNotification(body []byte, signature string) (*RailNotification, error)
+}
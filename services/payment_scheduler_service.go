@@ -10,15 +10,17 @@ import (
 
 // PaymentSchedulerService предоставляет методы для автоматической обработки платежей
 type PaymentSchedulerService struct {
-	db            *gorm.DB
-	creditService *CreditService
+	db             *gorm.DB
+	creditService  *CreditService
+	standingOrders *StandingOrderService
 }
 
 // NewPaymentSchedulerService создает новый экземпляр PaymentSchedulerService
-func NewPaymentSchedulerService(db *gorm.DB, creditService *CreditService) *PaymentSchedulerService {
+func NewPaymentSchedulerService(db *gorm.DB, creditService *CreditService, standingOrders *StandingOrderService) *PaymentSchedulerService {
 	return &PaymentSchedulerService{
-		db:            db,
-		creditService: creditService,
+		db:             db,
+		creditService:  creditService,
+		standingOrders: standingOrders,
 	}
 }
 
@@ -37,6 +39,19 @@ func (s *PaymentSchedulerService) Start() {
 		}
 	}()
 
+	// Запускаем выполнение постоянных поручений каждые 15 минут
+	standingOrderTicker := time.NewTicker(15 * time.Minute)
+	go func() {
+		for {
+			select {
+			case <-standingOrderTicker.C:
+				if _, err := s.standingOrders.ProcessDue(time.Now()); err != nil {
+					log.Printf("Ошибка при выполнении постоянных поручений: %v", err)
+				}
+			}
+		}
+	}()
+
 	// Запускаем обработку просроченных платежей каждый час
 	overdueTicker := time.NewTicker(1 * time.Hour)
 	go func() {
@@ -119,8 +134,9 @@ func (s *PaymentSchedulerService) processPayments() error {
 
 // processPayment обрабатывает один платеж
 func (s *PaymentSchedulerService) processPayment(tx *gorm.DB, payment *models.Payment) error {
-	// Проверяем достаточно ли средств на счете
-	if payment.Credit.Account.Balance < payment.Amount {
+	// Проверяем достаточно ли средств на счете. Со счета с запретом списаний
+	// платеж не списывается и считается просроченным.
+	if payment.Credit.Account.Available() < payment.Amount || !payment.Credit.Account.Status.CanDebit() {
 		if payment.IsOverdue {
 			return nil
 		}
@@ -146,7 +162,10 @@ func (s *PaymentSchedulerService) processPayment(tx *gorm.DB, payment *models.Pa
 
 	// Списываем средства со счета
 	payment.Credit.Account.Balance -= payment.Amount
-	if err := tx.Save(&payment.Credit.Account).Error; err != nil {
+	if err := tx.Model(&payment.Credit.Account).Updates(map[string]interface{}{
+		"balance":    payment.Credit.Account.Balance,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
 		return errors.New("ошибка при списании средств")
 	}
 
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+const (
+	phoneCodeTTL            = 10 * time.Minute // Срок действия кода подтверждения телефона
+	phoneCodeResendInterval = time.Minute      // Минимальный интервал между отправками кода
+	phoneCodeMaxAttempts    = 5                // Неверных кодов до сброса кода
+)
+
+// ConfirmPhoneDTO представляет код подтверждения телефона из SMS
+type ConfirmPhoneDTO struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// PhoneVerificationService подтверждает номера телефонов кодом из SMS.
+// Переводы по номеру находят только подтвержденные номера.
+type PhoneVerificationService struct {
+	db     *gorm.DB
+	sender PhoneCodeSender
+}
+
+// NewPhoneVerificationService создает новый экземпляр PhoneVerificationService
+func NewPhoneVerificationService(db *gorm.DB, sender PhoneCodeSender) *PhoneVerificationService {
+	return &PhoneVerificationService{
+		db:     db,
+		sender: sender,
+	}
+}
+
+// SendCode отправляет код подтверждения на номер, ожидающий подтверждения.
+// Номер, указанный до появления подтверждения, тоже можно подтвердить.
+func (s *PhoneVerificationService) SendCode(userID uint) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("пользователь не найден")
+	}
+
+	phone := phoneToVerify(user)
+	if phone == "" {
+		return errors.New("нет номера телефона, ожидающего подтверждения")
+	}
+
+	now := time.Now()
+	if resendAt := phoneCodeResendAt(user.PhoneCodeExpiresAt); resendAt.After(now) {
+		return fmt.Errorf("повторно запросить код можно после %s", resendAt.Format("15:04:05"))
+	}
+
+	code, err := randomDigits(rand.Reader, 6)
+	if err != nil {
+		return err
+	}
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&user).Updates(map[string]interface{}{
+		"pending_phone":         phone,
+		"phone_code_hash":       string(codeHash),
+		"phone_code_expires_at": now.Add(phoneCodeTTL),
+		"phone_code_attempts":   0,
+	}).Error; err != nil {
+		return errors.New("не удалось сохранить код подтверждения")
+	}
+
+	if err := s.sender.SendPhoneCode(phone, code); err != nil {
+		log.Printf("Ошибка отправки кода подтверждения телефона пользователю %d: %v", userID, err)
+		return errors.New("не удалось отправить код подтверждения")
+	}
+	return nil
+}
+
+// Confirm проверяет код из SMS и делает номер подтвержденным. Если тот же номер
+// был указан у другого пользователя, у него номер удаляется: номер принадлежит тому,
+// кто получил код. Поэтому занятость номера не раскрывается ни при указании, ни при подтверждении.
+func (s *PhoneVerificationService) Confirm(userID uint, code string) error {
+	var verifyErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("пользователь не найден")
+		}
+		if user.PendingPhone == "" || user.PhoneCodeHash == "" {
+			return errors.New("код подтверждения не запрашивался")
+		}
+
+		now := time.Now()
+		if user.PhoneCodeExpiresAt == nil || now.After(*user.PhoneCodeExpiresAt) {
+			return errors.New("срок действия кода истек, запросите новый")
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PhoneCodeHash), []byte(code)); err != nil {
+			// Неверный код: сохраняем счетчик попыток, не откатывая транзакцию
+			updates := map[string]interface{}{"phone_code_attempts": user.PhoneCodeAttempts + 1}
+			verifyErr = errors.New("неверный код подтверждения")
+			if user.PhoneCodeAttempts+1 >= phoneCodeMaxAttempts {
+				updates["phone_code_hash"] = ""
+				updates["phone_code_attempts"] = 0
+				verifyErr = errors.New("превышено количество попыток, запросите новый код")
+			}
+			return tx.Model(&user).Updates(updates).Error
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("phone = ? AND id <> ?", user.PendingPhone, user.ID).
+			Updates(map[string]interface{}{
+				"phone":             nil,
+				"phone_verified_at": nil,
+				"updated_at":        now,
+			}).Error; err != nil {
+			return errors.New("не удалось подтвердить телефон")
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"phone":                 user.PendingPhone,
+			"phone_verified_at":     now,
+			"pending_phone":         "",
+			"phone_code_hash":       "",
+			"phone_code_expires_at": nil,
+			"phone_code_attempts":   0,
+			"updated_at":            now,
+		}).Error; err != nil {
+			return errors.New("не удалось подтвердить телефон")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return verifyErr
+}
+
+// phoneToVerify возвращает номер, который ожидает подтверждения: новый номер
+// или неподтвержденный номер, указанный ранее
+func phoneToVerify(user models.User) string {
+	if user.PendingPhone != "" {
+		return user.PendingPhone
+	}
+	if user.Phone != nil && user.PhoneVerifiedAt == nil {
+		return *user.Phone
+	}
+	return ""
+}
+
+// phoneCodeResendAt возвращает момент, после которого можно отправить новый код
+func phoneCodeResendAt(expiresAt *time.Time) time.Time {
+	if expiresAt == nil {
+		return time.Time{}
+	}
+	return expiresAt.Add(-phoneCodeTTL).Add(phoneCodeResendInterval)
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"testing"
+	"time"
+)
+
+func TestPhoneToVerify(t *testing.T) {
+	phone := "+79123456789"
+	verifiedAt := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		user models.User
+		want string
+	}{
+		{"no phone", models.User{}, ""},
+		{"verified phone", models.User{Phone: &phone, PhoneVerifiedAt: &verifiedAt}, ""},
+		{"legacy unverified phone", models.User{Phone: &phone}, phone},
+		{"pending phone", models.User{Phone: &phone, PhoneVerifiedAt: &verifiedAt, PendingPhone: "+79000000000"}, "+79000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := phoneToVerify(tt.user); got != tt.want {
+				t.Errorf("phoneToVerify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhoneCodeResendAt(t *testing.T) {
+	if got := phoneCodeResendAt(nil); !got.IsZero() {
+		t.Errorf("phoneCodeResendAt(nil) = %v, want zero time", got)
+	}
+
+	sentAt := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	expiresAt := sentAt.Add(phoneCodeTTL)
+	if got := phoneCodeResendAt(&expiresAt); !got.Equal(sentAt.Add(phoneCodeResendInterval)) {
+		t.Errorf("phoneCodeResendAt() = %v, want %v", got, sentAt.Add(phoneCodeResendInterval))
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"strings"
+	"time"
+)
+
+// ProfileDTO представляет профиль пользователя
+type ProfileDTO struct {
+	ID            uint      `json:"id"`
+	FirstName     string    `json:"firstName"`
+	LastName      string    `json:"lastName"`
+	Email         string    `json:"email"`
+	Phone         string    `json:"phone,omitempty"`
+	PhoneVerified bool      `json:"phoneVerified"`
+	PendingPhone  string    `json:"pendingPhone,omitempty"`
+	PendingEmail  string    `json:"pendingEmail,omitempty"`
+	EmailVerified bool      `json:"emailVerified"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// UpdateProfileDTO представляет изменяемые данные профиля
+type UpdateProfileDTO struct {
+	FirstName string `json:"firstName" validate:"required,min=2,max=50"`
+	LastName  string `json:"lastName" validate:"required,min=2,max=50"`
+	// Phone номер телефона для переводов по номеру: nil — не менять, пустая строка — удалить.
+	// Новый номер начинает действовать после подтверждения кодом из SMS.
+	Phone *string `json:"phone" validate:"omitempty,max=20"`
+}
+
+// ChangePasswordDTO представляет запрос на смену пароля
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,password"`
+}
+
+// ChangeEmailDTO представляет запрос на смену email
+type ChangeEmailDTO struct {
+	Email    string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required"`
+}
+
+// CloseProfileDTO представляет запрос на закрытие профиля
+type CloseProfileDTO struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// ProfileService управляет профилем пользователя: данными, паролем и закрытием
+type ProfileService struct {
+	db       *gorm.DB
+	email    *EmailService
+	sessions *SessionService
+	apiKeys  *APIKeyService
+	phones   *PhoneVerificationService
+}
+
+// NewProfileService создает новый экземпляр ProfileService
+func NewProfileService(db *gorm.DB, email *EmailService, sessions *SessionService, apiKeys *APIKeyService, phones *PhoneVerificationService) *ProfileService {
+	return &ProfileService{
+		db:       db,
+		email:    email,
+		sessions: sessions,
+		apiKeys:  apiKeys,
+		phones:   phones,
+	}
+}
+
+// Get возвращает профиль пользователя
+func (s *ProfileService) Get(userID uint) (*ProfileDTO, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+	return toProfileDTO(user), nil
+}
+
+// Update изменяет имя, фамилию и номер телефона пользователя. Новый номер
+// сохраняется как ожидающий подтверждения, на него отправляется код из SMS.
+func (s *ProfileService) Update(userID uint, dto UpdateProfileDTO) (*ProfileDTO, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+
+	updates := map[string]interface{}{
+		"first_name": dto.FirstName,
+		"last_name":  dto.LastName,
+		"updated_at": time.Now(),
+	}
+	sendCode := false
+	if dto.Phone != nil {
+		if strings.TrimSpace(*dto.Phone) == "" {
+			updates["phone"] = nil
+			updates["phone_verified_at"] = nil
+			updates["pending_phone"] = ""
+			updates["phone_code_hash"] = ""
+			user.Phone = nil
+			user.PhoneVerifiedAt = nil
+			user.PendingPhone = ""
+		} else {
+			normalized, err := normalizePhone(*dto.Phone)
+			if err != nil {
+				return nil, err
+			}
+			if user.Phone == nil || *user.Phone != normalized || user.PhoneVerifiedAt == nil {
+				// Прежний код выдан для другого номера и больше не действует
+				updates["pending_phone"] = normalized
+				updates["phone_code_hash"] = ""
+				updates["phone_code_attempts"] = 0
+				user.PendingPhone = normalized
+				sendCode = true
+			} else {
+				updates["pending_phone"] = ""
+				updates["phone_code_hash"] = ""
+				user.PendingPhone = ""
+			}
+		}
+	}
+
+	// Номер без подтверждения не попадает в уникальный индекс, поэтому
+	// занятость номера другим клиентом по ответу не определить
+	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+		return nil, errors.New("не удалось обновить профиль")
+	}
+
+	if sendCode {
+		if err := s.phones.SendCode(userID); err != nil {
+			log.Printf("Код подтверждения телефона пользователю %d не отправлен: %v", userID, err)
+		}
+	}
+
+	user.FirstName = dto.FirstName
+	user.LastName = dto.LastName
+	return toProfileDTO(user), nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все сеансы, кроме текущего,
+// завершаются, API-ключи отзываются.
+func (s *ProfileService) ChangePassword(userID, currentSessionID uint, dto ChangePasswordDTO) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("пользователь не найден")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(dto.CurrentPassword)); err != nil {
+		return errors.New("неверный текущий пароль")
+	}
+	if dto.CurrentPassword == dto.NewPassword {
+		return errors.New("новый пароль должен отличаться от текущего")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(dto.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		return errors.New("не удалось изменить пароль")
+	}
+
+	if err := s.sessions.RevokeOthers(userID, currentSessionID); err != nil {
+		log.Printf("Ошибка завершения сеансов пользователя %d после смены пароля: %v", userID, err)
+	}
+	if err := s.apiKeys.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка отзыва API-ключей пользователя %d после смены пароля: %v", userID, err)
+	}
+	if err := s.email.SendPasswordChangedNotification(user.Email); err != nil {
+		log.Printf("Ошибка отправки уведомления о смене пароля: %v", err)
+	}
+	return nil
+}
+
+// Close закрывает профиль. Закрытие невозможно, пока на счетах есть средства
+// или долг и пока не погашены кредиты. Персональные данные обезличиваются,
+// счета, операции и кредиты сохраняются для отчетности.
+func (s *ProfileService) Close(userID uint, dto CloseProfileDTO) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("пользователь не найден")
+		}
+		if user.ClosedAt != nil {
+			return errors.New("профиль уже закрыт")
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(dto.Password)); err != nil {
+			return errors.New("неверный пароль")
+		}
+
+		var nonZero int64
+		if err := tx.Model(&models.BankAccount{}).
+			Where("holder_id = ? AND balance <> 0", userID).
+			Count(&nonZero).Error; err != nil {
+			return errors.New("ошибка при проверке счетов")
+		}
+		if nonZero > 0 {
+			return errors.New("выведите средства со всех счетов перед закрытием профиля")
+		}
+
+		var activeCredits int64
+		if err := tx.Model(&models.Credit{}).
+			Joins("JOIN bank_accounts ON bank_accounts.id = credits.account_id").
+			Where("bank_accounts.holder_id = ? AND credits.status IN ?", userID,
+				[]models.CreditStatus{models.CreditStatusActive, models.CreditStatusOverdue}).
+			Count(&activeCredits).Error; err != nil {
+			return errors.New("ошибка при проверке кредитов")
+		}
+		if activeCredits > 0 {
+			return errors.New("погасите кредиты перед закрытием профиля")
+		}
+
+		var restricted int64
+		if err := tx.Model(&models.BankAccount{}).
+			Where("holder_id = ? AND status IN ?", userID,
+				[]models.AccountStatus{models.AccountStatusFrozen, models.AccountStatusDebitBlocked}).
+			Count(&restricted).Error; err != nil {
+			return errors.New("ошибка при проверке счетов")
+		}
+		if restricted > 0 {
+			return errors.New("по вашим счетам действуют ограничения банка, обратитесь в поддержку")
+		}
+
+		// Счета закрытого профиля закрываются вместе с ним
+		now := time.Now()
+		if err := tx.Model(&models.BankAccount{}).
+			Where("holder_id = ? AND status <> ?", userID, models.AccountStatusClosed).
+			Updates(map[string]interface{}{
+				"status":     models.AccountStatusClosed,
+				"closed_at":  now,
+				"updated_at": now,
+			}).Error; err != nil {
+			return errors.New("не удалось закрыть счета")
+		}
+
+		// Поручения по счетам клиента и его поручения по чужим совместным счетам отменяются
+		if err := cancelStandingOrders(tx, tx.Model(&models.BankAccount{}).Select("id").Where("holder_id = ?", userID)); err != nil {
+			return errors.New("не удалось отменить постоянные поручения")
+		}
+		if err := tx.Model(&models.StandingOrder{}).
+			Where("user_id = ? AND status IN ?", userID, []models.StandingOrderStatus{models.StandingOrderActive, models.StandingOrderPaused, models.StandingOrderFailed}).
+			Updates(map[string]interface{}{
+				"status":      models.StandingOrderCancelled,
+				"next_run_at": nil,
+				"retry_at":    nil,
+				"updated_at":  now,
+			}).Error; err != nil {
+			return errors.New("не удалось отменить постоянные поручения")
+		}
+
+		return s.anonymize(tx, &user)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка завершения сеансов закрытого профиля %d: %v", userID, err)
+	}
+	log.Printf("Профиль пользователя %d закрыт", userID)
+	return nil
+}
+
+// anonymize удаляет персональные данные закрытого профиля и отключает доступ
+func (s *ProfileService) anonymize(tx *gorm.DB, user *models.User) error {
+	// Пароль заменяется случайным, которого никто не знает
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"first_name":        "Deleted",
+		"last_name":         "User",
+		"email":             fmt.Sprintf("closed-%d@deleted.invalid", user.ID),
+		"password":          string(hashedPassword),
+		"pending_email":     "",
+		"phone":             nil,
+		"phone_verified_at": nil,
+		"pending_phone":     "",
+		"phone_code_hash":   "",
+		"closed_at":         now,
+		"updated_at":        now,
+	}).Error; err != nil {
+		return errors.New("не удалось обезличить профиль")
+	}
+
+	// Карты закрытого профиля больше не должны проводить операции
+	if err := tx.Model(&models.Card{}).
+		Where("account_id IN (?) AND status IN ?",
+			tx.Model(&models.BankAccount{}).Select("id").Where("holder_id = ?", user.ID),
+			[]models.CardStatus{models.CardStatusActive, models.CardStatusIssued}).
+		Update("status", models.CardStatusBlocked).Error; err != nil {
+		return errors.New("не удалось заблокировать карты")
+	}
+
+	// Доступ к чужим совместным счетам и участники собственных счетов отключаются
+	if err := tx.Where("user_id = ? OR account_id IN (?)", user.ID,
+		tx.Model(&models.BankAccount{}).Select("id").Where("holder_id = ?", user.ID)).
+		Delete(&models.AccountMember{}).Error; err != nil {
+		return errors.New("не удалось отключить совместные счета")
+	}
+	if err := tx.Model(&models.AccountInvitation{}).
+		Where("account_id IN (?) AND accepted_at IS NULL AND revoked_at IS NULL",
+			tx.Model(&models.BankAccount{}).Select("id").Where("holder_id = ?", user.ID)).
+		Update("revoked_at", now).Error; err != nil {
+		return errors.New("не удалось отозвать приглашения к счетам")
+	}
+
+	if err := tx.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", now).Error; err != nil {
+		return errors.New("не удалось отозвать API-ключи")
+	}
+
+	// IP-адреса и устройства тоже относятся к персональным данным
+	if err := tx.Model(&models.SignInEvent{}).Where("user_id = ?", user.ID).
+		Updates(map[string]interface{}{"ip": "", "user_agent": ""}).Error; err != nil {
+		return errors.New("не удалось обезличить журнал входов")
+	}
+	if err := tx.Model(&models.Session{}).Where("user_id = ?", user.ID).
+		Updates(map[string]interface{}{"ip": "", "user_agent": ""}).Error; err != nil {
+		return errors.New("не удалось обезличить сеансы")
+	}
+	if err := tx.Model(&models.Notification{}).Where("user_id = ?", user.ID).
+		Update("recipient", "").Error; err != nil {
+		return errors.New("не удалось обезличить историю уведомлений")
+	}
+
+	// Сохраненные получатели содержат реквизиты третьих лиц
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.TransferTemplate{}).Error; err != nil {
+		return errors.New("не удалось удалить шаблоны переводов")
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.SavedPayee{}).Error; err != nil {
+		return errors.New("не удалось удалить сохраненных получателей")
+	}
+
+	for _, model := range []interface{}{
+		&models.UserTwoFactor{},
+		&models.RecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.UserToken{},
+	} {
+		if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return errors.New("не удалось удалить данные аутентификации")
+		}
+	}
+	return nil
+}
+
+func toProfileDTO(user models.User) *ProfileDTO {
+	var phone string
+	if user.Phone != nil {
+		phone = *user.Phone
+	}
+	return &ProfileDTO{
+		ID:            user.ID,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		Phone:         phone,
+		PhoneVerified: user.PhoneVerifiedAt != nil,
+		PendingPhone:  user.PendingPhone,
+		PendingEmail:  user.PendingEmail,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          string(user.Role),
+		CreatedAt:     user.CreatedAt,
+	}
+}
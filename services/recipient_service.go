@@ -0,0 +1,266 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// transferConfirmationTTL время, в течение которого можно подтвердить подготовленный перевод
+const transferConfirmationTTL = 5 * time.Minute
+
+// RecipientKind представляет способ указания получателя перевода
+type RecipientKind string
+
+const (
+	RecipientKindAccount RecipientKind = "account" // 20-значный номер счета
+	RecipientKindEmail   RecipientKind = "email"   // Email клиента банка
+	RecipientKindPhone   RecipientKind = "phone"   // Номер телефона клиента банка
+)
+
+// ErrRecipientNotFound возвращается, если получателя нет или он не может принять перевод.
+// Причина намеренно не уточняется, чтобы по ответу нельзя было узнать, есть ли клиент в банке.
+var ErrRecipientNotFound = errors.New("получатель не найден или не может принимать переводы")
+
+// ErrTransferConfirmationInvalid возвращается для неизвестного, истекшего или уже использованного подтверждения
+var ErrTransferConfirmationInvalid = errors.New("подтверждение перевода не найдено или истекло")
+
+// PrepareTransferDTO представляет перевод по номеру счета, email или телефону получателя
+type PrepareTransferDTO struct {
+	SourceID  uint    `json:"source_id" validate:"required"`
+	Recipient string  `json:"recipient" validate:"required,max=100"`
+	Amount    float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// ConfirmTransferDTO представляет подтверждение подготовленного перевода
+type ConfirmTransferDTO struct {
+	Token    string `json:"confirmation_token" validate:"required"`
+	TOTPCode string `json:"totp_code" validate:"omitempty,min=6,max=11"` // Код 2FA для переводов выше порога
+}
+
+// TransferPreviewDTO представляет данные получателя для подтверждения перевода
+type TransferPreviewDTO struct {
+	Token            string     `json:"confirmation_token,omitempty"`
+	RecipientName    string     `json:"recipient_name"`
+	RecipientAccount string     `json:"recipient_account"`
+	Amount           float64    `json:"amount"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+// RecipientService находит получателя перевода по номеру счета, email или телефону
+// и выполняет перевод после подтверждения
+type RecipientService struct {
+	db        *gorm.DB
+	bank      *BankService
+	twoFactor *TwoFactorService
+}
+
+// NewRecipientService создает новый экземпляр RecipientService
+func NewRecipientService(db *gorm.DB, bank *BankService, twoFactor *TwoFactorService) *RecipientService {
+	return &RecipientService{
+		db:        db,
+		bank:      bank,
+		twoFactor: twoFactor,
+	}
+}
+
+// Prepare находит получателя и возвращает его маскированные данные с токеном
+// подтверждения. Перевод выполняется только после вызова Confirm.
+func (s *RecipientService) Prepare(userID uint, dto PrepareTransferDTO) (*TransferPreviewDTO, error) {
+	source, err := AuthorizeAccount(s.db, userID, dto.SourceID, models.AccountActionSpend, dto.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountDebit(source); err != nil {
+		return nil, err
+	}
+
+	kind, value, err := parseRecipient(dto.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := s.resolve(kind, value)
+	if err != nil {
+		return nil, err
+	}
+	if destination.ID == source.ID {
+		return nil, errors.New("нельзя перевести средства на тот же счет")
+	}
+	if checkAccountCredit(destination) != nil {
+		return nil, ErrRecipientNotFound
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	confirmation := &models.TransferConfirmation{
+		UserID:           userID,
+		SourceID:         source.ID,
+		DestinationID:    destination.ID,
+		Amount:           dto.Amount,
+		RecipientName:    maskRecipientName(destination.Holder.FirstName, destination.Holder.LastName),
+		RecipientAccount: maskAccountNumber(destination.Number),
+		TokenHash:        utils.HashToken(token),
+		ExpiresAt:        time.Now().Add(transferConfirmationTTL),
+	}
+	if err := s.db.Create(confirmation).Error; err != nil {
+		return nil, errors.New("не удалось подготовить перевод")
+	}
+
+	preview := toTransferPreviewDTO(*confirmation)
+	preview.Token = token
+	preview.ExpiresAt = &confirmation.ExpiresAt
+	return preview, nil
+}
+
+// Confirm выполняет подготовленный перевод. Права на счет списания проверяются
+// повторно, токен подтверждения действует один раз.
+func (s *RecipientService) Confirm(userID uint, dto ConfirmTransferDTO) (*TransferPreviewDTO, error) {
+	var confirmation models.TransferConfirmation
+	if err := s.db.Where("token_hash = ? AND user_id = ?", utils.HashToken(dto.Token), userID).
+		First(&confirmation).Error; err != nil {
+		return nil, ErrTransferConfirmationInvalid
+	}
+	if confirmation.UsedAt != nil || time.Now().After(confirmation.ExpiresAt) {
+		return nil, ErrTransferConfirmationInvalid
+	}
+
+	if _, err := AuthorizeAccount(s.db, userID, confirmation.SourceID, models.AccountActionSpend, confirmation.Amount); err != nil {
+		return nil, err
+	}
+	// Неверный код 2FA не расходует подтверждение
+	if err := s.twoFactor.RequireForTransfer(userID, confirmation.Amount, dto.TOTPCode); err != nil {
+		return nil, err
+	}
+
+	// Помечаем подтверждение использованным до перевода, чтобы параллельный запрос не выполнил его дважды
+	result := s.db.Model(&models.TransferConfirmation{}).
+		Where("id = ? AND used_at IS NULL", confirmation.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, errors.New("ошибка при подтверждении перевода")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTransferConfirmationInvalid
+	}
+
+	if err := s.bank.Transfer(TransferRequest{
+		SourceID:      confirmation.SourceID,
+		DestinationID: confirmation.DestinationID,
+		Amount:        confirmation.Amount,
+		SpenderID:     confirmation.UserID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return toTransferPreviewDTO(confirmation), nil
+}
+
+// resolve находит счет получателя. По email и телефону выбирается первый
+// открытый счет клиента, владельцем которого он является.
+func (s *RecipientService) resolve(kind RecipientKind, value string) (*models.BankAccount, error) {
+	var account models.BankAccount
+	if kind == RecipientKindAccount {
+		if err := s.db.Preload("Holder").Where("number = ?", value).First(&account).Error; err != nil {
+			// Ключ рассчитывается по открытому алгоритму, поэтому опечатку можно назвать прямо
+			if err := utils.ValidateAccountNumber(s.bank.numbers.BIK(), value); err != nil {
+				return nil, err
+			}
+			return nil, ErrRecipientNotFound
+		}
+		if account.Holder.ClosedAt != nil {
+			return nil, ErrRecipientNotFound
+		}
+		return &account, nil
+	}
+
+	var user models.User
+	query := s.db.Where("closed_at IS NULL AND frozen_at IS NULL")
+	if kind == RecipientKindEmail {
+		query = query.Where("LOWER(email) = ? AND email_verified_at IS NOT NULL", value)
+	} else {
+		query = query.Where("phone = ? AND phone_verified_at IS NOT NULL", value)
+	}
+	if err := query.First(&user).Error; err != nil {
+		return nil, ErrRecipientNotFound
+	}
+
+	if err := s.db.Where("holder_id = ? AND status = ?", user.ID, models.AccountStatusActive).
+		Order("id").
+		First(&account).Error; err != nil {
+		return nil, ErrRecipientNotFound
+	}
+	account.Holder = user
+	return &account, nil
+}
+
+// parseRecipient определяет способ указания получателя и нормализует значение
+func parseRecipient(recipient string) (RecipientKind, string, error) {
+	recipient = strings.TrimSpace(recipient)
+	switch {
+	case strings.Contains(recipient, "@"):
+		return RecipientKindEmail, strings.ToLower(recipient), nil
+	case len(recipient) == 20 && isDigits(recipient):
+		return RecipientKindAccount, recipient, nil
+	}
+
+	phone, err := normalizePhone(recipient)
+	if err != nil {
+		return "", "", errors.New("укажите номер счета из 20 цифр, email или номер телефона")
+	}
+	return RecipientKindPhone, phone, nil
+}
+
+// normalizePhone приводит российский мобильный номер к формату +7XXXXXXXXXX.
+// Допускаются пробелы, скобки и дефисы, а также запись через 8.
+func normalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '(', r == ')':
+		default:
+			return "", errors.New("неверный формат номера телефона")
+		}
+	}
+
+	number := digits.String()
+	if len(number) == 11 && (number[0] == '7' || number[0] == '8') {
+		number = number[1:]
+	}
+	if len(number) != 10 || number[0] != '9' {
+		return "", errors.New("неверный формат номера телефона")
+	}
+	return "+7" + number, nil
+}
+
+// maskRecipientName возвращает имя получателя и первую букву фамилии: «Иван П.»
+func maskRecipientName(firstName, lastName string) string {
+	name := strings.TrimSpace(firstName)
+	if initial := []rune(strings.TrimSpace(lastName)); len(initial) > 0 {
+		name += " " + string(initial[0]) + "."
+	}
+	return name
+}
+
+// maskAccountNumber оставляет видимыми последние 4 цифры номера счета
+func maskAccountNumber(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+	return "**** " + number[len(number)-4:]
+}
+
+func toTransferPreviewDTO(confirmation models.TransferConfirmation) *TransferPreviewDTO {
+	return &TransferPreviewDTO{
+		RecipientName:    confirmation.RecipientName,
+		RecipientAccount: confirmation.RecipientAccount,
+		Amount:           confirmation.Amount,
+	}
+}
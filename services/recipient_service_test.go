@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestParseRecipient(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantKind  RecipientKind
+		wantValue string
+		wantErr   bool
+	}{
+		{"40817810000000000001", RecipientKindAccount, "40817810000000000001", false},
+		{" Ivan@Example.com ", RecipientKindEmail, "ivan@example.com", false},
+		{"+7 (912) 345-67-89", RecipientKindPhone, "+79123456789", false},
+		{"89123456789", RecipientKindPhone, "+79123456789", false},
+		{"9123456789", RecipientKindPhone, "+79123456789", false},
+		{"+7 495 123-45-67", "", "", true},    // городской номер
+		{"4081781000000000000", "", "", true}, // 19 цифр
+		{"12+3", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tc := range cases {
+		kind, value, err := parseRecipient(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseRecipient(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if kind != tc.wantKind || value != tc.wantValue {
+			t.Errorf("parseRecipient(%q) = %q, %q, want %q, %q", tc.input, kind, value, tc.wantKind, tc.wantValue)
+		}
+	}
+}
+
+func TestMaskRecipient(t *testing.T) {
+	if got := maskRecipientName("Иван", "Петров"); got != "Иван П." {
+		t.Errorf("maskRecipientName() = %q, want %q", got, "Иван П.")
+	}
+	if got := maskRecipientName("Иван", ""); got != "Иван" {
+		t.Errorf("maskRecipientName() without last name = %q, want %q", got, "Иван")
+	}
+	if got := maskAccountNumber("40817810000000000001"); got != "**** 0001" {
+		t.Errorf("maskAccountNumber() = %q, want %q", got, "**** 0001")
+	}
+}
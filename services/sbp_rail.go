@@ -0,0 +1,215 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// sbpRailName название Системы быстрых платежей в журнале платежей
+const sbpRailName = "SBP"
+
+// SBPSignatureHeader заголовок с подписью запросов и уведомлений СБП
+const SBPSignatureHeader = "X-SBP-Signature"
+
+// sbpMemberHeader заголовок с БИК банка-участника
+const sbpMemberHeader = "X-SBP-Member"
+
+// sbpPaymentRequest представляет исходящий платеж в формате API СБП
+type sbpPaymentRequest struct {
+	Reference    string  `json:"reference"`
+	PayerBIK     string  `json:"payer_bik"`
+	PayerAccount string  `json:"payer_account"`
+	PayerName    string  `json:"payer_name"`
+	PayeeBIK     string  `json:"payee_bik"`
+	PayeeAccount string  `json:"payee_account,omitempty"`
+	PayeePhone   string  `json:"payee_phone,omitempty"`
+	PayeeName    string  `json:"payee_name,omitempty"`
+	Amount       float64 `json:"amount"`
+	Purpose      string  `json:"purpose,omitempty"`
+}
+
+// sbpPaymentResponse представляет статус платежа в ответе API СБП
+type sbpPaymentResponse struct {
+	ID        string `json:"id"`
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// sbpNotification представляет уведомление СБП о входящем платеже или статусе исходящего
+type sbpNotification struct {
+	Type         string  `json:"type"`
+	ID           string  `json:"id"`
+	Reference    string  `json:"reference,omitempty"`
+	Status       string  `json:"status"`
+	Reason       string  `json:"reason,omitempty"`
+	Amount       float64 `json:"amount"`
+	PayerBIK     string  `json:"payer_bik,omitempty"`
+	PayerAccount string  `json:"payer_account,omitempty"`
+	PayerName    string  `json:"payer_name,omitempty"`
+	PayeeAccount string  `json:"payee_account,omitempty"`
+	PayeePhone   string  `json:"payee_phone,omitempty"`
+	Purpose      string  `json:"purpose,omitempty"`
+}
+
+// SBPRail передает платежи через HTTP API Системы быстрых платежей.
+// Запросы и уведомления подписываются HMAC-SHA256 с общим секретом.
+type SBPRail struct {
+	baseURL string
+	bik     string
+	secret  []byte
+	client  *http.Client
+}
+
+// NewSBPRail создает адаптер СБП с адресом, секретом и таймаутом из конфигурации
+func NewSBPRail(cfg *config.Config) *SBPRail {
+	return &SBPRail{
+		baseURL: cfg.SBPURL,
+		bik:     cfg.BankBIK,
+		secret:  []byte(cfg.SBPSecret),
+		client:  &http.Client{Timeout: time.Duration(cfg.SBPTimeout) * time.Second},
+	}
+}
+
+// Name возвращает название платежной системы
+func (r *SBPRail) Name() string {
+	return sbpRailName
+}
+
+// Send передает исходящий платеж в СБП
+func (r *SBPRail) Send(ctx context.Context, transfer RailTransfer) (*RailStatus, error) {
+	body, err := json.Marshal(sbpPaymentRequest{
+		Reference:    transfer.Reference,
+		PayerBIK:     transfer.PayerBIK,
+		PayerAccount: transfer.PayerAccount,
+		PayerName:    transfer.PayerName,
+		PayeeBIK:     transfer.PayeeBIK,
+		PayeeAccount: transfer.PayeeAccount,
+		PayeePhone:   transfer.PayeePhone,
+		PayeeName:    transfer.PayeeName,
+		Amount:       transfer.Amount,
+		Purpose:      transfer.Purpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.do(ctx, http.MethodPost, "/payments", body)
+}
+
+// Status запрашивает статус платежа в СБП
+func (r *SBPRail) Status(ctx context.Context, externalID string) (*RailStatus, error) {
+	return r.do(ctx, http.MethodGet, "/payments/"+url.PathEscape(externalID), nil)
+}
+
+// ParseNotification проверяет подпись уведомления СБП и разбирает его
+func (r *SBPRail) ParseNotification(body []byte, signature string) (*RailNotification, error) {
+	if !hmac.Equal([]byte(r.sign(body)), []byte(signature)) {
+		return nil, ErrRailSignatureInvalid
+	}
+
+	var notification sbpNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, errors.New("неверный формат уведомления")
+	}
+	if notification.ID == "" {
+		return nil, errors.New("в уведомлении нет идентификатора платежа")
+	}
+	status, err := parseSBPStatus(notification.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationType := RailNotificationType(notification.Type)
+	if notificationType != RailNotificationIncoming && notificationType != RailNotificationStatus {
+		return nil, fmt.Errorf("неизвестный тип уведомления %q", notification.Type)
+	}
+
+	return &RailNotification{
+		Type:         notificationType,
+		ExternalID:   notification.ID,
+		Reference:    notification.Reference,
+		Status:       status,
+		Reason:       notification.Reason,
+		Amount:       notification.Amount,
+		PayerBIK:     notification.PayerBIK,
+		PayerAccount: notification.PayerAccount,
+		PayerName:    notification.PayerName,
+		PayeeAccount: notification.PayeeAccount,
+		PayeePhone:   notification.PayeePhone,
+		Purpose:      notification.Purpose,
+	}, nil
+}
+
+// do выполняет подписанный запрос к API СБП и разбирает статус платежа.
+// Для GET-запросов подписывается путь запроса.
+func (r *SBPRail) do(ctx context.Context, method, path string, body []byte) (*RailStatus, error) {
+	signed := body
+	if body == nil {
+		signed = []byte(method + " " + path)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(sbpMemberHeader, r.bik)
+	request.Header.Set(SBPSignatureHeader, r.sign(signed))
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("СБП недоступна: %w", err)
+	}
+	defer response.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа СБП: %w", err)
+	}
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("СБП вернула статус %d", response.StatusCode)
+	}
+
+	var result sbpPaymentResponse
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil, fmt.Errorf("неверный формат ответа СБП: %w", err)
+	}
+	status, err := parseSBPStatus(result.Status)
+	if err != nil {
+		return nil, err
+	}
+	if result.ID == "" {
+		return nil, errors.New("в ответе СБП нет идентификатора платежа")
+	}
+
+	return &RailStatus{
+		ExternalID: result.ID,
+		Status:     status,
+		Reason:     result.Reason,
+	}, nil
+}
+
+// sign возвращает подпись данных общим секретом СБП
+func (r *SBPRail) sign(data []byte) string {
+	return utils.GenerateHMAC(string(data), r.secret)
+}
+
+// parseSBPStatus переводит статус СБП в статус межбанковского платежа
+func parseSBPStatus(status string) (models.InterbankStatus, error) {
+	switch models.InterbankStatus(status) {
+	case models.InterbankStatusSent, models.InterbankStatusSettled, models.InterbankStatusRejected, models.InterbankStatusReturned:
+		return models.InterbankStatus(status), nil
+	}
+	return "", fmt.Errorf("неизвестный статус платежа СБП %q", status)
+}
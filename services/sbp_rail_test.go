@@ -0,0 +1,184 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSBPServer имитирует API СБП: проверяет подпись, принимает платежи
+// и отдает их статус. Повторная отправка с тем же reference не создает дубль.
+type fakeSBPServer struct {
+	secret   []byte
+	status   models.InterbankStatus
+	mu       sync.Mutex
+	payments map[string]sbpPaymentResponse
+}
+
+func newFakeSBPServer(t *testing.T, secret string, status models.InterbankStatus) (*fakeSBPServer, *httptest.Server) {
+	fake := &fakeSBPServer{
+		secret:   []byte(secret),
+		status:   status,
+		payments: make(map[string]sbpPaymentResponse),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeSBPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	signed := body
+	if r.Method == http.MethodGet {
+		signed = []byte(r.Method + " " + r.URL.Path)
+	}
+	if r.Header.Get(SBPSignatureHeader) != utils.GenerateHMAC(string(signed), f.secret) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/payments":
+		var request sbpPaymentRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payment, ok := f.payments[request.Reference]
+		if !ok {
+			payment = sbpPaymentResponse{
+				ID:        fmt.Sprintf("SBP%d", len(f.payments)+1),
+				Reference: request.Reference,
+				Status:    string(f.status),
+			}
+			f.payments[request.Reference] = payment
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(payment)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/payments/"):
+		id := strings.TrimPrefix(r.URL.Path, "/payments/")
+		for _, payment := range f.payments {
+			if payment.ID == id {
+				payment.Status = string(models.InterbankStatusSettled)
+				json.NewEncoder(w).Encode(payment)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestSBPRail(url, secret string) *SBPRail {
+	return NewSBPRail(&config.Config{SBPURL: url, SBPSecret: secret, BankBIK: "044525999", SBPTimeout: 5})
+}
+
+func TestSBPRailSendAndStatus(t *testing.T) {
+	_, server := newFakeSBPServer(t, "secret", models.InterbankStatusSent)
+	rail := newTestSBPRail(server.URL, "secret")
+
+	transfer := RailTransfer{Reference: "OUT-1", PayeeBIK: "044525225", PayeePhone: "+79161234567", Amount: 100}
+	sent, err := rail.Send(context.Background(), transfer)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if sent.Status != models.InterbankStatusSent || sent.ExternalID == "" {
+		t.Fatalf("Send() = %+v, want SENT with external id", sent)
+	}
+
+	// Повторная отправка с тем же reference возвращает тот же платеж
+	again, err := rail.Send(context.Background(), transfer)
+	if err != nil || again.ExternalID != sent.ExternalID {
+		t.Fatalf("повторный Send() = %+v, %v, want external id %s", again, err, sent.ExternalID)
+	}
+
+	status, err := rail.Status(context.Background(), sent.ExternalID)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Status != models.InterbankStatusSettled {
+		t.Errorf("Status() = %s, want SETTLED", status.Status)
+	}
+
+	if _, err := rail.Status(context.Background(), "unknown"); err == nil {
+		t.Error("Status() для неизвестного платежа: ожидалась ошибка")
+	}
+}
+
+func TestSBPRailWrongSecret(t *testing.T) {
+	_, server := newFakeSBPServer(t, "secret", models.InterbankStatusSent)
+	rail := newTestSBPRail(server.URL, "other")
+
+	if _, err := rail.Send(context.Background(), RailTransfer{Reference: "OUT-1", Amount: 1}); err == nil {
+		t.Error("Send() с неверным секретом: ожидалась ошибка")
+	}
+}
+
+func TestSBPRailParseNotification(t *testing.T) {
+	rail := newTestSBPRail("http://localhost", "secret")
+	body := []byte(`{"type":"INCOMING","id":"SBP7","status":"SETTLED","amount":250.5,"payer_bik":"044525225","payee_phone":"+79161234567"}`)
+	signature := utils.GenerateHMAC(string(body), []byte("secret"))
+
+	notification, err := rail.ParseNotification(body, signature)
+	if err != nil {
+		t.Fatalf("ParseNotification() error = %v", err)
+	}
+	if notification.Type != RailNotificationIncoming || notification.ExternalID != "SBP7" || notification.Amount != 250.5 {
+		t.Errorf("ParseNotification() = %+v", notification)
+	}
+
+	if _, err := rail.ParseNotification(body, "bad"); !errors.Is(err, ErrRailSignatureInvalid) {
+		t.Errorf("неверная подпись: error = %v, want ErrRailSignatureInvalid", err)
+	}
+
+	unknown := []byte(`{"type":"INCOMING","id":"SBP8","status":"LOST"}`)
+	if _, err := rail.ParseNotification(unknown, utils.GenerateHMAC(string(unknown), []byte("secret"))); err == nil {
+		t.Error("неизвестный статус: ожидалась ошибка")
+	}
+}
+
+func TestCanChangeInterbankStatus(t *testing.T) {
+	cases := []struct {
+		from, to models.InterbankStatus
+		want     bool
+	}{
+		{models.InterbankStatusCreated, models.InterbankStatusSent, true},
+		{models.InterbankStatusCreated, models.InterbankStatusSettled, true},
+		{models.InterbankStatusCreated, models.InterbankStatusRejected, true},
+		{models.InterbankStatusCreated, models.InterbankStatusReturned, false},
+		{models.InterbankStatusSent, models.InterbankStatusSettled, true},
+		{models.InterbankStatusSent, models.InterbankStatusRejected, true},
+		{models.InterbankStatusSent, models.InterbankStatusCreated, false},
+		{models.InterbankStatusSettled, models.InterbankStatusReturned, true},
+		{models.InterbankStatusSettled, models.InterbankStatusRejected, false},
+		{models.InterbankStatusRejected, models.InterbankStatusSettled, false},
+		{models.InterbankStatusReturned, models.InterbankStatusSettled, false},
+	}
+
+	for _, tc := range cases {
+		if got := canChangeInterbankStatus(tc.from, tc.to); got != tc.want {
+			t.Errorf("canChangeInterbankStatus(%s, %s) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+
+	if id, ok := parseInterbankReference("OUT-42"); !ok || id != 42 {
+		t.Errorf("parseInterbankReference(OUT-42) = %d, %v", id, ok)
+	}
+	if _, ok := parseInterbankReference("SBP42"); ok {
+		t.Error("parseInterbankReference(SBP42): ожидалась ошибка")
+	}
+}
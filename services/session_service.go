@@ -0,0 +1,310 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// refreshTokenLength длина refresh-токена в байтах
+const refreshTokenLength = 32
+
+// ErrInvalidRefreshToken возвращается при недействительном refresh-токене
+var ErrInvalidRefreshToken = errors.New("недействительный refresh-токен")
+
+// SessionDTO представляет активный сеанс пользователя
+type SessionDTO struct {
+	ID         uint      `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // Сеанс, из которого выполнен запрос
+}
+
+// SessionService управляет сеансами пользователей и refresh-токенами
+type SessionService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewSessionService создает новый экземпляр SessionService
+func NewSessionService(db *gorm.DB, cfg *config.Config) *SessionService {
+	return &SessionService{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// Create открывает новый сеанс и возвращает его вместе с refresh-токеном
+func (s *SessionService) Create(userID uint, meta RequestMeta) (*models.Session, string, error) {
+	refreshToken, err := utils.GenerateSecureToken(refreshTokenLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:           userID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		IP:               meta.IP,
+		UserAgent:        truncate(meta.UserAgent, 255),
+		ExpiresAt:        now.Add(s.refreshTTL()),
+		LastUsedAt:       now,
+	}
+
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, "", errors.New("не удалось создать сеанс")
+	}
+
+	return session, refreshToken, nil
+}
+
+// Rotate обменивает refresh-токен на новый. Предъявленный токен становится недействительным
+// и запоминается как замененный. Повторное предъявление замененного токена считается
+// признаком кражи: в этом случае отзываются все сеансы пользователя.
+func (s *SessionService) Rotate(refreshToken string, meta RequestMeta) (*models.Session, string, error) {
+	newRefreshToken, err := utils.GenerateSecureToken(refreshTokenLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tokenHash := utils.HashToken(refreshToken)
+	var session models.Session
+	var reuseDetected bool
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ?", tokenHash).
+			First(&session).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("ошибка при поиске сеанса")
+			}
+
+			// Токен уже заменен: находим сеанс, которому он принадлежал
+			var retired models.RetiredRefreshToken
+			if err := tx.Where("token_hash = ?", tokenHash).First(&retired).Error; err != nil {
+				return ErrInvalidRefreshToken
+			}
+			if err := tx.First(&session, retired.SessionID).Error; err != nil {
+				return ErrInvalidRefreshToken
+			}
+			reuseDetected = true
+			return ErrInvalidRefreshToken
+		}
+
+		// Токен завершенного сеанса недействителен, но это не признак кражи:
+		// клиент мог повторить запрос после выхода
+		if session.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if utils.IsExpired(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.Create(&models.RetiredRefreshToken{
+			TokenHash: tokenHash,
+			SessionID: session.ID,
+			ExpiresAt: session.ExpiresAt,
+		}).Error; err != nil {
+			return errors.New("не удалось обновить сеанс")
+		}
+
+		now := time.Now()
+		session.RefreshTokenHash = utils.HashToken(newRefreshToken)
+		session.LastUsedAt = now
+		session.IP = meta.IP
+		session.UserAgent = truncate(meta.UserAgent, 255)
+		return tx.Save(&session).Error
+	})
+
+	if reuseDetected {
+		log.Printf("Повторное использование refresh-токена сеанса %d, отзываем все сеансы пользователя %d", session.ID, session.UserID)
+		if err := s.RevokeAll(session.UserID); err != nil {
+			log.Printf("Ошибка отзыва сеансов пользователя %d: %v", session.UserID, err)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &session, newRefreshToken, nil
+}
+
+// BindAccessToken привязывает к сеансу идентификатор нового access-токена.
+// Предыдущий access-токен сеанса отзывается.
+func (s *SessionService) BindAccessToken(sessionID uint, jti string, expiresAt time.Time) error {
+	var session models.Session
+	if err := s.db.First(&session, sessionID).Error; err != nil {
+		return errors.New("сеанс не найден")
+	}
+
+	if session.AccessJTI != "" && session.AccessJTI != jti {
+		if err := s.revokeJTI(s.db, session.AccessJTI, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Model(&session).Update("access_jti", jti).Error
+}
+
+// Revoke завершает сеанс пользователя и отзывает его access-токен
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		if err := tx.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("сеанс не найден")
+			}
+			return errors.New("ошибка при поиске сеанса")
+		}
+
+		return s.revokeSessions(tx, []models.Session{session})
+	})
+}
+
+// RevokeAll завершает все сеансы пользователя («выход на всех устройствах»)
+func (s *SessionService) RevokeAll(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var sessions []models.Session
+		if err := tx.Where("user_id = ? AND revoked_at IS NULL", userID).Find(&sessions).Error; err != nil {
+			return errors.New("ошибка при получении сеансов")
+		}
+
+		return s.revokeSessions(tx, sessions)
+	})
+}
+
+// RevokeOthers завершает все сеансы пользователя, кроме текущего
+func (s *SessionService) RevokeOthers(userID, currentSessionID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var sessions []models.Session
+		if err := tx.Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, currentSessionID).Find(&sessions).Error; err != nil {
+			return errors.New("ошибка при получении сеансов")
+		}
+
+		return s.revokeSessions(tx, sessions)
+	})
+}
+
+// ListActive возвращает действующие сеансы пользователя, начиная с последнего использованного
+func (s *SessionService) ListActive(userID, currentSessionID uint) ([]SessionDTO, error) {
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, errors.New("ошибка при получении сеансов")
+	}
+
+	return toSessionDTOs(sessions, currentSessionID), nil
+}
+
+// toSessionDTOs преобразует сеансы в DTO и отмечает текущий сеанс
+func toSessionDTOs(sessions []models.Session, currentSessionID uint) []SessionDTO {
+	result := make([]SessionDTO, len(sessions))
+	for i, session := range sessions {
+		result[i] = SessionDTO{
+			ID:         session.ID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		}
+	}
+	return result
+}
+
+// IsActive проверяет, что сеанс не отозван и не истек, а access-токен не отозван
+func (s *SessionService) IsActive(sessionID uint, jti string) bool {
+	var session models.Session
+	if err := s.db.Select("id", "expires_at", "revoked_at").First(&session, sessionID).Error; err != nil {
+		return false
+	}
+	if session.RevokedAt != nil || utils.IsExpired(session.ExpiresAt) {
+		return false
+	}
+
+	var revoked int64
+	if err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&revoked).Error; err != nil {
+		return false
+	}
+	return revoked == 0
+}
+
+// revokeSessions отмечает сеансы отозванными и отзывает их текущие access-токены
+func (s *SessionService) revokeSessions(tx *gorm.DB, sessions []models.Session) error {
+	now := time.Now()
+	// Access-токен не живет дольше своего времени жизни, после этого запись не нужна
+	accessExpiresAt := now.Add(s.accessTTL())
+
+	for _, session := range sessions {
+		if session.RevokedAt == nil {
+			if err := tx.Model(&models.Session{}).Where("id = ?", session.ID).Update("revoked_at", now).Error; err != nil {
+				return errors.New("не удалось отозвать сеанс")
+			}
+		}
+		if session.AccessJTI != "" {
+			if err := s.revokeJTI(tx, session.AccessJTI, accessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// revokeJTI добавляет идентификатор access-токена в список отозванных
+func (s *SessionService) revokeJTI(tx *gorm.DB, jti string, expiresAt time.Time) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		return errors.New("не удалось отозвать токен")
+	}
+	return nil
+}
+
+// CleanupExpired удаляет истекшие записи об отозванных и замененных токенах
+func (s *SessionService) CleanupExpired() error {
+	now := time.Now()
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("expires_at < ?", now).Delete(&models.RetiredRefreshToken{}).Error
+}
+
+// StartCleanup запускает фоновую очистку истекших отозванных токенов
+func (s *SessionService) StartCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.CleanupExpired(); err != nil {
+					log.Printf("Ошибка при очистке отозванных токенов: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// AccessTTL возвращает время жизни access-токена
+func (s *SessionService) AccessTTL() time.Duration {
+	return s.accessTTL()
+}
+
+func (s *SessionService) accessTTL() time.Duration {
+	return time.Duration(s.config.JWT.ExpiresIn) * time.Minute
+}
+
+func (s *SessionService) refreshTTL() time.Duration {
+	return time.Duration(s.config.JWT.RefreshExpiresIn) * time.Hour
+}
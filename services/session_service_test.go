@@ -0,0 +1,33 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"testing"
+	"time"
+)
+
+func TestToSessionDTOs(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	sessions := []models.Session{
+		{ID: 3, IP: "10.0.0.1", UserAgent: "okhttp/4.12.0", LastUsedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: 5, IP: "10.0.0.2", UserAgent: "Mozilla/5.0", LastUsedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+	}
+
+	dtos := toSessionDTOs(sessions, 5)
+	if len(dtos) != 2 {
+		t.Fatalf("len = %d, want 2", len(dtos))
+	}
+	if dtos[0].ID != 3 || dtos[0].Current {
+		t.Errorf("dtos[0] = %+v, want session 3 not current", dtos[0])
+	}
+	if dtos[1].ID != 5 || !dtos[1].Current {
+		t.Errorf("dtos[1] = %+v, want session 5 current", dtos[1])
+	}
+	if dtos[0].IP != "10.0.0.1" || dtos[0].UserAgent != "okhttp/4.12.0" || !dtos[0].LastUsedAt.Equal(now) {
+		t.Errorf("dtos[0] fields not copied: %+v", dtos[0])
+	}
+
+	if got := toSessionDTOs(nil, 5); got == nil || len(got) != 0 {
+		t.Errorf("toSessionDTOs(nil) = %#v, want empty slice", got)
+	}
+}
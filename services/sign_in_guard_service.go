@@ -0,0 +1,126 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// ErrAccountLocked возвращается, когда вход заблокирован после неудачных попыток
+var ErrAccountLocked = errors.New("вход временно заблокирован из-за неудачных попыток")
+
+// SignInGuard защищает вход от перебора паролей: после серии неудачных попыток
+// учетная запись блокируется, и каждая следующая блокировка вдвое дольше предыдущей
+type SignInGuard struct {
+	db     *gorm.DB
+	config *config.Config
+	email  *EmailService
+}
+
+// NewSignInGuard создает новый экземпляр SignInGuard
+func NewSignInGuard(db *gorm.DB, cfg *config.Config, email *EmailService) *SignInGuard {
+	return &SignInGuard{
+		db:     db,
+		config: cfg,
+		email:  email,
+	}
+}
+
+// CheckLocked возвращает ErrAccountLocked, если вход для пользователя заблокирован
+func (g *SignInGuard) CheckLocked(user *models.User) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return fmt.Errorf("%w до %s", ErrAccountLocked, user.LockedUntil.Format("02.01.2006 15:04"))
+	}
+	return nil
+}
+
+// RecordFailure учитывает неудачную попытку входа и при достижении порога
+// блокирует вход с уведомлением владельца
+func (g *SignInGuard) RecordFailure(userID uint, ip string) {
+	var user models.User
+	var locked bool
+	var attempts int
+
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем строку, чтобы параллельные попытки не потеряли счетчик
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		user.FailedSignIns++
+		attempts = user.FailedSignIns
+		updates := map[string]interface{}{"failed_sign_ins": user.FailedSignIns}
+
+		if user.FailedSignIns >= g.config.SignInMaxFailures {
+			lockedUntil := time.Now().Add(g.lockoutDuration(user.LockoutLevel))
+			updates["locked_until"] = lockedUntil
+			updates["lockout_level"] = user.LockoutLevel + 1
+			updates["failed_sign_ins"] = 0
+			user.LockedUntil = &lockedUntil
+			locked = true
+		}
+
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if err != nil {
+		log.Printf("Ошибка учета неудачного входа пользователя %d: %v", userID, err)
+		return
+	}
+
+	if locked {
+		log.Printf("Вход пользователя %d заблокирован до %s", userID, user.LockedUntil.Format(time.RFC3339))
+		if err := g.email.SendAccountLockedNotification(user.Email, attempts, *user.LockedUntil, ip); err != nil {
+			log.Printf("Ошибка отправки уведомления о блокировке входа: %v", err)
+		}
+	}
+}
+
+// RecordSuccess сбрасывает счетчики после успешного входа
+func (g *SignInGuard) RecordSuccess(user *models.User) {
+	if user.FailedSignIns == 0 && user.LockoutLevel == 0 && user.LockedUntil == nil {
+		return
+	}
+
+	if err := g.db.Model(user).Updates(map[string]interface{}{
+		"failed_sign_ins": 0,
+		"lockout_level":   0,
+		"locked_until":    nil,
+	}).Error; err != nil {
+		log.Printf("Ошибка сброса счетчика входов пользователя %d: %v", user.ID, err)
+	}
+}
+
+// Unlock снимает блокировку входа в рамках транзакции tx (действие сотрудника банка)
+func (g *SignInGuard) Unlock(tx *gorm.DB, userID uint) error {
+	result := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_sign_ins": 0,
+		"lockout_level":   0,
+		"locked_until":    nil,
+	})
+	if result.Error != nil {
+		return errors.New("не удалось снять блокировку")
+	}
+	if result.RowsAffected == 0 {
+		return ErrAdminTargetNotFound
+	}
+	return nil
+}
+
+// lockoutDuration возвращает длительность блокировки для уровня:
+// базовый срок, удваиваемый за каждую предыдущую блокировку, но не больше максимума
+func (g *SignInGuard) lockoutDuration(level int) time.Duration {
+	duration := time.Duration(g.config.SignInLockout) * time.Minute
+	maxDuration := time.Duration(g.config.SignInMaxLockout) * time.Minute
+	for i := 0; i < level && duration < maxDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxDuration {
+		duration = maxDuration
+	}
+	return duration
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+// signInHistoryLimit максимальное количество записей журнала входов в ответе
+const signInHistoryLimit = 50
+
+// SignInEventDTO представляет запись журнала входов
+type SignInEventDTO struct {
+	ID        uint      `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignInHistory ведет журнал входов и уведомляет о входе с нового устройства
+type SignInHistory struct {
+	db    *gorm.DB
+	email *EmailService
+}
+
+// NewSignInHistory создает новый экземпляр SignInHistory
+func NewSignInHistory(db *gorm.DB, email *EmailService) *SignInHistory {
+	return &SignInHistory{
+		db:    db,
+		email: email,
+	}
+}
+
+// Record записывает попытку входа. Ошибки записи не прерывают вход.
+func (h *SignInHistory) Record(userID uint, meta RequestMeta, outcome models.SignInOutcome) {
+	if err := h.db.Create(newSignInEvent(userID, meta, outcome)).Error; err != nil {
+		log.Printf("Ошибка записи в журнал входов пользователя %d: %v", userID, err)
+	}
+}
+
+// RecordSuccess записывает успешный вход. Если пользователь уже входил раньше,
+// но не с этого устройства, владельцу отправляется уведомление.
+func (h *SignInHistory) RecordSuccess(user *models.User, meta RequestMeta) {
+	fingerprint := DeviceFingerprint(meta)
+
+	var known, total int64
+	if err := h.db.Model(&models.SignInEvent{}).
+		Where("user_id = ? AND outcome = ?", user.ID, models.SignInOutcomeSuccess).
+		Count(&total).Error; err != nil {
+		log.Printf("Ошибка чтения журнала входов пользователя %d: %v", user.ID, err)
+	}
+	if total > 0 {
+		if err := h.db.Model(&models.SignInEvent{}).
+			Where("user_id = ? AND device_fingerprint = ? AND outcome = ?", user.ID, fingerprint, models.SignInOutcomeSuccess).
+			Count(&known).Error; err != nil {
+			log.Printf("Ошибка чтения журнала входов пользователя %d: %v", user.ID, err)
+			known = 1
+		}
+	}
+
+	h.Record(user.ID, meta, models.SignInOutcomeSuccess)
+
+	if isNewDevice(total, known) {
+		if err := h.email.SendNewDeviceSignInNotification(user.Email, meta.IP, meta.UserAgent, time.Now()); err != nil {
+			log.Printf("Ошибка отправки уведомления о входе с нового устройства: %v", err)
+		}
+	}
+}
+
+// List возвращает последние попытки входа пользователя
+func (h *SignInHistory) List(userID uint) ([]SignInEventDTO, error) {
+	var events []models.SignInEvent
+	if err := h.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(signInHistoryLimit).
+		Find(&events).Error; err != nil {
+		return nil, errors.New("ошибка при получении журнала входов")
+	}
+
+	result := make([]SignInEventDTO, len(events))
+	for i, event := range events {
+		result[i] = SignInEventDTO{
+			ID:        event.ID,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Outcome:   string(event.Outcome),
+			CreatedAt: event.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// newSignInEvent создает запись журнала входов с отпечатком устройства
+func newSignInEvent(userID uint, meta RequestMeta, outcome models.SignInOutcome) *models.SignInEvent {
+	return &models.SignInEvent{
+		UserID:            userID,
+		IP:                meta.IP,
+		UserAgent:         truncate(meta.UserAgent, 255),
+		DeviceFingerprint: DeviceFingerprint(meta),
+		Outcome:           outcome,
+	}
+}
+
+// isNewDevice определяет, нужно ли уведомлять о входе: total — число прежних
+// успешных входов пользователя, known — число успешных входов с этого устройства.
+// Первый вход после регистрации новым устройством не считается.
+func isNewDevice(total, known int64) bool {
+	return total > 0 && known == 0
+}
+
+// DeviceFingerprint вычисляет отпечаток устройства по идентификатору устройства
+// (если клиент его передает) и User-Agent. IP-адрес не учитывается: он меняется
+// при смене сети, и уведомления приходили бы слишком часто.
+func DeviceFingerprint(meta RequestMeta) string {
+	return utils.HashToken(strings.TrimSpace(meta.DeviceID) + "|" + strings.ToLower(strings.TrimSpace(meta.UserAgent)))
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"strings"
+	"testing"
+)
+
+func TestIsNewDevice(t *testing.T) {
+	cases := []struct {
+		name         string
+		total, known int64
+		want         bool
+	}{
+		{name: "первый вход после регистрации", total: 0, known: 0, want: false},
+		{name: "вход с известного устройства", total: 3, known: 2, want: false},
+		{name: "вход с нового устройства", total: 3, known: 0, want: true},
+	}
+	for _, tc := range cases {
+		if got := isNewDevice(tc.total, tc.known); got != tc.want {
+			t.Errorf("%s: isNewDevice(%d, %d) = %v, want %v", tc.name, tc.total, tc.known, got, tc.want)
+		}
+	}
+}
+
+func TestDeviceFingerprint(t *testing.T) {
+	base := RequestMeta{IP: "10.0.0.1", UserAgent: "Mozilla/5.0 (X11; Linux)"}
+
+	// Смена сети не делает устройство новым
+	otherNetwork := base
+	otherNetwork.IP = "192.168.1.10"
+	if DeviceFingerprint(base) != DeviceFingerprint(otherNetwork) {
+		t.Error("fingerprint depends on IP")
+	}
+
+	sameAgent := base
+	sameAgent.UserAgent = "  MOZILLA/5.0 (x11; linux) "
+	if DeviceFingerprint(base) != DeviceFingerprint(sameAgent) {
+		t.Error("fingerprint depends on User-Agent case or spacing")
+	}
+
+	otherDevice := base
+	otherDevice.DeviceID = "device-2"
+	if DeviceFingerprint(base) == DeviceFingerprint(otherDevice) {
+		t.Error("fingerprint ignores X-Device-ID")
+	}
+
+	otherAgent := base
+	otherAgent.UserAgent = "okhttp/4.12.0"
+	if DeviceFingerprint(base) == DeviceFingerprint(otherAgent) {
+		t.Error("fingerprint ignores User-Agent")
+	}
+}
+
+func TestNewSignInEvent(t *testing.T) {
+	meta := RequestMeta{IP: "10.0.0.1", UserAgent: strings.Repeat("a", 300), DeviceID: "device-1"}
+
+	event := newSignInEvent(7, meta, models.SignInOutcomeSuccess)
+	if event.UserID != 7 || event.IP != meta.IP || event.Outcome != models.SignInOutcomeSuccess {
+		t.Errorf("event = %+v", event)
+	}
+	if len(event.UserAgent) != 255 {
+		t.Errorf("UserAgent length = %d, want 255", len(event.UserAgent))
+	}
+	if event.DeviceFingerprint != DeviceFingerprint(meta) {
+		t.Error("fingerprint computed from truncated User-Agent")
+	}
+}
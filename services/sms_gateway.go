@@ -0,0 +1,73 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// PhoneCodeSender доставляет код подтверждения на номер телефона
+type PhoneCodeSender interface {
+	SendPhoneCode(phone, code string) error
+}
+
+// smsMessage представляет сообщение в формате API SMS-шлюза
+type smsMessage struct {
+	Phone string `json:"phone"`
+	Text  string `json:"text"`
+}
+
+// SMSGateway отправляет SMS через HTTP API шлюза с токеном доступа
+type SMSGateway struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewSMSGateway создает клиент SMS-шлюза с адресом и токеном из конфигурации
+func NewSMSGateway(cfg *config.Config) *SMSGateway {
+	return &SMSGateway{
+		baseURL: cfg.SMSGatewayURL,
+		token:   cfg.SMSGatewayToken,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendPhoneCode отправляет код подтверждения телефона
+func (g *SMSGateway) SendPhoneCode(phone, code string) error {
+	if g.baseURL == "" {
+		return errors.New("отправка SMS не настроена (SMS_GATEWAY_URL)")
+	}
+
+	body, err := json.Marshal(smsMessage{
+		Phone: phone,
+		Text:  fmt.Sprintf("Код подтверждения телефона: %s. Никому не сообщайте его.", code),
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, g.baseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		request.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("SMS-шлюз недоступен: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("SMS-шлюз вернул статус %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,666 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"strings"
+	"time"
+)
+
+// standingOrderDateLayout формат дат постоянного поручения в API
+const standingOrderDateLayout = "2006-01-02"
+
+// standingOrderMaxAttempts число попыток перевода при нехватке средств, после которого перевод пропускается
+const standingOrderMaxAttempts = 4
+
+// standingOrderRetryInterval интервал между попытками перевода при нехватке средств
+const standingOrderRetryInterval = 6 * time.Hour
+
+// standingOrderLease время, на которое поручение резервируется за обработчиком. Если обработчик
+// завершится до записи результата, поручение будет выполнено повторно по истечении этого времени.
+const standingOrderLease = 10 * time.Minute
+
+// ErrStandingOrderNotFound возвращается для неизвестного или чужого поручения
+var ErrStandingOrderNotFound = errors.New("постоянное поручение не найдено")
+
+// CreateStandingOrderDTO представляет создание постоянного поручения
+type CreateStandingOrderDTO struct {
+	SourceID      uint    `json:"source_id" validate:"required"`
+	DestinationID uint    `json:"destination_id" validate:"required"`
+	Amount        float64 `json:"amount" validate:"required,gt=0"`
+	Description   string  `json:"description" validate:"omitempty,max=140"`
+	Schedule      string  `json:"schedule" validate:"required,oneof=ONCE WEEKLY MONTHLY LAST_BUSINESS_DAY"`
+	DayOfMonth    int     `json:"day_of_month" validate:"omitempty,min=1,max=31"` // Обязательно для MONTHLY
+	StartDate     string  `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate       string  `json:"end_date" validate:"omitempty,datetime=2006-01-02"`
+	MaxRuns       int     `json:"max_runs" validate:"omitempty,min=1"`
+	TOTPCode      string  `json:"totp_code" validate:"omitempty,min=6,max=11"` // Код 2FA для сумм выше порога
+}
+
+// UpdateStandingOrderDTO представляет изменение постоянного поручения.
+// Незаполненные поля не меняются; пустая end_date и max_runs = 0 снимают ограничение.
+type UpdateStandingOrderDTO struct {
+	Amount      *float64 `json:"amount" validate:"omitempty,gt=0"`
+	Description *string  `json:"description" validate:"omitempty,max=140"`
+	DayOfMonth  *int     `json:"day_of_month" validate:"omitempty,min=1,max=31"`
+	EndDate     *string  `json:"end_date" validate:"omitempty,max=10"`
+	MaxRuns     *int     `json:"max_runs" validate:"omitempty,min=0"`
+	TOTPCode    string   `json:"totp_code" validate:"omitempty,min=6,max=11"`
+}
+
+// StandingOrderDTO представляет постоянное поручение в ответах API
+type StandingOrderDTO struct {
+	ID            uint       `json:"id"`
+	SourceID      uint       `json:"source_id"`
+	DestinationID uint       `json:"destination_id"`
+	Amount        float64    `json:"amount"`
+	Description   string     `json:"description,omitempty"`
+	Schedule      string     `json:"schedule"`
+	DayOfMonth    int        `json:"day_of_month,omitempty"`
+	StartDate     string     `json:"start_date"`
+	EndDate       string     `json:"end_date,omitempty"`
+	MaxRuns       *int       `json:"max_runs,omitempty"`
+	RunCount      int        `json:"run_count"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	RetryAt       *time.Time `json:"retry_at,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Status        string     `json:"status"`
+}
+
+// StandingOrderService управляет постоянными поручениями и выполняет их переводом
+// через BankService по расписанию
+type StandingOrderService struct {
+	db        *gorm.DB
+	bank      *BankService
+	twoFactor *TwoFactorService
+	email     *EmailService
+}
+
+// NewStandingOrderService создает новый экземпляр StandingOrderService
+func NewStandingOrderService(db *gorm.DB, bank *BankService, twoFactor *TwoFactorService, email *EmailService) *StandingOrderService {
+	return &StandingOrderService{
+		db:        db,
+		bank:      bank,
+		twoFactor: twoFactor,
+		email:     email,
+	}
+}
+
+// Create создает постоянное поручение. Пользователь должен иметь право списания
+// со счета на сумму поручения; для сумм выше порога нужен код 2FA.
+func (s *StandingOrderService) Create(userID uint, dto CreateStandingOrderDTO) (*StandingOrderDTO, error) {
+	if dto.SourceID == dto.DestinationID {
+		return nil, errors.New("нельзя перевести средства на тот же счет")
+	}
+
+	order := &models.StandingOrder{
+		UserID:        userID,
+		SourceID:      dto.SourceID,
+		DestinationID: dto.DestinationID,
+		Amount:        dto.Amount,
+		Description:   strings.TrimSpace(dto.Description),
+		Schedule:      models.StandingOrderSchedule(dto.Schedule),
+		Status:        models.StandingOrderActive,
+	}
+	if order.Schedule == models.StandingOrderMonthly {
+		if dto.DayOfMonth == 0 {
+			return nil, errors.New("для ежемесячного поручения укажите day_of_month")
+		}
+		order.DayOfMonth = dto.DayOfMonth
+	}
+
+	startDate, err := parseStandingOrderDate(dto.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	if startDate.Before(bankDate(time.Now())) {
+		return nil, errors.New("дата начала не может быть в прошлом")
+	}
+	order.StartDate = startDate
+	if err := setStandingOrderEnd(order, &dto.EndDate, &dto.MaxRuns); err != nil {
+		return nil, err
+	}
+
+	source, err := AuthorizeAccount(s.db, userID, dto.SourceID, models.AccountActionSpend, dto.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountDebit(source); err != nil {
+		return nil, err
+	}
+	var destination models.BankAccount
+	if err := s.db.First(&destination, dto.DestinationID).Error; err != nil {
+		return nil, errors.New("счет получателя не найден")
+	}
+	if err := checkAccountCredit(&destination); err != nil {
+		return nil, fmt.Errorf("счет получателя недоступен: %w", err)
+	}
+	if err := s.twoFactor.RequireForTransfer(userID, dto.Amount, dto.TOTPCode); err != nil {
+		return nil, err
+	}
+
+	next, ok := nextStandingOrderRun(order, order.StartDate)
+	if !ok {
+		return nil, errors.New("по расписанию не будет ни одного перевода до даты окончания")
+	}
+	order.NextRunAt = &next
+
+	if err := s.db.Create(order).Error; err != nil {
+		return nil, errors.New("не удалось создать постоянное поручение")
+	}
+	return toStandingOrderDTO(*order), nil
+}
+
+// List возвращает постоянные поручения пользователя
+func (s *StandingOrderService) List(userID uint) ([]StandingOrderDTO, error) {
+	var orders []models.StandingOrder
+	if err := s.db.Where("user_id = ?", userID).Order("id DESC").Find(&orders).Error; err != nil {
+		return nil, errors.New("ошибка при получении постоянных поручений")
+	}
+
+	result := make([]StandingOrderDTO, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, *toStandingOrderDTO(order))
+	}
+	return result, nil
+}
+
+// Update изменяет сумму, назначение, число месяца или условия окончания поручения.
+// Дата очередного перевода пересчитывается.
+func (s *StandingOrderService) Update(userID, orderID uint, dto UpdateStandingOrderDTO) (*StandingOrderDTO, error) {
+	if dto.Amount != nil {
+		if err := s.twoFactor.RequireForTransfer(userID, *dto.Amount, dto.TOTPCode); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *models.StandingOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockStandingOrder(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+		if !isStandingOrderOpen(order.Status) {
+			return errors.New("завершенное или отмененное поручение нельзя изменить")
+		}
+
+		if dto.Amount != nil {
+			if _, err := AuthorizeAccount(tx, userID, order.SourceID, models.AccountActionSpend, *dto.Amount); err != nil {
+				return err
+			}
+			order.Amount = *dto.Amount
+		}
+		if dto.Description != nil {
+			order.Description = strings.TrimSpace(*dto.Description)
+		}
+		if dto.DayOfMonth != nil {
+			if order.Schedule != models.StandingOrderMonthly {
+				return errors.New("число месяца задается только для ежемесячного поручения")
+			}
+			order.DayOfMonth = *dto.DayOfMonth
+		}
+		if err := setStandingOrderEnd(order, dto.EndDate, dto.MaxRuns); err != nil {
+			return err
+		}
+
+		// Ожидающая повтора попытка сохраняется, остальные даты считаются заново
+		if order.RetryAt == nil {
+			from := bankDate(time.Now())
+			if start := bankDate(order.StartDate); start.After(from) {
+				from = start
+			}
+			next, ok := nextStandingOrderRun(order, from)
+			if !ok {
+				return errors.New("по новым условиям не будет ни одного перевода")
+			}
+			order.NextRunAt = &next
+		}
+
+		if err := tx.Model(order).Updates(map[string]interface{}{
+			"amount":       order.Amount,
+			"description":  order.Description,
+			"day_of_month": order.DayOfMonth,
+			"end_date":     order.EndDate,
+			"max_runs":     order.MaxRuns,
+			"next_run_at":  order.NextRunAt,
+			"updated_at":   time.Now(),
+		}).Error; err != nil {
+			return errors.New("не удалось изменить постоянное поручение")
+		}
+		result = order
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toStandingOrderDTO(*result), nil
+}
+
+// Pause приостанавливает поручение. Пока оно приостановлено, переводы не выполняются
+// и не повторяются.
+func (s *StandingOrderService) Pause(userID, orderID uint) (*StandingOrderDTO, error) {
+	return s.changeStatus(userID, orderID, func(order *models.StandingOrder) (map[string]interface{}, error) {
+		if order.Status != models.StandingOrderActive {
+			return nil, errors.New("приостановить можно только действующее поручение")
+		}
+		return map[string]interface{}{
+			"status":      models.StandingOrderPaused,
+			"retry_at":    nil,
+			"retry_count": 0,
+		}, nil
+	})
+}
+
+// Resume возобновляет приостановленное или остановленное из-за ошибки поручение.
+// Переводы, пропущенные за время паузы, не выполняются.
+func (s *StandingOrderService) Resume(userID, orderID uint) (*StandingOrderDTO, error) {
+	return s.changeStatus(userID, orderID, func(order *models.StandingOrder) (map[string]interface{}, error) {
+		if order.Status != models.StandingOrderPaused && order.Status != models.StandingOrderFailed {
+			return nil, errors.New("возобновить можно только приостановленное или остановленное поручение")
+		}
+
+		from := bankDate(time.Now())
+		if start := bankDate(order.StartDate); start.After(from) {
+			from = start
+		}
+		next, ok := nextStandingOrderRun(order, from)
+		if !ok {
+			return nil, errors.New("по расписанию не осталось переводов")
+		}
+		return map[string]interface{}{
+			"status":      models.StandingOrderActive,
+			"next_run_at": next,
+			"retry_at":    nil,
+			"retry_count": 0,
+			"last_error":  "",
+		}, nil
+	})
+}
+
+// Cancel отменяет поручение без возможности возобновления
+func (s *StandingOrderService) Cancel(userID, orderID uint) error {
+	_, err := s.changeStatus(userID, orderID, func(order *models.StandingOrder) (map[string]interface{}, error) {
+		if !isStandingOrderOpen(order.Status) {
+			return nil, errors.New("поручение уже завершено или отменено")
+		}
+		return map[string]interface{}{
+			"status":      models.StandingOrderCancelled,
+			"next_run_at": nil,
+			"retry_at":    nil,
+		}, nil
+	})
+	return err
+}
+
+// ProcessDue выполняет поручения, дата перевода или повторной попытки которых наступила.
+// Возвращает число обработанных поручений.
+func (s *StandingOrderService) ProcessDue(now time.Time) (int, error) {
+	var ids []uint
+	if err := s.db.Model(&models.StandingOrder{}).
+		Where("status = ? AND COALESCE(retry_at, next_run_at) <= ?", models.StandingOrderActive, now).
+		Order("id").
+		Limit(100).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, errors.New("ошибка при поиске постоянных поручений")
+	}
+
+	processed := 0
+	for _, id := range ids {
+		order, err := s.claim(id, now)
+		if err != nil {
+			log.Printf("Ошибка при резервировании поручения %d: %v", id, err)
+			continue
+		}
+		if order == nil {
+			continue
+		}
+		if err := s.execute(order, now); err != nil {
+			log.Printf("Ошибка при выполнении поручения %d: %v", id, err)
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// claim резервирует наступившее поручение, чтобы параллельный обработчик его не выполнил.
+// Возвращает nil, если поручение уже обработано или больше не действует.
+func (s *StandingOrderService) claim(orderID uint, now time.Time) (*models.StandingOrder, error) {
+	var claimed *models.StandingOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.StandingOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			return err
+		}
+		if order.Status != models.StandingOrderActive || !isStandingOrderDue(&order, now) {
+			return nil
+		}
+
+		lease := now.Add(standingOrderLease)
+		if err := tx.Model(&order).Update("retry_at", lease).Error; err != nil {
+			return err
+		}
+		claimed = &order
+		return nil
+	})
+	return claimed, err
+}
+
+// execute выполняет перевод по поручению через transferTx, как и переводы клиентов,
+// и записывает результат. Перевод и перенос поручения на следующую дату фиксируются
+// в одной транзакции, поэтому перевод за одну дату не выполняется дважды, даже если
+// резерв поручения истек и его взял другой обработчик.
+// При нехватке средств перевод повторяется; после последней попытки он пропускается
+// до следующей даты. Прочие ошибки останавливают поручение.
+func (s *StandingOrderService) execute(order *models.StandingOrder, now time.Time) error {
+	_, err := AuthorizeAccount(s.db, order.UserID, order.SourceID, models.AccountActionSpend, order.Amount)
+	if err == nil {
+		description := order.Description
+		if description == "" {
+			description = fmt.Sprintf("standing order #%d", order.ID)
+		}
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			var current models.StandingOrder
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, order.ID).Error; err != nil {
+				return errors.New("ошибка при поиске постоянного поручения")
+			}
+			if !sameStandingOrderRun(&current, order) {
+				// Перевод за эту дату уже выполнен или поручение изменено
+				return nil
+			}
+
+			if _, err := s.bank.transferTx(tx, TransferRequest{
+				SourceID:      order.SourceID,
+				DestinationID: order.DestinationID,
+				Amount:        order.Amount,
+				Description:   description,
+				SpenderID:     order.UserID,
+			}); err != nil {
+				return err
+			}
+
+			executed := *order
+			executed.RunCount++
+			updates := map[string]interface{}{
+				"last_run_at": now,
+				"last_error":  "",
+				"updated_at":  time.Now(),
+			}
+			s.advance(&executed, updates)
+			if err := tx.Model(order).Updates(updates).Error; err != nil {
+				return errors.New("не удалось сохранить результат перевода")
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		return nil
+	}
+
+	// Пропущенные переводы не учитываются в max_runs
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	var notify string
+	stopped := false
+	switch {
+	case errors.Is(err, ErrInsufficientFunds) && order.RetryCount+1 < standingOrderMaxAttempts:
+		updates["retry_count"] = order.RetryCount + 1
+		updates["retry_at"] = now.Add(standingOrderRetryInterval)
+		updates["last_error"] = err.Error()
+	case errors.Is(err, ErrInsufficientFunds):
+		updates["last_error"] = err.Error()
+		s.advance(order, updates)
+		notify = "перевод пропущен: " + err.Error()
+	default:
+		updates["status"] = models.StandingOrderFailed
+		updates["retry_at"] = nil
+		updates["retry_count"] = 0
+		updates["last_error"] = truncate(err.Error(), 255)
+		notify = err.Error()
+		stopped = true
+	}
+
+	if dbErr := s.db.Model(order).Updates(updates).Error; dbErr != nil {
+		return fmt.Errorf("не удалось сохранить результат перевода: %w", dbErr)
+	}
+	if notify != "" {
+		s.notifyFailed(order, notify, stopped)
+	}
+	return err
+}
+
+// advance переносит поручение на следующую дату по расписанию или завершает его
+func (s *StandingOrderService) advance(order *models.StandingOrder, updates map[string]interface{}) {
+	updates["run_count"] = order.RunCount
+	updates["retry_at"] = nil
+	updates["retry_count"] = 0
+
+	if order.NextRunAt != nil {
+		if next, ok := nextStandingOrderRun(order, order.NextRunAt.AddDate(0, 0, 1)); ok {
+			updates["next_run_at"] = next
+			return
+		}
+	}
+	updates["next_run_at"] = nil
+	updates["status"] = models.StandingOrderCompleted
+}
+
+// changeStatus загружает поручение пользователя с блокировкой и применяет изменения,
+// которые возвращает change
+func (s *StandingOrderService) changeStatus(userID, orderID uint, change func(order *models.StandingOrder) (map[string]interface{}, error)) (*StandingOrderDTO, error) {
+	var result models.StandingOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockStandingOrder(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+		updates, err := change(order)
+		if err != nil {
+			return err
+		}
+		updates["updated_at"] = time.Now()
+		if err := tx.Model(order).Updates(updates).Error; err != nil {
+			return errors.New("не удалось изменить постоянное поручение")
+		}
+		return tx.First(&result, order.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toStandingOrderDTO(result), nil
+}
+
+// notifyFailed сообщает автору поручения о пропущенном переводе или остановке поручения
+func (s *StandingOrderService) notifyFailed(order *models.StandingOrder, reason string, stopped bool) {
+	var user models.User
+	if err := s.db.First(&user, order.UserID).Error; err != nil {
+		return
+	}
+	if err := s.email.SendStandingOrderFailedNotification(user.Email, order.ID, order.Amount, reason, stopped); err != nil {
+		log.Printf("Ошибка отправки уведомления: %v", err)
+	}
+}
+
+// cancelStandingOrders отменяет открытые поручения, связанные со счетами accountIDs
+// (список или подзапрос), например при закрытии счета
+func cancelStandingOrders(tx *gorm.DB, accountIDs interface{}) error {
+	return tx.Model(&models.StandingOrder{}).
+		Where("status IN ?", []models.StandingOrderStatus{models.StandingOrderActive, models.StandingOrderPaused, models.StandingOrderFailed}).
+		Where("source_id IN (?) OR destination_id IN (?)", accountIDs, accountIDs).
+		Updates(map[string]interface{}{
+			"status":      models.StandingOrderCancelled,
+			"next_run_at": nil,
+			"retry_at":    nil,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+// lockStandingOrder загружает поручение пользователя с блокировкой строки
+func lockStandingOrder(tx *gorm.DB, userID, orderID uint) (*models.StandingOrder, error) {
+	var order models.StandingOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", orderID, userID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStandingOrderNotFound
+		}
+		return nil, errors.New("ошибка при поиске постоянного поручения")
+	}
+	return &order, nil
+}
+
+// setStandingOrderEnd применяет дату окончания и число переводов. nil не меняет значение,
+// пустая дата и 0 снимают ограничение.
+func setStandingOrderEnd(order *models.StandingOrder, endDate *string, maxRuns *int) error {
+	if endDate != nil {
+		order.EndDate = nil
+		if *endDate != "" {
+			date, err := parseStandingOrderDate(*endDate)
+			if err != nil {
+				return err
+			}
+			if date.Before(bankDate(order.StartDate)) {
+				return errors.New("дата окончания раньше даты начала")
+			}
+			order.EndDate = &date
+		}
+	}
+	if maxRuns != nil {
+		order.MaxRuns = nil
+		if *maxRuns > 0 {
+			if *maxRuns <= order.RunCount {
+				return fmt.Errorf("по поручению уже выполнено %d переводов", order.RunCount)
+			}
+			runs := *maxRuns
+			order.MaxRuns = &runs
+		}
+	}
+	if order.Schedule == models.StandingOrderOnce {
+		order.EndDate = nil
+		order.MaxRuns = nil
+	}
+	return nil
+}
+
+// nextStandingOrderRun возвращает первую дату перевода по расписанию не раньше from.
+// false означает, что переводов больше не будет: истекла дата окончания
+// или выполнено заданное число переводов.
+func nextStandingOrderRun(order *models.StandingOrder, from time.Time) (time.Time, bool) {
+	from = bankDate(from)
+	if order.MaxRuns != nil && order.RunCount >= *order.MaxRuns {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	switch order.Schedule {
+	case models.StandingOrderOnce:
+		next = bankDate(order.StartDate)
+		if next.Before(from) || order.RunCount > 0 {
+			return time.Time{}, false
+		}
+	case models.StandingOrderWeekly:
+		shift := (int(order.StartDate.Weekday()) - int(from.Weekday()) + 7) % 7
+		next = from.AddDate(0, 0, shift)
+	case models.StandingOrderMonthly, models.StandingOrderLastBusinessDay:
+		next = standingOrderMonthRun(order, from.Year(), from.Month())
+		if next.Before(from) {
+			next = standingOrderMonthRun(order, from.Year(), from.Month()+1)
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if order.EndDate != nil && next.After(bankDate(*order.EndDate)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// standingOrderMonthRun возвращает дату перевода в указанном месяце: число DayOfMonth
+// (последний день, если в месяце меньше дней) или последний рабочий день
+func standingOrderMonthRun(order *models.StandingOrder, year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	last := first.AddDate(0, 1, -1)
+
+	if order.Schedule == models.StandingOrderMonthly {
+		if order.DayOfMonth < last.Day() {
+			return first.AddDate(0, 0, order.DayOfMonth-1)
+		}
+		return last
+	}
+
+	// Праздники не учитываются, рабочими считаются дни с понедельника по пятницу
+	for last.Weekday() == time.Saturday || last.Weekday() == time.Sunday {
+		last = last.AddDate(0, 0, -1)
+	}
+	return last
+}
+
+// isStandingOrderDue проверяет, что дата перевода или повторной попытки наступила
+func isStandingOrderDue(order *models.StandingOrder, now time.Time) bool {
+	if order.RetryAt != nil {
+		return !order.RetryAt.After(now)
+	}
+	return order.NextRunAt != nil && !order.NextRunAt.After(now)
+}
+
+// sameStandingOrderRun проверяет, что поручение не изменилось с момента резервирования:
+// перевод за ту же дату не выполнен и попытка не записана другим обработчиком
+func sameStandingOrderRun(current, claimed *models.StandingOrder) bool {
+	if current.Status != models.StandingOrderActive ||
+		current.RunCount != claimed.RunCount ||
+		current.RetryCount != claimed.RetryCount {
+		return false
+	}
+	if current.NextRunAt == nil || claimed.NextRunAt == nil {
+		return current.NextRunAt == claimed.NextRunAt
+	}
+	return current.NextRunAt.Equal(*claimed.NextRunAt)
+}
+
+// isStandingOrderOpen проверяет, что поручение еще можно изменить или отменить
+func isStandingOrderOpen(status models.StandingOrderStatus) bool {
+	return status == models.StandingOrderActive || status == models.StandingOrderPaused || status == models.StandingOrderFailed
+}
+
+// parseStandingOrderDate разбирает дату в формате ГГГГ-ММ-ДД
+func parseStandingOrderDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation(standingOrderDateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("дата должна быть в формате ГГГГ-ММ-ДД")
+	}
+	return date, nil
+}
+
+// bankDate возвращает начало календарного дня в часовом поясе банка
+func bankDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func toStandingOrderDTO(order models.StandingOrder) *StandingOrderDTO {
+	dto := &StandingOrderDTO{
+		ID:            order.ID,
+		SourceID:      order.SourceID,
+		DestinationID: order.DestinationID,
+		Amount:        order.Amount,
+		Description:   order.Description,
+		Schedule:      string(order.Schedule),
+		DayOfMonth:    order.DayOfMonth,
+		StartDate:     order.StartDate.Format(standingOrderDateLayout),
+		MaxRuns:       order.MaxRuns,
+		RunCount:      order.RunCount,
+		NextRunAt:     order.NextRunAt,
+		RetryAt:       order.RetryAt,
+		LastRunAt:     order.LastRunAt,
+		LastError:     order.LastError,
+		Status:        string(order.Status),
+	}
+	if order.EndDate != nil {
+		dto.EndDate = order.EndDate.Format(standingOrderDateLayout)
+	}
+	return dto
+}
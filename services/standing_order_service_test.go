@@ -0,0 +1,114 @@
+package services
+
+import (
+	"awesomeProject/models"
+	"testing"
+	"time"
+)
+
+func TestNextStandingOrderRun(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+	runs := func(n int) *int { return &n }
+
+	cases := []struct {
+		name   string
+		order  models.StandingOrder
+		from   time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "однократный в дату начала",
+			order:  models.StandingOrder{Schedule: models.StandingOrderOnce, StartDate: date(2026, 3, 10)},
+			from:   date(2026, 3, 1),
+			want:   date(2026, 3, 10),
+			wantOK: true,
+		},
+		{
+			name:  "однократный после выполнения",
+			order: models.StandingOrder{Schedule: models.StandingOrderOnce, StartDate: date(2026, 3, 10), RunCount: 1},
+			from:  date(2026, 3, 10),
+		},
+		{
+			name:   "еженедельно в день недели даты начала",
+			order:  models.StandingOrder{Schedule: models.StandingOrderWeekly, StartDate: date(2026, 3, 2)}, // понедельник
+			from:   date(2026, 3, 4),
+			want:   date(2026, 3, 9),
+			wantOK: true,
+		},
+		{
+			name:   "ежемесячно в текущем месяце",
+			order:  models.StandingOrder{Schedule: models.StandingOrderMonthly, DayOfMonth: 15, StartDate: date(2026, 1, 1)},
+			from:   date(2026, 3, 10),
+			want:   date(2026, 3, 15),
+			wantOK: true,
+		},
+		{
+			name:   "31 число в феврале переносится на последний день",
+			order:  models.StandingOrder{Schedule: models.StandingOrderMonthly, DayOfMonth: 31, StartDate: date(2026, 1, 1)},
+			from:   date(2026, 2, 1),
+			want:   date(2026, 2, 28),
+			wantOK: true,
+		},
+		{
+			name:   "ежемесячно переходит через год",
+			order:  models.StandingOrder{Schedule: models.StandingOrderMonthly, DayOfMonth: 5, StartDate: date(2026, 1, 1)},
+			from:   date(2026, 12, 6),
+			want:   date(2027, 1, 5),
+			wantOK: true,
+		},
+		{
+			name:   "последний рабочий день выпадает на выходные",
+			order:  models.StandingOrder{Schedule: models.StandingOrderLastBusinessDay, StartDate: date(2026, 1, 1)},
+			from:   date(2026, 5, 1), // 31 мая 2026 — воскресенье
+			want:   date(2026, 5, 29),
+			wantOK: true,
+		},
+		{
+			name:  "после даты окончания",
+			order: models.StandingOrder{Schedule: models.StandingOrderMonthly, DayOfMonth: 20, StartDate: date(2026, 1, 1), EndDate: ptrTime(date(2026, 3, 19))},
+			from:  date(2026, 3, 1),
+		},
+		{
+			name:  "выполнено заданное число переводов",
+			order: models.StandingOrder{Schedule: models.StandingOrderWeekly, StartDate: date(2026, 1, 5), MaxRuns: runs(3), RunCount: 3},
+			from:  date(2026, 1, 26),
+		},
+	}
+
+	for _, tc := range cases {
+		got, ok := nextStandingOrderRun(&tc.order, tc.from)
+		if ok != tc.wantOK || (ok && !got.Equal(tc.want)) {
+			t.Errorf("%s: nextStandingOrderRun() = %s, %v, want %s, %v", tc.name, got.Format(standingOrderDateLayout), ok, tc.want.Format(standingOrderDateLayout), tc.wantOK)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func TestSameStandingOrderRun(t *testing.T) {
+	next := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	claimed := models.StandingOrder{Status: models.StandingOrderActive, NextRunAt: ptrTime(next), RunCount: 2, RetryCount: 1}
+
+	cases := []struct {
+		name    string
+		current models.StandingOrder
+		want    bool
+	}{
+		{"без изменений", claimed, true},
+		{"перевод выполнен", models.StandingOrder{Status: models.StandingOrderActive, NextRunAt: ptrTime(next.AddDate(0, 1, 0)), RunCount: 3}, false},
+		{"записана попытка", models.StandingOrder{Status: models.StandingOrderActive, NextRunAt: ptrTime(next), RunCount: 2, RetryCount: 2}, false},
+		{"приостановлено", models.StandingOrder{Status: models.StandingOrderPaused, NextRunAt: ptrTime(next), RunCount: 2, RetryCount: 1}, false},
+		{"завершено", models.StandingOrder{Status: models.StandingOrderActive, RunCount: 2, RetryCount: 1}, false},
+	}
+
+	for _, tc := range cases {
+		if got := sameStandingOrderRun(&tc.current, &claimed); got != tc.want {
+			t.Errorf("%s: sameStandingOrderRun() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
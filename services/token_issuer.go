@@ -0,0 +1,313 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AccessClaims содержит данные access-токена. Пользователь передается в sub,
+// идентификатор токена — в jti, сеанс — в sid. Изменение роли вступает в силу
+// со следующего access-токена.
+type AccessClaims struct {
+	Email     string      `json:"email"`
+	Role      models.Role `json:"role"`
+	SessionID uint        `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// UserID возвращает идентификатор пользователя из claim sub
+func (c *AccessClaims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0, errors.New("некорректный sub в токене")
+	}
+	return uint(id), nil
+}
+
+// JWK описывает открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet представляет набор открытых ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// signingKey представляет ключ подписи токенов
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// TokenIssuer выпускает и проверяет access-токены. Токены подписываются
+// асимметричным ключом (RS256 или EdDSA), ключ указывается в заголовке kid.
+// Старые ключи остаются доступными для проверки после ротации.
+type TokenIssuer struct {
+	mu        sync.RWMutex
+	keys      map[string]*signingKey
+	order     []string
+	activeKID string
+	issuer    string
+	audience  string
+	ttl       time.Duration
+}
+
+// NewTokenIssuer создает TokenIssuer из настроек JWT
+func NewTokenIssuer(cfg *config.Config) (*TokenIssuer, error) {
+	issuer := &TokenIssuer{
+		keys:     make(map[string]*signingKey),
+		issuer:   cfg.JWT.Issuer,
+		audience: cfg.JWT.Audience,
+		ttl:      time.Duration(cfg.JWT.ExpiresIn) * time.Minute,
+	}
+
+	// Без постоянного ключа токены теряли бы силу при каждом перезапуске
+	if len(cfg.JWT.SigningKeys) == 0 {
+		return nil, errors.New("не задан ключ подписи JWT (JWT_SIGNING_KEY)")
+	}
+
+	if cfg.JWT.ActiveKeyVersion < 1 || cfg.JWT.ActiveKeyVersion > len(cfg.JWT.SigningKeys) {
+		return nil, fmt.Errorf("ключ подписи JWT версии %d не найден", cfg.JWT.ActiveKeyVersion)
+	}
+
+	for i, pemKey := range cfg.JWT.SigningKeys {
+		key, err := parseSigningKey(pemKey)
+		if err != nil {
+			return nil, fmt.Errorf("ключ подписи JWT версии %d: %v", i+1, err)
+		}
+		issuer.addKey(key)
+		if i+1 == cfg.JWT.ActiveKeyVersion {
+			issuer.activeKID = key.kid
+		}
+	}
+
+	return issuer, nil
+}
+
+// Rotate добавляет новый ключ подписи и делает его активным.
+// Токены, подписанные прежними ключами, продолжают проходить проверку.
+func (t *TokenIssuer) Rotate(pemKey string) (string, error) {
+	key, err := parseSigningKey(pemKey)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.addKey(key)
+	t.activeKID = key.kid
+	return key.kid, nil
+}
+
+// Retire удаляет ключ из набора проверки. Активный ключ удалить нельзя.
+func (t *TokenIssuer) Retire(kid string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if kid == t.activeKID {
+		return errors.New("нельзя удалить активный ключ подписи")
+	}
+	if _, ok := t.keys[kid]; !ok {
+		return errors.New("ключ подписи не найден")
+	}
+
+	delete(t.keys, kid)
+	for i, id := range t.order {
+		if id == kid {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Issue выпускает access-токен сеанса. Возвращает токен, его jti и время истечения.
+func (t *TokenIssuer) Issue(user *models.User, sessionID uint) (string, string, time.Time, error) {
+	jti, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	t.mu.RLock()
+	key := t.keys[t.activeKID]
+	t.mu.RUnlock()
+
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+	claims := &AccessClaims{
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{t.audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return signed, jti, expiresAt, nil
+}
+
+// Parse проверяет подпись, срок действия и claims iss, aud, sub и jti токена
+func (t *TokenIssuer) Parse(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		t.mu.RLock()
+		key, ok := t.keys[kid]
+		t.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("неизвестный ключ подписи: %q", kid)
+		}
+		// Алгоритм определяется ключом, а не заголовком токена
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("неожиданный алгоритм подписи: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithAudience(t.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("в токене отсутствует sub")
+	}
+	if claims.ID == "" {
+		return nil, errors.New("в токене отсутствует jti")
+	}
+
+	return claims, nil
+}
+
+// TTL возвращает время жизни access-токена
+func (t *TokenIssuer) TTL() time.Duration {
+	return t.ttl
+}
+
+// JWKS возвращает открытые ключи для проверки токенов другими сервисами
+func (t *TokenIssuer) JWKS() JWKSet {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(t.order))}
+	for _, kid := range t.order {
+		key := t.keys[kid]
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// addKey добавляет ключ в набор (вызывается под блокировкой или при создании)
+func (t *TokenIssuer) addKey(key *signingKey) {
+	if _, ok := t.keys[key.kid]; !ok {
+		t.order = append(t.order, key.kid)
+	}
+	t.keys[key.kid] = key
+}
+
+// parseSigningKey разбирает приватный ключ в формате PEM (PKCS#8 или PKCS#1)
+func parseSigningKey(pemKey string) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("ключ подписи должен быть в формате PEM")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if rsaErr != nil {
+			return nil, fmt.Errorf("не удалось разобрать ключ подписи: %v", err)
+		}
+		private = rsaKey
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("неподдерживаемый тип ключа подписи")
+	}
+	return newSigningKey(signer)
+}
+
+// newSigningKey определяет алгоритм по типу ключа и вычисляет kid
+// как SHA-256 от открытого ключа в формате DER
+func newSigningKey(private crypto.Signer) (*signingKey, error) {
+	var method jwt.SigningMethod
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("длина ключа RSA должна быть не менее 2048 бит")
+		}
+		method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("поддерживаются только ключи RSA и Ed25519")
+	}
+
+	public := private.Public()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+
+	return &signingKey{
+		kid:     base64.RawURLEncoding.EncodeToString(sum[:12]),
+		method:  method,
+		private: private,
+		public:  public,
+	}, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+// testTokenConfig возвращает настройки JWT без ключей подписи
+func testTokenConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.JWT.Issuer = "test-issuer"
+	cfg.JWT.Audience = "test-audience"
+	cfg.JWT.ExpiresIn = 15
+	return cfg
+}
+
+// pemKey кодирует приватный ключ в PEM (PKCS#8)
+func pemKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestTokenIssuerRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, key := range map[string]interface{}{"RS256": rsaKey, "EdDSA": edKey} {
+		cfg := testTokenConfig()
+		cfg.JWT.SigningKeys = []string{pemKey(t, key)}
+		cfg.JWT.ActiveKeyVersion = 1
+
+		issuer, err := NewTokenIssuer(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		token, jti, _, err := issuer.Issue(&models.User{ID: 42, Email: "user@example.com", Role: models.RoleSupport}, 7)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		claims, err := issuer.Parse(token)
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", name, err)
+		}
+		userID, err := claims.UserID()
+		if err != nil || userID != 42 {
+			t.Errorf("%s: UserID() = %d, %v, want 42", name, userID, err)
+		}
+		if claims.ID != jti || claims.SessionID != 7 || claims.Email != "user@example.com" || claims.Role != models.RoleSupport {
+			t.Errorf("%s: unexpected claims %+v", name, claims)
+		}
+
+		jwks := issuer.JWKS()
+		if len(jwks.Keys) != 1 || jwks.Keys[0].Alg != name {
+			t.Errorf("%s: unexpected JWKS %+v", name, jwks)
+		}
+	}
+}
+
+func TestTokenIssuerRejectsForeignAudienceAndIssuer(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKey := pemKey(t, edKey)
+
+	newIssuer := func(issuerName, audience string) *TokenIssuer {
+		cfg := testTokenConfig()
+		cfg.JWT.SigningKeys = []string{signingKey}
+		cfg.JWT.ActiveKeyVersion = 1
+		if issuerName != "" {
+			cfg.JWT.Issuer = issuerName
+		}
+		if audience != "" {
+			cfg.JWT.Audience = audience
+		}
+		issuer, err := NewTokenIssuer(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return issuer
+	}
+
+	token, _, _, err := newIssuer("", "").Issue(&models.User{ID: 1, Email: "user@example.com"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newIssuer("", "other-audience").Parse(token); err == nil {
+		t.Error("token with foreign audience was accepted")
+	}
+	if _, err := newIssuer("other-issuer", "").Parse(token); err == nil {
+		t.Error("token with foreign issuer was accepted")
+	}
+	if _, err := newIssuer("", "").Parse(token); err != nil {
+		t.Errorf("token rejected by issuer with the same key: %v", err)
+	}
+}
+
+func TestTokenIssuerRequiresSigningKey(t *testing.T) {
+	if _, err := NewTokenIssuer(testTokenConfig()); err == nil {
+		t.Error("issuer created without a signing key")
+	}
+}
+
+func TestTokenIssuerRotation(t *testing.T) {
+	_, firstKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testTokenConfig()
+	cfg.JWT.SigningKeys = []string{pemKey(t, firstKey)}
+	cfg.JWT.ActiveKeyVersion = 1
+
+	issuer, err := NewTokenIssuer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _, _, err := issuer.Issue(&models.User{ID: 1, Email: "user@example.com"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKID := issuer.JWKS().Keys[0].Kid
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKID, err := issuer.Rotate(pemKey(t, edKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := issuer.Parse(oldToken); err != nil {
+		t.Errorf("token signed before rotation rejected: %v", err)
+	}
+	if len(issuer.JWKS().Keys) != 2 {
+		t.Errorf("JWKS has %d keys after rotation, want 2", len(issuer.JWKS().Keys))
+	}
+
+	if err := issuer.Retire(newKID); err == nil {
+		t.Error("active key was retired")
+	}
+	if err := issuer.Retire(oldKID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Parse(oldToken); err == nil {
+		t.Error("token signed with retired key was accepted")
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount  = 10              // Количество кодов восстановления в наборе
+	twoFactorTokenTTL  = 5 * time.Minute // Время жизни токена второго шага входа
+	twoFactorTokenSize = 32              // Длина токена второго шага в байтах
+)
+
+// TwoFactorEnrollmentDTO содержит данные для подключения приложения-аутентификатора
+type TwoFactorEnrollmentDTO struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorCodeDTO представляет код TOTP или код восстановления
+type TwoFactorCodeDTO struct {
+	Code string `json:"code" validate:"required,min=6,max=11"`
+}
+
+// RecoveryCodesDTO представляет набор кодов восстановления. Коды показываются один раз.
+type RecoveryCodesDTO struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+var (
+	// ErrTwoFactorRequired возвращается, когда операция требует кода 2FA
+	ErrTwoFactorRequired = errors.New("требуется код двухфакторной аутентификации")
+	// ErrInvalidTwoFactorCode возвращается при неверном коде 2FA
+	ErrInvalidTwoFactorCode = errors.New("неверный код двухфакторной аутентификации")
+)
+
+// TwoFactorService управляет двухфакторной аутентификацией по TOTP (RFC 6238)
+type TwoFactorService struct {
+	db     *gorm.DB
+	config *config.Config
+	keys   *CardKeyManager
+}
+
+// NewTwoFactorService создает новый экземпляр TwoFactorService.
+// Секреты TOTP шифруются теми же версионированными ключами, что и данные карт.
+func NewTwoFactorService(db *gorm.DB, cfg *config.Config, keys *CardKeyManager) *TwoFactorService {
+	return &TwoFactorService{
+		db:     db,
+		config: cfg,
+		keys:   keys,
+	}
+}
+
+// BeginEnrollment генерирует новый секрет TOTP. 2FA включается только после
+// подтверждения первым кодом из приложения.
+func (s *TwoFactorService) BeginEnrollment(userID uint) (*TwoFactorEnrollmentDTO, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("пользователь не найден")
+	}
+
+	var settings models.UserTwoFactor
+	err := s.db.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("ошибка при получении настроек 2FA")
+	}
+	if settings.Enabled {
+		return nil, errors.New("двухфакторная аутентификация уже включена")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, version, err := s.keys.Encrypt(secret)
+	if err != nil {
+		return nil, errors.New("не удалось зашифровать секрет")
+	}
+
+	settings.UserID = userID
+	settings.SecretEncrypted = encrypted
+	settings.KeyVersion = version
+	// Счетчик неверных кодов не сбрасывается: иначе перезапуск подключения
+	// снимал бы блокировку подбора кода подтверждения
+	settings.LastCounter = 0
+	if err := s.db.Save(&settings).Error; err != nil {
+		return nil, errors.New("не удалось сохранить настройки 2FA")
+	}
+
+	return &TwoFactorEnrollmentDTO{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.config.TwoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment проверяет первый код из приложения, включает 2FA
+// и выдает набор кодов восстановления. Неверные коды учитываются так же, как в Verify.
+func (s *TwoFactorService) ConfirmEnrollment(userID uint, code string) (*RecoveryCodesDTO, error) {
+	var codes []string
+	var verifyErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var settings models.UserTwoFactor
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).First(&settings).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("подключение 2FA не начато")
+			}
+			return errors.New("ошибка при получении настроек 2FA")
+		}
+		if settings.Enabled {
+			return errors.New("двухфакторная аутентификация уже включена")
+		}
+
+		now := time.Now()
+		if settings.LockedUntil != nil && now.Before(*settings.LockedUntil) {
+			return fmt.Errorf("проверка 2FA заблокирована до %s", settings.LockedUntil.Format("15:04"))
+		}
+
+		counter, err := s.checkTOTP(&settings, code)
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			// Неверный код: сохраняем счетчик попыток, не откатывая транзакцию
+			verifyErr = s.registerFailedAttempt(&settings, now)
+			return tx.Save(&settings).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		settings.Enabled = true
+		settings.EnabledAt = &now
+		settings.LastCounter = counter
+		settings.FailedAttempts = 0
+		settings.LockedUntil = nil
+		if err := tx.Save(&settings).Error; err != nil {
+			return errors.New("не удалось включить 2FA")
+		}
+
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verifyErr != nil {
+		return nil, verifyErr
+	}
+
+	return &RecoveryCodesDTO{Codes: codes}, nil
+}
+
+// Disable отключает 2FA после проверки действующего кода
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error; err != nil {
+			return errors.New("не удалось отключить 2FA")
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return errors.New("не удалось удалить коды восстановления")
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления взамен прежнего
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) (*RecoveryCodesDTO, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesDTO{Codes: codes}, nil
+}
+
+// IsEnabled проверяет, включена ли у пользователя 2FA
+func (s *TwoFactorService) IsEnabled(userID uint) bool {
+	var count int64
+	s.db.Model(&models.UserTwoFactor{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count)
+	return count > 0
+}
+
+// Verify проверяет код TOTP или одноразовый код восстановления.
+// После нескольких неверных кодов проверка временно блокируется.
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	var verifyErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем настройки, чтобы параллельные попытки не обошли счетчик
+		var settings models.UserTwoFactor
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND enabled = ?", userID, true).First(&settings).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("двухфакторная аутентификация не включена")
+			}
+			return errors.New("ошибка при получении настроек 2FA")
+		}
+
+		now := time.Now()
+		if settings.LockedUntil != nil && now.Before(*settings.LockedUntil) {
+			return fmt.Errorf("проверка 2FA заблокирована до %s", settings.LockedUntil.Format("15:04"))
+		}
+
+		if counter, err := s.checkTOTP(&settings, code); err == nil {
+			settings.LastCounter = counter
+			settings.FailedAttempts = 0
+			settings.LockedUntil = nil
+			return tx.Save(&settings).Error
+		}
+
+		used, err := s.useRecoveryCode(tx, userID, code)
+		if err != nil {
+			return err
+		}
+		if used {
+			settings.FailedAttempts = 0
+			settings.LockedUntil = nil
+			return tx.Save(&settings).Error
+		}
+
+		// Неверный код: сохраняем счетчик попыток, не откатывая транзакцию
+		verifyErr = s.registerFailedAttempt(&settings, now)
+		return tx.Save(&settings).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return verifyErr
+}
+
+// registerFailedAttempt учитывает неверный код и блокирует проверку после
+// TwoFactorMaxAttempts попыток подряд. Возвращает ошибку для пользователя.
+func (s *TwoFactorService) registerFailedAttempt(settings *models.UserTwoFactor, now time.Time) error {
+	settings.FailedAttempts++
+	if settings.FailedAttempts < s.config.TwoFactorMaxAttempts {
+		return ErrInvalidTwoFactorCode
+	}
+
+	lockedUntil := now.Add(time.Duration(s.config.TwoFactorLockout) * time.Minute)
+	settings.LockedUntil = &lockedUntil
+	settings.FailedAttempts = 0
+	return fmt.Errorf("превышено количество попыток, проверка 2FA заблокирована до %s", lockedUntil.Format("15:04"))
+}
+
+// RequireForTransfer применяет политику 2FA к переводу: переводы выше порога
+// требуют включенной 2FA и действующего кода
+func (s *TwoFactorService) RequireForTransfer(userID uint, amount float64, code string) error {
+	if amount <= s.config.TwoFactorTransferThreshold {
+		return nil
+	}
+
+	if !s.IsEnabled(userID) {
+		return fmt.Errorf("для переводов свыше %.2f необходимо подключить двухфакторную аутентификацию", s.config.TwoFactorTransferThreshold)
+	}
+	if code == "" {
+		return ErrTwoFactorRequired
+	}
+
+	return s.Verify(userID, code)
+}
+
+// StartChallenge создает токен второго шага входа для пользователя с включенной 2FA
+func (s *TwoFactorService) StartChallenge(userID uint) (string, error) {
+	token, err := utils.GenerateSecureToken(twoFactorTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := &models.TwoFactorChallenge{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(twoFactorTokenTTL),
+	}
+	if err := s.db.Create(challenge).Error; err != nil {
+		return "", errors.New("не удалось начать проверку 2FA")
+	}
+
+	return token, nil
+}
+
+// CompleteChallenge проверяет код второго шага входа и возвращает ID пользователя.
+// Токен второго шага одноразовый. При неверном коде вместе с ошибкой возвращается
+// ID пользователя, чтобы попытка учитывалась защитой входа.
+func (s *TwoFactorService) CompleteChallenge(token, code string) (uint, error) {
+	var challenge models.TwoFactorChallenge
+	if err := s.db.Where("token_hash = ?", utils.HashToken(token)).First(&challenge).Error; err != nil {
+		return 0, errors.New("недействительный токен входа")
+	}
+	if challenge.UsedAt != nil || utils.IsExpired(challenge.ExpiresAt) {
+		return 0, errors.New("недействительный токен входа")
+	}
+
+	if err := s.Verify(challenge.UserID, code); err != nil {
+		return challenge.UserID, err
+	}
+
+	// Условие защищает от повторного использования токена параллельным запросом
+	result := s.db.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return 0, errors.New("недействительный токен входа")
+	}
+
+	return challenge.UserID, nil
+}
+
+// checkTOTP проверяет код TOTP и возвращает его шаг.
+// Код, шаг которого не новее последнего принятого, отклоняется.
+func (s *TwoFactorService) checkTOTP(settings *models.UserTwoFactor, code string) (int64, error) {
+	secret, err := s.keys.Decrypt(settings.KeyVersion, settings.SecretEncrypted)
+	if err != nil {
+		return 0, errors.New("не удалось расшифровать секрет 2FA")
+	}
+
+	counter, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok || counter <= settings.LastCounter {
+		return 0, ErrInvalidTwoFactorCode
+	}
+	return counter, nil
+}
+
+// useRecoveryCode погашает код восстановления, если он действителен
+func (s *TwoFactorService) useRecoveryCode(tx *gorm.DB, userID uint, code string) (bool, error) {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, errors.New("ошибка при проверке кода восстановления")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// replaceRecoveryCodes удаляет прежние коды восстановления и создает новые
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, errors.New("не удалось удалить коды восстановления")
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, errors.New("не удалось сохранить коды восстановления")
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode генерирует код восстановления вида XXXXX-XXXXX (50 бит)
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode приводит код восстановления к виду для хэширования
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
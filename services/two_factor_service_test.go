@@ -0,0 +1,35 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegisterFailedAttempt(t *testing.T) {
+	cfg := &config.Config{TwoFactorMaxAttempts: 3, TwoFactorLockout: 15}
+	s := &TwoFactorService{config: cfg}
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	settings := &models.UserTwoFactor{}
+
+	for i := 1; i < cfg.TwoFactorMaxAttempts; i++ {
+		if err := s.registerFailedAttempt(settings, now); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: error = %v, want ErrInvalidTwoFactorCode", i, err)
+		}
+		if settings.LockedUntil != nil {
+			t.Fatalf("attempt %d: locked too early", i)
+		}
+	}
+
+	if err := s.registerFailedAttempt(settings, now); err == nil || errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("last attempt error = %v, want lockout error", err)
+	}
+	if settings.LockedUntil == nil || !settings.LockedUntil.Equal(now.Add(15*time.Minute)) {
+		t.Errorf("LockedUntil = %v, want %v", settings.LockedUntil, now.Add(15*time.Minute))
+	}
+	if settings.FailedAttempts != 0 {
+		t.Errorf("FailedAttempts = %d after lockout, want 0", settings.FailedAttempts)
+	}
+}
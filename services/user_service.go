@@ -55,6 +55,8 @@ func (h *UserService) CreateUserInternal(req CreateUserRequest) (*models.User, e
 		LastName:  req.LastName,
 		Email:     req.Email,
 		Password:  string(hashedPassword),
+		Role:      models.RoleCustomer,
+		KYCLevel:  models.KYCLevelNone,
 	}
 
 	if err := h.db.DB.Create(user).Error; err != nil {
@@ -75,6 +77,11 @@ func (h *UserService) findById(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByID возвращает пользователя по идентификатору
+func (h *UserService) FindByID(id uint) (*models.User, error) {
+	return h.findById(id)
+}
+
 func (h *UserService) getById(id uint) (*models.User, error) {
 	var user models.User
 	if err := h.db.DB.First(&user, id).Error; err != nil {
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"awesomeProject/config"
+	"awesomeProject/models"
+	"awesomeProject/utils"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour // Время жизни ссылки подтверждения email
+	passwordResetTTL     = time.Hour      // Время жизни ссылки сброса пароля
+	userTokenSize        = 32             // Длина токена в байтах
+)
+
+// ErrInvalidUserToken возвращается при недействительном, истекшем или использованном токене
+var ErrInvalidUserToken = errors.New("ссылка недействительна или устарела")
+
+// VerificationService подтверждает email и сбрасывает пароли по одноразовым ссылкам
+type VerificationService struct {
+	db       *gorm.DB
+	config   *config.Config
+	email    *EmailService
+	sessions *SessionService
+	apiKeys  *APIKeyService
+}
+
+// NewVerificationService создает новый экземпляр VerificationService
+func NewVerificationService(db *gorm.DB, cfg *config.Config, email *EmailService, sessions *SessionService, apiKeys *APIKeyService) *VerificationService {
+	return &VerificationService{
+		db:       db,
+		config:   cfg,
+		email:    email,
+		sessions: sessions,
+		apiKeys:  apiKeys,
+	}
+}
+
+// SendVerification отправляет пользователю ссылку для подтверждения email.
+// Ранее отправленные ссылки перестают действовать.
+func (s *VerificationService) SendVerification(userID uint) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("пользователь не найден")
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New("email уже подтвержден")
+	}
+
+	token, err := s.issueToken(user.ID, models.UserTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.email.SendEmailVerification(user.Email, s.link("/verify-email", token))
+}
+
+// VerifyEmail подтверждает email по токену из письма
+func (s *VerificationService) VerifyEmail(token string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := s.consumeToken(tx, token, models.UserTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", userToken.UserID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return errors.New("не удалось подтвердить email")
+		}
+		return nil
+	})
+}
+
+// BackfillEmailVerification отмечает подтвержденными адреса пользователей,
+// зарегистрированных до появления подтверждения email. Таким пользователям
+// ссылка подтверждения не выпускалась, а новым она выпускается при регистрации.
+func (s *VerificationService) BackfillEmailVerification() (int64, error) {
+	issued := s.db.Model(&models.UserToken{}).
+		Select("1").
+		Where("user_tokens.user_id = users.id AND user_tokens.purpose = ?", models.UserTokenEmailVerification)
+
+	result := s.db.Model(&models.User{}).
+		Where("email_verified_at IS NULL AND NOT EXISTS (?)", issued).
+		Update("email_verified_at", gorm.Expr("created_at"))
+	if result.Error != nil {
+		return 0, errors.New("не удалось отметить подтвержденные email")
+	}
+	return result.RowsAffected, nil
+}
+
+// IsEmailVerified проверяет, подтвердил ли пользователь email
+func (s *VerificationService) IsEmailVerified(userID uint) bool {
+	var count int64
+	s.db.Model(&models.User{}).Where("id = ? AND email_verified_at IS NOT NULL", userID).Count(&count)
+	return count > 0
+}
+
+// ForgotPassword отправляет ссылку для сброса пароля. Поиск пользователя, выпуск
+// токена и отправка письма выполняются в фоне: ни ответ, ни время ответа не
+// показывают, зарегистрирован ли адрес.
+func (s *VerificationService) ForgotPassword(email string) error {
+	go s.sendPasswordReset(email)
+	return nil
+}
+
+// sendPasswordReset выпускает токен сброса пароля и отправляет письмо.
+// Для неизвестного email ничего не происходит.
+func (s *VerificationService) sendPasswordReset(email string) {
+	var user models.User
+	if err := s.db.Where("LOWER(TRIM(email)) = LOWER(TRIM(?))", email).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Ошибка при поиске пользователя для сброса пароля: %v", err)
+		}
+		return
+	}
+
+	token, err := s.issueToken(user.ID, models.UserTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		log.Printf("Ошибка выпуска ссылки сброса пароля пользователю %d: %v", user.ID, err)
+		return
+	}
+
+	if err := s.email.SendPasswordReset(user.Email, s.link("/reset-password", token)); err != nil {
+		log.Printf("Ошибка отправки ссылки сброса пароля пользователю %d: %v", user.ID, err)
+	}
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма, завершает все сеансы
+// пользователя и отзывает его API-ключи. Переход по ссылке из письма также подтверждает email.
+func (s *VerificationService) ResetPassword(token, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var userID uint
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := s.consumeToken(tx, token, models.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		userID = userToken.UserID
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("password", string(hashedPassword)).Error; err != nil {
+			return errors.New("не удалось изменить пароль")
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", userID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return errors.New("не удалось изменить пароль")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка завершения сеансов пользователя %d после сброса пароля: %v", userID, err)
+	}
+	if err := s.apiKeys.RevokeAll(userID); err != nil {
+		log.Printf("Ошибка отзыва API-ключей пользователя %d после сброса пароля: %v", userID, err)
+	}
+	return nil
+}
+
+// RequestEmailChange проверяет пароль и отправляет ссылку подтверждения на новый адрес.
+// До подтверждения для входа используется прежний email.
+func (s *VerificationService) RequestEmailChange(userID uint, newEmail, password string) error {
+	newEmail = strings.TrimSpace(newEmail)
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("пользователь не найден")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return errors.New("неверный пароль")
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return errors.New("новый email совпадает с текущим")
+	}
+
+	var taken int64
+	if err := s.db.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", newEmail).Count(&taken).Error; err != nil {
+		return errors.New("ошибка при проверке email")
+	}
+	if taken > 0 {
+		return errors.New("email уже используется")
+	}
+
+	if err := s.db.Model(&user).Update("pending_email", newEmail).Error; err != nil {
+		return errors.New("не удалось сохранить новый email")
+	}
+
+	token, err := s.issueToken(user.ID, models.UserTokenEmailChange, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.email.SendEmailChangeRequestedNotification(user.Email, newEmail); err != nil {
+		log.Printf("Ошибка отправки уведомления о смене email пользователю %d: %v", user.ID, err)
+	}
+	return s.email.SendEmailChangeVerification(user.ID, newEmail, s.link("/confirm-email-change", token))
+}
+
+// ConfirmEmailChange заменяет email на подтвержденный новый адрес
+func (s *VerificationService) ConfirmEmailChange(token string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := s.consumeToken(tx, token, models.UserTokenEmailChange)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userToken.UserID).Error; err != nil {
+			return ErrInvalidUserToken
+		}
+		if user.PendingEmail == "" {
+			return ErrInvalidUserToken
+		}
+
+		// Адрес мог быть занят, пока ссылка ждала подтверждения
+		var taken int64
+		if err := tx.Model(&models.User{}).
+			Where("LOWER(email) = LOWER(?) AND id <> ?", user.PendingEmail, user.ID).
+			Count(&taken).Error; err != nil {
+			return errors.New("ошибка при проверке email")
+		}
+		if taken > 0 {
+			return errors.New("email уже используется")
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":             user.PendingEmail,
+			"pending_email":     "",
+			"email_verified_at": time.Now(),
+		}).Error; err != nil {
+			return errors.New("не удалось изменить email")
+		}
+		return nil
+	})
+}
+
+// issueToken создает одноразовый токен и погашает прежние токены того же назначения
+func (s *VerificationService) issueToken(userID uint, purpose models.UserTokenPurpose, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateSecureToken(userTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: utils.GenerateExpirationTime(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", errors.New("не удалось создать ссылку")
+	}
+
+	return token, nil
+}
+
+// consumeToken находит действующий токен и отмечает его использованным
+func (s *VerificationService) consumeToken(tx *gorm.DB, token string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	var userToken models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).
+		First(&userToken).Error; err != nil {
+		return nil, ErrInvalidUserToken
+	}
+
+	if userToken.UsedAt != nil || utils.IsExpired(userToken.ExpiresAt) {
+		return nil, ErrInvalidUserToken
+	}
+
+	now := time.Now()
+	userToken.UsedAt = &now
+	if err := tx.Model(&userToken).Update("used_at", now).Error; err != nil {
+		return nil, errors.New("не удалось использовать ссылку")
+	}
+	return &userToken, nil
+}
+
+// link формирует ссылку на клиентское приложение с токеном
+func (s *VerificationService) link(path, token string) string {
+	return s.config.Server.BaseURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+)
+
+// accountKeyWeights весовые коэффициенты для расчета контрольного ключа номера счета
+var accountKeyWeights = [3]int{7, 1, 3}
+
+// accountKeyPosition позиция контрольного ключа в 20-значном номере счета
+const accountKeyPosition = 8
+
+// AccountControlKey вычисляет контрольный ключ номера счета по алгоритму Банка России.
+// Ключ рассчитывается по условному номеру из трех последних цифр БИК и 20 цифр счета,
+// в котором ключ заменен нулем. Возвращается цифра ключа.
+func AccountControlKey(bik, account string) (byte, error) {
+	if err := checkAccountDigits(bik, account); err != nil {
+		return 0, err
+	}
+	payload := []byte(bik[6:] + account)
+	payload[3+accountKeyPosition] = '0'
+	return byte('0' + accountKeySum(string(payload))*3%10), nil
+}
+
+// ValidateAccountNumber проверяет номер счета в банке с указанным БИК:
+// длину, состав и контрольный ключ
+func ValidateAccountNumber(bik, account string) error {
+	if err := checkAccountDigits(bik, account); err != nil {
+		return err
+	}
+	if accountKeySum(bik[6:]+account) != 0 {
+		return errors.New("неверный контрольный ключ номера счета")
+	}
+	return nil
+}
+
+// accountKeySum возвращает последнюю цифру суммы младших разрядов произведений цифр на веса
+func accountKeySum(payload string) int {
+	sum := 0
+	for i := 0; i < len(payload); i++ {
+		sum += int(payload[i]-'0') * accountKeyWeights[i%3] % 10
+	}
+	return sum % 10
+}
+
+// checkAccountDigits проверяет, что БИК состоит из 9 цифр, а номер счета — из 20
+func checkAccountDigits(bik, account string) error {
+	if len(bik) != 9 || !onlyDigits(bik) {
+		return errors.New("БИК должен состоять из 9 цифр")
+	}
+	if len(account) != 20 || !onlyDigits(account) {
+		return errors.New("номер счета должен состоять из 20 цифр")
+	}
+	return nil
+}
+
+func onlyDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
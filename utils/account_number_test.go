@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+)
+
+// sberbankBIK БИК ПАО Сбербанк, для которого известны реальные номера счетов
+const sberbankBIK = "044525225"
+
+func TestValidateAccountNumber(t *testing.T) {
+	if err := ValidateAccountNumber(sberbankBIK, "40702810638050013199"); err != nil {
+		t.Errorf("ValidateAccountNumber(valid) error = %v", err)
+	}
+
+	invalid := []struct {
+		bik     string
+		account string
+	}{
+		{sberbankBIK, "40702810638050013198"}, // изменена последняя цифра
+		{sberbankBIK, "40702810738050013199"}, // неверный ключ
+		{"044525226", "40702810638050013199"}, // счет другого банка
+		{sberbankBIK, "4070281063805001319"},  // 19 цифр
+		{sberbankBIK, "4070281063805001319a"},
+		{"04452522", "40702810638050013199"},
+	}
+	for _, tc := range invalid {
+		if err := ValidateAccountNumber(tc.bik, tc.account); err == nil {
+			t.Errorf("ValidateAccountNumber(%s, %s) = nil, want error", tc.bik, tc.account)
+		}
+	}
+}
+
+func TestAccountControlKey(t *testing.T) {
+	key, err := AccountControlKey(sberbankBIK, "40702810038050013199")
+	if err != nil {
+		t.Fatalf("AccountControlKey() error = %v", err)
+	}
+	if key != '6' {
+		t.Errorf("AccountControlKey() = %c, want 6", key)
+	}
+
+	// Любая замена одной цифры нарушает ключ, так как веса 7, 1, 3 взаимно просты с 10
+	account := []byte("40817810000000000001")
+	key, _ = AccountControlKey(sberbankBIK, string(account))
+	account[8] = key
+	for i := range account {
+		for d := byte('0'); d <= '9'; d++ {
+			if d == account[i] {
+				continue
+			}
+			changed := append([]byte(nil), account...)
+			changed[i] = d
+			if ValidateAccountNumber(sberbankBIK, string(changed)) == nil {
+				t.Errorf("ValidateAccountNumber(%s) = nil after changing digit %d", changed, i)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	_ "errors"
 	"fmt"
 	"golang.org/x/crypto/openpgp"
@@ -131,6 +132,12 @@ func GenerateSecureToken(length int) (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
+// HashToken возвращает SHA-256 хеш токена для хранения в базе данных
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // HashPassword создает хеш пароля
 func HashPassword(password string) (string, error) {
 	// Генерируем соль
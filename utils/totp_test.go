@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret общий секрет тестовых векторов RFC 4226 и RFC 6238 (SHA-1)
+const rfcSecret = "12345678901234567890"
+
+func TestHOTPRFC4226Vectors(t *testing.T) {
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, want := range expected {
+		if got := hotp([]byte(rfcSecret), uint64(counter), 6); got != want {
+			t.Errorf("hotp(counter=%d) = %s, want %s", counter, got, want)
+		}
+	}
+}
+
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, v := range vectors {
+		got := hotp([]byte(rfcSecret), totpCounter(time.Unix(v.unix, 0)), 8)
+		if got != v.want {
+			t.Errorf("TOTP(T=%d) = %s, want %s", v.unix, got, v.want)
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOneStepSkew(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(rfcSecret))
+	now := time.Unix(1111111111, 0)
+
+	code, err := GenerateTOTP(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, delta := range []time.Duration{-30 * time.Second, 0, 30 * time.Second} {
+		if _, ok := ValidateTOTP(secret, code, now.Add(delta)); !ok {
+			t.Errorf("code rejected with clock skew %v", delta)
+		}
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(90*time.Second)); ok {
+		t.Error("code accepted three steps later")
+	}
+
+	counter, ok := ValidateTOTP(strings.ToLower(secret), code, now)
+	if !ok || counter != int64(totpCounter(now)) {
+		t.Errorf("ValidateTOTP() = %d, %v, want %d, true", counter, ok, totpCounter(now))
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Awesome Bank", "user@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Awesome%20Bank:user@example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+	for _, param := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Awesome+Bank", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("%s does not contain %s", uri, param)
+		}
+	}
+}